@@ -0,0 +1,79 @@
+// Agent 离线评测命令
+//
+// 用法:
+//   go run ./cmd/eval -suite eval/suites/basic.yaml -backend fake
+//   go run ./cmd/eval -suite eval/suites/basic.yaml -backend ollama -models qwen2.5:14b,qwen2.5:7b
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"winds-assistant/eval"
+	"winds-assistant/utils"
+)
+
+func main() {
+	suitePath := flag.String("suite", "eval/suites/basic.yaml", "评测套件文件")
+	backendName := flag.String("backend", "fake", "后端名称 (fake 或 config/llm_settings.yaml 中的后端)")
+	models := flag.String("models", "", "逗号分隔的模型列表，默认使用配置中的模型")
+	out := flag.String("out", "", "将 JSON 报告写入该文件")
+	minRate := flag.Float64("min-rate", 0, "任一模型通过率低于该值时返回非零退出码 (0~1)")
+	flag.Parse()
+
+	suite, err := eval.LoadSuite(*suitePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	var reports []eval.ModelReport
+	if *backendName == "fake" {
+		reports = append(reports, eval.RunSuite(ctx, suite, "fake", func(c eval.Case) eval.Backend {
+			return &eval.ScriptedBackend{Script: c.Script}
+		}))
+	} else {
+		cfg, err := utils.LoadLLMCfg()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		backendCfg, ok := cfg.Backend[*backendName]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown backend: %s\n", *backendName)
+			os.Exit(2)
+		}
+
+		modelList := []string{backendCfg.Model}
+		if *models != "" {
+			modelList = strings.Split(*models, ",")
+		}
+		for _, model := range modelList {
+			modelCfg := backendCfg
+			modelCfg.Model = strings.TrimSpace(model)
+			reports = append(reports, eval.RunSuite(ctx, suite, modelCfg.Model, func(c eval.Case) eval.Backend {
+				return &eval.LLMBackend{Name: *backendName, Cfg: modelCfg}
+			}))
+		}
+	}
+
+	eval.PrintReport(os.Stdout, suite, reports)
+
+	if *out != "" {
+		data, _ := json.MarshalIndent(reports, "", "  ")
+		if err := os.WriteFile(*out, data, 0644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	for _, r := range reports {
+		if r.SuccessRate() < *minRate {
+			os.Exit(1)
+		}
+	}
+}
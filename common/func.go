@@ -1,6 +1,8 @@
 package common
 
 import (
+	"sort"
+	"strings"
 	"sync"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -157,4 +159,28 @@ func (cp *ChatChunkProcessor) GetString() string {
     bufferPool.Put(bufferPtr)
     
     return result
-}
\ No newline at end of file
+}
+// 将指标标签格式化为按键名排序的 k=v,k=v 形式
+func FormatLabels(labels map[string]string) string {
+	var keys []string
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		parts = append(parts, k+"="+labels[k])
+	}
+	return strings.Join(parts, ",")
+}
+
+// 解析 FormatLabels 的输出
+func ParseLabels(s string) map[string]string {
+	labels := map[string]string{}
+	for _, part := range strings.Split(s, ",") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			labels[k] = v
+		}
+	}
+	return labels
+}
@@ -2,12 +2,11 @@ package common
 
 import (
 	"context"
+	"time"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/widget"
 	"github.com/shirou/gopsutil/v4/cpu"
-	"github.com/shirou/gopsutil/v4/disk"
-	"github.com/shirou/gopsutil/v4/mem"
 )
 
 type LLMMessage struct {
@@ -37,12 +36,35 @@ type Settings struct {
     EnableAgent    bool                    // 是否启用Agent调用系统能力
     SysPrompt      string                  // 系统 Prompt
     Running        bool                    // 是否正在对话
+    Messages       []LLMMessage            // 本次会话的完整对话记录
+    ToolCalls      []ToolCallRecord        // 本次会话的工具调用记录
+}
+
+// 工具调用记录
+type ToolCallRecord struct {
+    Name           string                 `json:"name"`        // 工具名
+    Args           map[string]interface{} `json:"args"`        // 模型给出的参数
+    StartTime      time.Time              `json:"start_time"`  // 开始时间
+    EndTime        time.Time              `json:"end_time"`    // 结束时间
+    Bytes          int                    `json:"bytes"`       // 输出字节数
+    Status         string                 `json:"status"`      // ok / cached / error / invalid
+    Output         string                 `json:"output"`      // 原始输出
+}
+
+// 保存到文件的会话
+type DialogRecord struct {
+    DialogID       string                 `json:"dialog_id"`
+    Backend        string                 `json:"backend"`
+    Model          string                 `json:"model"`
+    Messages       []LLMMessage           `json:"messages"`
+    ToolCalls      []ToolCallRecord       `json:"tool_calls"`
 }
 
 // 配置文件解析
 type LLMConfig struct {
     Backend        map[string]BackendConfig `yaml:"backend"`     // 后端配置
     Default        string                   `yaml:"default_backend"`     // 默认后端
+    SecretProvider string                   `yaml:"secret_provider,omitempty"` // 凭据存储: vault(默认) 或 keyring
 }
 
 type BackendConfig struct {
@@ -51,17 +73,79 @@ type BackendConfig struct {
     Model          string `yaml:"model"`       // 模型名称
 }
 
+// 系统监控配置（config/monitor_settings.yaml 的 monitor 小节）
+type MonitorConfig struct {
+    DataDir        string                 `yaml:"data_dir"`       // 时序数据目录
+    Interval       time.Duration          `yaml:"interval"`       // 采集器的默认周期
+    StoreInterval  time.Duration          `yaml:"store_interval"` // 写入存储的周期
+    Collectors     map[string]CollectorConfig `yaml:"collectors"` // 按采集器名称单独配置
+    Metrics        []string               `yaml:"metrics"`        // 记录的指标，为空表示全部
+    Thresholds     map[string]float64     `yaml:"thresholds"`     // 统计超阈值时长使用的阈值，覆盖默认值
+    Alerts         []AlertRuleConfig      `yaml:"alerts"`         // 告警规则
+    Disk           DiskMonitorConfig      `yaml:"disk"`
+    Process        ProcessMonitorConfig   `yaml:"process"`
+    Exporter       ExporterConfig         `yaml:"exporter"`
+    Retention      RetentionConfig        `yaml:"retention"`
+}
+
+// 告警规则，如 gpu_temp > 85 for 2m、mem_used > 90% for 5m、disk_used_percent{mount=C:} > 95
+type AlertRuleConfig struct {
+    Name           string                 `yaml:"name"`        // 显示名称，为空时使用规则本身
+    Rule           string                 `yaml:"rule"`
+    Diagnose       bool                   `yaml:"diagnose"`    // 触发时自动运行 Agent 收集进程和事件并给出诊断
+}
+
+type CollectorConfig struct {
+    Enabled        *bool                  `yaml:"enabled"`     // 为空表示启用
+    Interval       time.Duration          `yaml:"interval"`    // 为空使用默认周期
+}
+
+// 各精度数据的保留时长，格式如 72h
+type RetentionConfig struct {
+    Raw            time.Duration          `yaml:"raw"`         // 原始采样
+    Minute         time.Duration          `yaml:"1m"`          // 1 分钟汇总
+    Hour           time.Duration          `yaml:"1h"`          // 1 小时汇总
+    Anomalies      time.Duration          `yaml:"anomalies"`   // 检测到的异常
+    Alerts         time.Duration          `yaml:"alerts"`      // 告警历史
+}
+
+type DiskMonitorConfig struct {
+    FsTypes        []string               `yaml:"fs_types"`    // 记录的文件系统类型（忽略大小写）
+}
+
+// OpenMetrics 导出，供 Prometheus 抓取
+type ExporterConfig struct {
+    Enabled        bool                   `yaml:"enabled"`
+    Listen         string                 `yaml:"listen"`      // 监听地址，默认 127.0.0.1:9464
+}
+
+type ProcessMonitorConfig struct {
+    TopN           int                    `yaml:"top_n"`       // 分别按 CPU、内存、I/O 记录占用最高的进程数
+}
+
 type GPUInfoStat struct {
 	Index		   uint64  `json:"index"`		 // GPU序号
 	Name		   string  `json:"name"`         // GPU名称
-    Utilization    float64 `json:"gpu_util"`     // GPU利用率(%) 
-    MemUsed        uint64  `json:"mem_used"`     // 显存使用量(MB)
-    MemTotal       uint64  `json:"mem_total"`    // 显存总量(MB)
-    CoreClock      uint64  `json:"core_clock"`   // 核心频率(MHz)
-    MemClock       uint64  `json:"mem_clock"`    // 显存频率(MHz)
-    Temperature    uint64  `json:"temp"`         // 温度(℃)
-    PowerDraw      float64 `json:"power"`        // 实时功耗(W)
-    Vendor         string  `json:"vendor"`       // 厂商(NVIDIA)
+    Utilization    float64 `json:"gpu_util"`     // GPU利用率(%)，-1 表示无法获取
+    MemUsed        int64   `json:"mem_used"`     // 显存使用量(MB)，-1 表示无法获取
+    MemTotal       uint64  `json:"mem_total"`    // 显存总量(MB)，0 表示无法获取
+    CoreClock      int64   `json:"core_clock"`   // 核心频率(MHz)，-1 表示无法获取
+    MemClock       int64   `json:"mem_clock"`    // 显存频率(MHz)，-1 表示无法获取
+    Temperature    int64   `json:"temp"`         // 温度(℃)，-1 表示无法获取
+    PowerDraw      float64 `json:"power"`        // 实时功耗(W)，-1 表示无法获取
+    Vendor         string  `json:"vendor"`       // 厂商(NVIDIA / AMD / Intel)
+}
+
+// 进程的累计资源计数，由两次采集的差值计算速率
+type ProcessCounterStat struct {
+    Pid            int32
+    CreateTime     int64                         // 创建时间戳(毫秒)，与 Pid 一起识别进程（Pid 会被复用）
+    Name           string
+    Exe            string                        // 执行路径，无权限时为空
+    CPUTime        float64                       // 累计 CPU 时间(秒，用户态 + 内核态)
+    RSS            uint64                        // 常驻内存(字节)
+    ReadBytes      uint64                        // 累计读取字节数
+    WriteBytes     uint64                        // 累计写入字节数
 }
 
 type CPUInfoStat struct{
@@ -69,12 +153,58 @@ type CPUInfoStat struct{
     Percent        float64                       // CPU利用率
 }
 
-type MetricData struct {
-	CPU            CPUInfoStat
-	Mem            *mem.VirtualMemoryStat
-	Disk           *disk.UsageStat
-	GPU            []GPUInfoStat
-	Time           int64
+// 一个指标采样点，由采集器产生
+type MetricSample struct {
+    Time           int64                         // Unix 时间戳(秒)
+    Name           string                        // 指标名，如 cpu_percent
+    Value          float64
+    Unit           string
+    Source         string                        // 产生该采样的采集器名称
+    Labels         map[string]string             // 区分同名指标的标签，如 gpu=0
+}
+
+// 进程操作的审计记录，无论用户是否确认都会记录
+type ProcessAuditRecord struct {
+    Time           int64             `json:"time"`             // Unix 时间戳(秒)
+    Action         string            `json:"action"`           // terminate, kill, suspend, resume, set_priority
+    Priority       string            `json:"priority,omitempty"`
+    Pid            int32             `json:"pid"`
+    Name           string            `json:"name"`
+    Exe            string            `json:"exe,omitempty"`
+    User           string            `json:"user,omitempty"`
+    CreateTime     int64             `json:"create_time"`      // 进程创建时间戳(毫秒)
+    Reason         string            `json:"reason,omitempty"` // 模型给出的操作理由
+    Confirmed      bool              `json:"confirmed"`
+    Result         string            `json:"result"`           // ok、denied 或错误信息
+}
+
+// 告警事件（触发、恢复或诊断结果），同一次告警的各事件 ID 相同
+type AlertEvent struct {
+    ID             string            `json:"id"`
+    Time           int64             `json:"time"`             // Unix 时间戳(秒)
+    State          string            `json:"state"`            // firing / resolved / diagnosis
+    Rule           string            `json:"rule"`             // 规则名称
+    Expr           string            `json:"expr"`             // 规则表达式
+    Name           string            `json:"name"`             // 指标名
+    Labels         map[string]string `json:"labels,omitempty"`
+    Unit           string            `json:"unit,omitempty"`
+    Value          float64           `json:"value"`
+    Since          int64             `json:"since,omitempty"`  // 条件开始满足的时间
+    Reason         string            `json:"reason,omitempty"` // 非因条件不再满足而恢复时的原因
+    Diagnosis      string            `json:"diagnosis,omitempty"`
+}
+
+// 检测到的指标异常，由监控守护进程中的检测器产生
+type Anomaly struct {
+    Time           int64             `json:"time"`             // Unix 时间戳(秒)
+    Name           string            `json:"name"`             // 指标名
+    Labels         map[string]string `json:"labels,omitempty"`
+    Unit           string            `json:"unit"`
+    Value          float64           `json:"value"`            // 实际值
+    Expected       float64           `json:"expected"`         // 检测器给出的期望值
+    Score          float64           `json:"score"`            // 偏离期望的标准差倍数
+    Detector       string            `json:"detector"`         // 检测器名称
+    Severity       string            `json:"severity"`         // warning / critical
 }
 
 type Widgets struct {
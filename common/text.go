@@ -15,6 +15,7 @@ const (
 	CHAT_END = "\n<对话结束>\n"
 	CHAT_AGENT_MID = "\n中间结果:\n"
 	CHAT_TERMINATE = "\n<对话终止>\n"
+	CHAT_TOOL_CALL_INFO = "\n🔧 <%s> %s | 耗时 %v | %s\n"
 
 	// WIDGET 文本
 	WIDGET_SETTING = "设置"
@@ -39,8 +40,43 @@ const (
 	WIDGET_FASTCLIBOARD = "快捷指令"
 	WIDGET_SKIP_TO_BOTTOM = "跳转底部"
 	WIDGET_AGENT_SETTING = "AGENT 设置"
+	WIDGET_TOOL_CFG_MISSING = "⚠ 缺少配置项: %s (config/agent_tools.yaml)"
+	WIDGET_TOOL_CFG_ERROR = "⚠ 配置读取失败: %v"
+	WIDGET_SECRET_SETTING = "凭据管理"
+	WIDGET_SECRET_UNLOCK = "解锁凭据库"
+	WIDGET_SECRET_CREATE = "创建凭据库（设置口令）"
+	WIDGET_SECRET_PROVIDER = "当前凭据存储: %s"
+	WIDGET_SECRET_HINT = "在配置文件中使用 ${secret:名称} 引用凭据，或使用 ${env:变量名} 引用环境变量"
+	WIDGET_FORM_PASSPHRASE = "口令"
+	WIDGET_FORM_SECRET_NAME = "名称"
+	WIDGET_FORM_SECRET_VALUE = "值"
+	WIDGET_DELETE = "删除"
+	WIDGET_TOOL_ACTIVITY = "工具调用记录"
+	WIDGET_TOOL_ACTIVITY_EMPTY = "本次会话还没有工具调用"
+	WIDGET_COPY = "复制"
+	WIDGET_ATTACH = "附加文件"
+	CHAT_ATTACHMENT = "\n[附件] %s"
+	WIDGET_ALERT_HISTORY = "告警记录"
+	WIDGET_ALERT_HISTORY_EMPTY = "还没有告警，可在 config/monitor_settings.yaml 的 alerts 中配置告警规则"
+	ALERT_FIRING_TITLE = "告警: "
+	ALERT_RESOLVED_TITLE = "已恢复: "
+	ALERT_DIAGNOSIS_TITLE = "诊断: "
+	ALERT_DIAGNOSIS_FAILED = "诊断失败: "
+	ALERT_REASON_STALE = "序列已停止上报"
+	ALERT_REASON_RULE_REMOVED = "规则已删除或修改"
+	WIDGET_PROCESS_ACTION = "确认进程操作"
+	WIDGET_PROCESS_ACTION_HINT = "Agent 请求对以下进程执行操作，确认后立即执行："
 )
 
+// 进程操作在确认对话框中的名称
+var PROCESS_ACTION_MAP = map[string]string{
+	"terminate": 	"结束进程",
+	"kill": 		"强制结束进程",
+	"suspend": 		"挂起进程",
+	"resume": 		"恢复进程",
+	"set_priority": "修改优先级",
+}
+
 var BACKEND_MAP = map[string]string{
 	"ollama": 		"OLLAMA",
 	"qwen": 		"通义千问",
@@ -0,0 +1,38 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"winds-assistant/common"
+	"winds-assistant/workers"
+)
+
+// 评测使用的模型后端
+type Backend interface {
+	Chat(ctx context.Context, messages []common.LLMMessage) (string, error)
+}
+
+// 真实的 LLM 后端，复用 workers 中的请求与解析
+type LLMBackend struct {
+	Name string
+	Cfg  common.BackendConfig
+}
+
+func (b *LLMBackend) Chat(ctx context.Context, messages []common.LLMMessage) (string, error) {
+	return workers.ChatReq(ctx, b.Name, b.Cfg, messages)
+}
+
+// 脚本化假后端：按顺序返回用例中预先写好的回复，不访问网络
+type ScriptedBackend struct {
+	Script []string
+	turn   int
+}
+
+func (b *ScriptedBackend) Chat(ctx context.Context, messages []common.LLMMessage) (string, error) {
+	if b.turn >= len(b.Script) {
+		return "", fmt.Errorf("script exhausted after %d turns", len(b.Script))
+	}
+	reply := b.Script[b.turn]
+	b.turn++
+	return reply, nil
+}
@@ -0,0 +1,59 @@
+package eval
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func loadBasicSuite(t *testing.T) *Suite {
+	t.Helper()
+	suite, err := LoadSuite("suites/basic.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suite.Cases) == 0 {
+		t.Fatal("suite has no cases")
+	}
+	return suite
+}
+
+// 用脚本化后端运行套件，校验工具选择、参数解析和回答检查的整条链路
+func TestBasicSuiteScripted(t *testing.T) {
+	suite := loadBasicSuite(t)
+	report := RunSuite(context.Background(), suite, "fake", func(c Case) Backend {
+		return &ScriptedBackend{Script: c.Script}
+	})
+	for _, r := range report.Results {
+		if !r.Passed() {
+			t.Errorf("case %s failed: err=%q problems=%v chosen=%v", r.Case, r.Err, r.Problems, r.Chosen)
+		}
+	}
+	if rate := report.SuccessRate(); rate != 1 {
+		t.Errorf("success rate = %.2f, want 1", rate)
+	}
+}
+
+// 回复与期望不符时应判为失败，避免评测本身总是通过
+func TestBasicSuiteDetectsFailures(t *testing.T) {
+	suite := loadBasicSuite(t)
+	report := RunSuite(context.Background(), suite, "wrong", func(c Case) Backend {
+		return &ScriptedBackend{Script: []string{`{"tools": {"get_sys_driver": {}}}`, "不知道。"}}
+	})
+	if rate := report.SuccessRate(); rate != 0 {
+		var passed []string
+		for _, r := range report.Results {
+			if r.Passed() {
+				passed = append(passed, r.Case)
+			}
+		}
+		t.Errorf("success rate = %.2f with wrong replies, passed: %s", rate, strings.Join(passed, ", "))
+	}
+
+	report = RunSuite(context.Background(), suite, "empty", func(c Case) Backend { return &ScriptedBackend{} })
+	for _, r := range report.Results {
+		if r.Err == "" || r.Passed() {
+			t.Errorf("case %s passed without any reply: %+v", r.Case, r)
+		}
+	}
+}
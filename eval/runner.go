@@ -0,0 +1,209 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"winds-assistant/common"
+	"winds-assistant/workers"
+)
+
+// 单个用例的评测结果
+type CaseResult struct {
+	Case      string                 `json:"case"`
+	ToolsOK   bool                   `json:"tools_ok"`   // 工具选择是否正确
+	ArgsOK    bool                   `json:"args_ok"`    // 参数是否符合期望
+	AnswerOK  bool                   `json:"answer_ok"`  // 最终回答是否符合期望
+	Chosen    map[string]interface{} `json:"chosen"`     // 模型实际选择的工具及参数
+	Answer    string                 `json:"answer"`     // 最终回答
+	Problems  []string               `json:"problems"`   // 不符合期望的原因
+	Duration  time.Duration          `json:"duration"`
+	Err       string                 `json:"error,omitempty"`
+}
+
+func (r CaseResult) Passed() bool {
+	return r.Err == "" && r.ToolsOK && r.ArgsOK && r.AnswerOK
+}
+
+// 单个模型的评测报告
+type ModelReport struct {
+	Model   string       `json:"model"`
+	Results []CaseResult `json:"results"`
+}
+
+func (m ModelReport) count(f func(CaseResult) bool) int {
+	n := 0
+	for _, r := range m.Results {
+		if f(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// 用例通过率
+func (m ModelReport) SuccessRate() float64 {
+	if len(m.Results) == 0 {
+		return 0
+	}
+	return float64(m.count(CaseResult.Passed)) / float64(len(m.Results))
+}
+
+// 对单个模型运行整个套件，newBackend 为每个用例创建独立的后端
+func RunSuite(ctx context.Context, suite *Suite, model string, newBackend func(Case) Backend) ModelReport {
+	report := ModelReport{Model: model}
+	for _, c := range suite.Cases {
+		report.Results = append(report.Results, runCase(ctx, suite, c, newBackend(c)))
+	}
+	return report
+}
+
+// 与界面中 ProcessStreamWithTools 相同的两轮对话：先选择工具，再根据工具输出回答
+func runCase(ctx context.Context, suite *Suite, c Case, backend Backend) (r CaseResult) {
+	start := time.Now()
+	r.Case = c.Name
+	defer func() { r.Duration = time.Since(start) }()
+
+	messages := []common.LLMMessage{
+		{Role: "system", Content: workers.BuildAgentSysPrompt() + workers.AgentTimePrompt()},
+		{Role: "user", Content: c.Prompt},
+	}
+	first, err := backend.Chat(ctx, messages)
+	if err != nil {
+		r.Err = err.Error()
+		return
+	}
+
+	chosen, cleaned, useTool := workers.ParseToolCalls(first)
+	r.Chosen = chosen
+	r.ToolsOK, r.ArgsOK, r.Problems = checkTools(c.Expect, chosen)
+
+	r.Answer = cleaned
+	r.AnswerOK = true
+	if useTool {
+		// 使用录制的工具输出代替真实工具
+		var names []string
+		for name := range chosen {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var midOutput string
+		for _, name := range names {
+			midOutput += suite.Fixture(name)
+		}
+
+		messages = append(messages, common.LLMMessage{Role: "user", Content: workers.USER_PROMPT_WITH_TOOLS + midOutput})
+		second, err := backend.Chat(ctx, messages)
+		if err != nil {
+			r.Err = err.Error()
+			return
+		}
+		_, cleaned, again := workers.ParseToolCalls(second)
+		if again {
+			r.AnswerOK = false
+			r.Problems = append(r.Problems, "工具输出返回后模型再次调用了工具")
+		}
+		r.Answer = cleaned
+	}
+
+	for _, want := range c.AnswerContains {
+		if !strings.Contains(r.Answer, want) {
+			r.AnswerOK = false
+			r.Problems = append(r.Problems, fmt.Sprintf("回答中缺少 %q", want))
+		}
+	}
+	return
+}
+
+// 对比模型选择的工具与期望
+func checkTools(expect map[string]map[string]interface{}, chosen map[string]interface{}) (toolsOK bool, argsOK bool, problems []string) {
+	toolsOK, argsOK = true, true
+	for name := range expect {
+		if _, ok := chosen[name]; !ok {
+			toolsOK = false
+			problems = append(problems, fmt.Sprintf("未调用工具 <%s>", name))
+		}
+	}
+	for name := range chosen {
+		if _, ok := expect[name]; !ok {
+			toolsOK = false
+			problems = append(problems, fmt.Sprintf("多余的工具 <%s>", name))
+		}
+	}
+
+	for name, wantArgs := range expect {
+		gotArgs, _ := chosen[name].(map[string]interface{})
+		for arg, want := range wantArgs {
+			got, ok := gotArgs[arg]
+			if !ok || !valueEqual(want, got) {
+				argsOK = false
+				problems = append(problems, fmt.Sprintf("<%s>.%s = %v, 期望 %v", name, arg, got, want))
+			}
+		}
+	}
+	sort.Strings(problems)
+	return
+}
+
+// 宽松比较 YAML 期望值与模型返回的 JSON 值：数字按数值比较，字符串忽略大小写，列表逐项比较
+func valueEqual(want interface{}, got interface{}) bool {
+	switch w := want.(type) {
+	case int:
+		g, ok := got.(float64)
+		return ok && g == float64(w)
+	case float64:
+		g, ok := got.(float64)
+		return ok && g == w
+	case string:
+		g, ok := got.(string)
+		return ok && strings.EqualFold(strings.TrimSpace(g), strings.TrimSpace(w))
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for i := range w {
+			if !valueEqual(w[i], g[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return fmt.Sprint(want) == fmt.Sprint(got)
+	}
+}
+
+// 输出文字报告
+func PrintReport(w io.Writer, suite *Suite, reports []ModelReport) {
+	fmt.Fprintf(w, "套件: %s (%d 个用例)\n", suite.Name, len(suite.Cases))
+	for _, m := range reports {
+		fmt.Fprintf(w, "\n== 模型 %s ==\n", m.Model)
+		for _, r := range m.Results {
+			status := "PASS"
+			if !r.Passed() {
+				status = "FAIL"
+			}
+			fmt.Fprintf(w, "[%s] %s (%v)\n", status, r.Case, r.Duration.Round(time.Millisecond))
+			if r.Err != "" {
+				fmt.Fprintf(w, "    错误: %s\n", r.Err)
+			}
+			for _, p := range r.Problems {
+				fmt.Fprintf(w, "    %s\n", p)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\n%-32s %8s %8s %8s %8s\n", "模型", "工具选择", "参数", "回答", "通过率")
+	for _, m := range reports {
+		n := len(m.Results)
+		fmt.Fprintf(w, "%-32s %8s %8s %8s %7.1f%%\n", m.Model,
+			fmt.Sprintf("%d/%d", m.count(func(r CaseResult) bool { return r.ToolsOK }), n),
+			fmt.Sprintf("%d/%d", m.count(func(r CaseResult) bool { return r.ArgsOK }), n),
+			fmt.Sprintf("%d/%d", m.count(func(r CaseResult) bool { return r.AnswerOK }), n),
+			m.SuccessRate()*100,
+		)
+	}
+}
@@ -0,0 +1,62 @@
+package eval
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 评测套件
+type Suite struct {
+	Name     string            `yaml:"name"`
+	Fixtures map[string]string `yaml:"fixtures"` // 工具名 -> 录制的工具输出文件（相对于套件文件）
+	Cases    []Case            `yaml:"cases"`
+
+	fixtureData map[string]string
+}
+
+// 单个评测用例
+type Case struct {
+	Name string `yaml:"name"`
+	// 用户输入
+	Prompt string `yaml:"prompt"`
+	// 期望调用的工具及参数，只校验列出的参数；为空表示不应调用任何工具
+	Expect map[string]map[string]interface{} `yaml:"expect"`
+	// 最终回答中应包含的文字（可选）
+	AnswerContains []string `yaml:"answer_contains"`
+	// 脚本化假后端依次返回的回复
+	Script []string `yaml:"script"`
+}
+
+// 读取评测套件及其引用的工具输出文件
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("read suite failed: %w", err)
+	}
+	var suite Suite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("extract suite failed: %w", err)
+	}
+
+	suite.fixtureData = make(map[string]string)
+	dir := filepath.Dir(path)
+	for tool, file := range suite.Fixtures {
+		b, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return nil, fmt.Errorf("read fixture of <%s> failed: %w", tool, err)
+		}
+		suite.fixtureData[tool] = string(b)
+	}
+	return &suite, nil
+}
+
+// 返回工具的录制输出，格式与真实工具一致
+func (s *Suite) Fixture(tool string) string {
+	if out, ok := s.fixtureData[tool]; ok {
+		return fmt.Sprintf("<%s> 返回结果：%s", tool, out)
+	}
+	return fmt.Sprintf("<%s> 返回结果：(无录制数据)", tool)
+}
@@ -0,0 +1,143 @@
+package tools
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 通过 journalctl -o json 查询 systemd journal
+// LogName 映射：System -> 系统日志，Security -> auth/authpriv 设施，Application -> 全部日志，其他值视为 systemd 单元名
+type JournaldEventSource struct{}
+
+func (s *JournaldEventSource) Name() string {
+	return "journald"
+}
+
+// journald 的 PRIORITY -> 统一等级
+var journaldLevels = map[string]string{
+	"0": LevelCritical,
+	"1": LevelCritical,
+	"2": LevelCritical,
+	"3": LevelError,
+	"4": LevelWarning,
+	"5": LevelInformation,
+	"6": LevelInformation,
+	"7": LevelVerbose,
+}
+
+func (s *JournaldEventSource) Query(q EventQuery) ([]LogEvent, error) {
+	args := []string{"-o", "json", "--no-pager", "-r"}
+	if !q.StartTime.IsZero() {
+		args = append(args, "--since", q.StartTime.Format(time.DateTime))
+	}
+	if !q.EndTime.IsZero() {
+		args = append(args, "--until", q.EndTime.Format(time.DateTime))
+	}
+	// 关键字只能在取出后匹配，此时不在 journalctl 侧限制条数
+	if q.MaxEvents > 0 && len(q.Keywords) == 0 {
+		args = append(args, "-n", fmt.Sprint(q.MaxEvents))
+	}
+	// 同一字段的多个匹配为“或”，不同字段之间为“与”
+	for _, level := range q.Levels {
+		for priority, name := range journaldLevels {
+			if strings.EqualFold(strings.TrimSpace(level), name) {
+				args = append(args, "PRIORITY="+priority)
+			}
+		}
+	}
+	for _, p := range q.Providers {
+		args = append(args, "SYSLOG_IDENTIFIER="+strings.TrimSpace(p))
+	}
+	for _, id := range q.EventIDs {
+		args = append(args, "MESSAGE_ID="+strings.TrimSpace(id))
+	}
+	switch strings.ToLower(q.LogName) {
+	case "", "application":
+	case "system":
+		args = append(args, "--system")
+	case "security":
+		args = append(args, "SYSLOG_FACILITY=4", "SYSLOG_FACILITY=10")
+	default:
+		args = append(args, "-u", q.LogName)
+	}
+
+	out, err := exec.Command("journalctl", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("journalctl failed: %w", err)
+	}
+	events, err := parseJournaldJSON(string(out))
+	if err != nil || len(q.Keywords) == 0 {
+		return events, err
+	}
+
+	var matched []LogEvent
+	for _, e := range events {
+		if q.Match(e) {
+			matched = append(matched, e)
+			if q.MaxEvents > 0 && len(matched) >= q.MaxEvents {
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
+// 解析 journalctl -o json 的输出（每行一个 JSON 对象）
+func parseJournaldJSON(out string) ([]LogEvent, error) {
+	var events []LogEvent
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("extract journal entry failed: %w", err)
+		}
+
+		usec, _ := strconv.ParseInt(journaldString(entry["__REALTIME_TIMESTAMP"]), 10, 64)
+		level, ok := journaldLevels[journaldString(entry["PRIORITY"])]
+		if !ok {
+			level = LevelInformation
+		}
+		source := journaldString(entry["SYSLOG_IDENTIFIER"])
+		if source == "" {
+			source = journaldString(entry["_COMM"])
+		}
+		events = append(events, LogEvent{
+			Time:    time.UnixMicro(usec).Local(),
+			Level:   level,
+			Source:  source,
+			ID:      journaldString(entry["MESSAGE_ID"]),
+			Message: journaldString(entry["MESSAGE"]),
+		})
+	}
+	return events, scanner.Err()
+}
+
+// journald 字段可能是字符串，也可能是字节数组（非 UTF-8 内容）
+func journaldString(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var b []byte
+	var ints []int
+	if err := json.Unmarshal(raw, &ints); err == nil {
+		for _, i := range ints {
+			b = append(b, byte(i))
+		}
+		return string(b)
+	}
+	return string(raw)
+}
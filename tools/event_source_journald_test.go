@@ -0,0 +1,33 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseJournaldJSON(t *testing.T) {
+	got, err := parseJournaldJSON(readFixture(t, "journald", "entries.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEvents(t, got, []LogEvent{
+		{Time: time.UnixMicro(1740795123123456), Level: LevelError, Source: "sshd", Message: "error: kex_exchange_identification: Connection closed by remote host"},
+		{Time: time.UnixMicro(1740795000000000), Level: LevelWarning, Source: "kworker/0:1", Message: "disk full\xff"}, // 没有 SYSLOG_IDENTIFIER，消息为字节数组
+		{Time: time.UnixMicro(1740794000000000), Level: LevelCritical, Source: "kernel", Message: "Out of memory: Killed process 4321 (java)"},
+		{Time: time.UnixMicro(1740793000000000), Level: LevelInformation, Source: "systemd", ID: "39f53479d3a045ac8e11786248231fbf", Message: "Started Daily apt upgrade and clean activities."},
+		{Time: time.UnixMicro(1740792000000000), Level: LevelVerbose, Source: "NetworkManager", Message: "dhcp4 (eth0): state changed"},
+		{Time: time.UnixMicro(1740791000000000), Level: LevelInformation, Source: "myapp", Message: "no priority field"},
+	})
+}
+
+func TestParseJournaldJSONNoOutput(t *testing.T) {
+	for _, out := range []string{"", "\n", " \n\n"} {
+		got, err := parseJournaldJSON(out)
+		if err != nil || len(got) != 0 {
+			t.Errorf("parseJournaldJSON(%q) = %v, %v, want no events", out, got, err)
+		}
+	}
+	if _, err := parseJournaldJSON("-- No entries --"); err == nil {
+		t.Error("parseJournaldJSON accepted non-JSON output")
+	}
+}
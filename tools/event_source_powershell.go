@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"winds-assistant/utils"
+)
+
+// 通过 PowerShell Get-WinEvent 查询 Windows 事件日志
+type PowerShellEventSource struct{}
+
+func (s *PowerShellEventSource) Name() string {
+	return "powershell"
+}
+
+// Get-WinEvent 的数字等级 -> 统一等级
+var winEventLevels = map[int]string{
+	0: LevelInformation, // LogAlways
+	1: LevelCritical,
+	2: LevelError,
+	3: LevelWarning,
+	4: LevelInformation,
+	5: LevelVerbose,
+}
+
+func (s *PowerShellEventSource) Query(q EventQuery) ([]LogEvent, error) {
+	out, _, err := utils.RunCommand("PowerShell", "-Command", winEventCommand(q))
+	if err != nil {
+		// 没有匹配的事件时 Get-WinEvent 会报错，视为空结果
+		if strings.Contains(out, "NoMatchingEventsFound") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(out))
+	}
+	return parseWinEventJSON(out)
+}
+
+// 根据查询条件拼出 Get-WinEvent 命令
+// 等级、来源、事件 ID 和时间范围交给 FilterHashtable 在系统侧过滤，关键字使用 Where-Object 匹配消息
+func winEventCommand(q EventQuery) string {
+	filter := []string{"LogName=" + psQuote(q.LogName)}
+	if !q.StartTime.IsZero() {
+		filter = append(filter, fmt.Sprintf("StartTime=[datetime]'%s'", q.StartTime.Format("2006-01-02T15:04:05")))
+	}
+	if !q.EndTime.IsZero() {
+		filter = append(filter, fmt.Sprintf("EndTime=[datetime]'%s'", q.EndTime.Format("2006-01-02T15:04:05")))
+	}
+	if levels := winEventLevelValues(q.Levels); len(levels) > 0 {
+		filter = append(filter, "Level=@("+strings.Join(levels, ",")+")")
+	}
+	if len(q.Providers) > 0 {
+		var providers []string
+		for _, p := range q.Providers {
+			providers = append(providers, psQuote(p))
+		}
+		filter = append(filter, "ProviderName=@("+strings.Join(providers, ",")+")")
+	}
+	var ids []string
+	for _, id := range q.EventIDs {
+		if _, err := strconv.Atoi(strings.TrimSpace(id)); err == nil {
+			ids = append(ids, strings.TrimSpace(id))
+		}
+	}
+	if len(ids) > 0 {
+		filter = append(filter, "Id=@("+strings.Join(ids, ",")+")")
+	}
+
+	cmd := "Get-WinEvent -FilterHashtable @{ " + strings.Join(filter, "; ") + " }"
+	if len(q.Keywords) == 0 {
+		if q.MaxEvents > 0 {
+			cmd += fmt.Sprintf(" -MaxEvents %d", q.MaxEvents)
+		}
+	} else {
+		// 关键字过滤在取出事件之后进行，数量限制也要放到过滤之后
+		var conds []string
+		for _, k := range q.Keywords {
+			conds = append(conds, "$_.Message -like "+psQuote("*"+k+"*"))
+		}
+		cmd += " | Where-Object { " + strings.Join(conds, " -or ") + " }"
+		if q.MaxEvents > 0 {
+			cmd += fmt.Sprintf(" | Select-Object -First %d", q.MaxEvents)
+		}
+	}
+
+	// 时间以 ISO 8601 输出，避免依赖系统区域格式；@() 保证单个事件时也输出数组
+	return "ConvertTo-Json -Compress -InputObject @(" + cmd +
+		" | Select-Object @{n='TimeCreated';e={$_.TimeCreated.ToString('o')}},Level,ProviderName,Id,Message)"
+}
+
+// 统一等级 -> Get-WinEvent 的数字等级
+func winEventLevelValues(levels []string) (values []string) {
+	for _, level := range levels {
+		for v, name := range winEventLevels {
+			if strings.EqualFold(strings.TrimSpace(level), name) {
+				values = append(values, strconv.Itoa(v))
+			}
+		}
+	}
+	sort.Strings(values)
+	return
+}
+
+// PowerShell 单引号字符串，去掉会破坏命令行参数的双引号
+func psQuote(s string) string {
+	s = strings.ReplaceAll(s, `"`, "")
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// Select-Object 之后的单条事件
+type winEventJSON struct {
+	TimeCreated  string  `json:"TimeCreated"`
+	Level        *int    `json:"Level"`
+	ProviderName string  `json:"ProviderName"`
+	Id           int     `json:"Id"`
+	Message      *string `json:"Message"` // 提供程序缺少消息资源时为 null
+}
+
+// 解析 ConvertTo-Json 的输出，兼容数组与单个对象两种形式
+func parseWinEventJSON(out string) ([]LogEvent, error) {
+	out = strings.TrimSpace(strings.TrimPrefix(out, "\ufeff"))
+	if out == "" {
+		return nil, nil
+	}
+	var rows []winEventJSON
+	if strings.HasPrefix(out, "{") {
+		var row winEventJSON
+		if err := json.Unmarshal([]byte(out), &row); err != nil {
+			return nil, fmt.Errorf("extract events failed: %w", err)
+		}
+		rows = append(rows, row)
+	} else if err := json.Unmarshal([]byte(out), &rows); err != nil {
+		return nil, fmt.Errorf("extract events failed: %w", err)
+	}
+
+	events := make([]LogEvent, 0, len(rows))
+	for _, row := range rows {
+		t := parseWinEventTime(row.TimeCreated)
+		level := LevelInformation
+		if row.Level != nil {
+			if l, ok := winEventLevels[*row.Level]; ok {
+				level = l
+			}
+		}
+		var message string
+		if row.Message != nil {
+			message = *row.Message
+		}
+		events = append(events, LogEvent{
+			Time:    t,
+			Level:   level,
+			Source:  row.ProviderName,
+			ID:      strconv.Itoa(row.Id),
+			Message: message,
+		})
+	}
+	return events, nil
+}
+
+// Windows PowerShell 5.1 的 ConvertTo-Json 将 DateTime 序列化为 /Date(毫秒数)/，可能带时区偏移
+var psDatePattern = regexp.MustCompile(`^/Date\((-?\d+)([+-]\d{4})?\)/$`)
+
+// 解析事件时间：ISO 8601（命令中已转换）或 /Date(...)/，无法解析时为零值
+func parseWinEventTime(s string) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t
+	}
+	if sub := psDatePattern.FindStringSubmatch(s); sub != nil {
+		ms, _ := strconv.ParseInt(sub[1], 10, 64)
+		return time.UnixMilli(ms) // 偏移只影响显示，毫秒数本身是 UTC
+	}
+	return time.Time{}
+}
@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseWinEventJSON(t *testing.T) {
+	cst := time.FixedZone("", 8*3600)
+	tests := []struct {
+		fixture string
+		want    []LogEvent
+	}{
+		{"single.json", []LogEvent{
+			{Time: time.Date(2025, 3, 1, 10, 12, 3, 123456700, cst), Level: LevelError, Source: "Application Error", ID: "1000", Message: "Faulting application name: app.exe"},
+		}},
+		{"array.json", []LogEvent{
+			{Time: time.Date(2025, 3, 1, 10, 12, 3, 123456700, cst), Level: LevelWarning, Source: "Microsoft-Windows-DNS-Client", ID: "1014", Message: "Name resolution for the name example.com timed out."},
+			{Time: time.Date(2025, 3, 1, 9, 0, 0, 0, cst), Level: LevelInformation, Source: "Service Control Manager", ID: "7036", Message: "The Windows Update service entered the running state."},
+		}},
+		{"empty.json", []LogEvent{}},
+		{"msdate.json", []LogEvent{
+			{Time: time.UnixMilli(1740795123123), Level: LevelCritical, Source: "Microsoft-Windows-Kernel-Power", ID: "41", Message: "The system has rebooted without cleanly shutting down first."},
+		}},
+		{"null_message.json", []LogEvent{
+			{Time: time.Date(2025, 3, 1, 10, 12, 3, 0, cst), Level: LevelInformation, Source: "MyService", ID: "0", Message: ""},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "winevent", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseWinEventJSON(string(data))
+			if err != nil {
+				t.Fatalf("parseWinEventJSON: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d events, want %d", len(got), len(tt.want))
+			}
+			for i, e := range got {
+				w := tt.want[i]
+				if !e.Time.Equal(w.Time) || e.Level != w.Level || e.Source != w.Source || e.ID != w.ID || e.Message != w.Message {
+					t.Errorf("event %d = %+v, want %+v", i, e, w)
+				}
+			}
+		})
+	}
+}
+
+func TestParseWinEventJSONNoOutput(t *testing.T) {
+	for _, out := range []string{"", "\ufeff", " \r\n"} {
+		got, err := parseWinEventJSON(out)
+		if err != nil || len(got) != 0 {
+			t.Errorf("parseWinEventJSON(%q) = %v, %v, want no events", out, got, err)
+		}
+	}
+	if _, err := parseWinEventJSON("Get-WinEvent : The specified channel could not be found."); err == nil {
+		t.Error("parseWinEventJSON accepted non-JSON output")
+	}
+}
+
+func TestWinEventCommand(t *testing.T) {
+	const (
+		prefix = "ConvertTo-Json -Compress -InputObject @("
+		suffix = " | Select-Object @{n='TimeCreated';e={$_.TimeCreated.ToString('o')}},Level,ProviderName,Id,Message)"
+	)
+	start := time.Date(2025, 3, 1, 8, 0, 0, 0, time.Local)
+	end := time.Date(2025, 3, 1, 18, 30, 0, 0, time.Local)
+	tests := []struct {
+		name string
+		q    EventQuery
+		want string
+	}{
+		{
+			"log name only",
+			EventQuery{LogName: "System"},
+			"Get-WinEvent -FilterHashtable @{ LogName='System' }",
+		},
+		{
+			"time range and max events",
+			EventQuery{LogName: "Application", EventFilter: EventFilter{StartTime: start, EndTime: end, MaxEvents: 50}},
+			"Get-WinEvent -FilterHashtable @{ LogName='Application'; StartTime=[datetime]'2025-03-01T08:00:00'; EndTime=[datetime]'2025-03-01T18:30:00' } -MaxEvents 50",
+		},
+		{
+			"levels providers and ids",
+			EventQuery{LogName: "System", EventFilter: EventFilter{
+				Levels:    []string{"warning", " Error ", "unknown"},
+				Providers: []string{"Service Control Manager", "Disk"},
+				EventIDs:  []string{"7036", " 41 ", "abc"},
+			}},
+			"Get-WinEvent -FilterHashtable @{ LogName='System'; Level=@(2,3); ProviderName=@('Service Control Manager','Disk'); Id=@(7036,41) }",
+		},
+		{
+			"keywords limit after filtering",
+			EventQuery{LogName: "Application", EventFilter: EventFilter{Keywords: []string{"timeout", "crash"}, MaxEvents: 10}},
+			"Get-WinEvent -FilterHashtable @{ LogName='Application' } | Where-Object { $_.Message -like '*timeout*' -or $_.Message -like '*crash*' } | Select-Object -First 10",
+		},
+		{
+			"quotes are escaped",
+			EventQuery{LogName: `O'Brien"s Log`, EventFilter: EventFilter{Keywords: []string{"it's"}}},
+			"Get-WinEvent -FilterHashtable @{ LogName='O''Briens Log' } | Where-Object { $_.Message -like '*it''s*' }",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := winEventCommand(tt.q); got != prefix+tt.want+suffix {
+				t.Errorf("winEventCommand() =\n%s\nwant\n%s", got, prefix+tt.want+suffix)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 直接读取 syslog 文本文件（没有 journald 的系统）
+// 纯文本日志没有等级字段，等级根据消息中的关键字推断
+type SyslogFileEventSource struct {
+	Files map[string][]string // 日志名称 -> 候选文件，使用第一个存在的文件
+}
+
+func NewSyslogFileEventSource() *SyslogFileEventSource {
+	return &SyslogFileEventSource{Files: map[string][]string{
+		"application": {"/var/log/syslog", "/var/log/messages"},
+		"system":      {"/var/log/syslog", "/var/log/messages", "/var/log/kern.log"},
+		"security":    {"/var/log/auth.log", "/var/log/secure"},
+	}}
+}
+
+func (s *SyslogFileEventSource) Name() string {
+	return "syslog"
+}
+
+// 只读取 Files 中配置的日志，不接受任意文件路径（应用日志请使用 get_log_file，受允许目录限制）
+func (s *SyslogFileEventSource) Query(q EventQuery) ([]LogEvent, error) {
+	candidates, ok := s.Files[strings.ToLower(q.LogName)]
+	if !ok {
+		var names []string
+		for name := range s.Files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown log %q, available: %s", q.LogName, strings.Join(names, ", "))
+	}
+	for _, path := range candidates {
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		events, err := readSyslog(file, q)
+		file.Close()
+		return events, err
+	}
+	return nil, fmt.Errorf("no readable syslog file for %s", q.LogName)
+}
+
+// 逐行读取并只保留满足过滤条件的最新 MaxEvents 条
+func readSyslog(file *os.File, q EventQuery) ([]LogEvent, error) {
+	var events []LogEvent
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	now := time.Now()
+	for scanner.Scan() {
+		e, ok := parseSyslogLine(scanner.Text(), now)
+		if !ok || !q.Match(e) {
+			continue
+		}
+		events = append(events, e)
+		if q.MaxEvents > 0 && len(events) > q.MaxEvents {
+			events = events[1:]
+		}
+	}
+
+	// 按时间从新到旧
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events, scanner.Err()
+}
+
+var (
+	// 2025-03-01T10:12:03.123456+08:00 host proc[123]: msg
+	syslogISOPattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}T\S+)\s+\S+\s+([^:\[\s]+)(?:\[(\d+)\])?:\s*(.*)$`)
+	// Mar  1 10:12:03 host proc[123]: msg
+	syslogBSDPattern = regexp.MustCompile(`^([A-Z][a-z]{2}\s+\d{1,2}\s\d{2}:\d{2}:\d{2})\s+\S+\s+([^:\[\s]+)(?:\[(\d+)\])?:\s*(.*)$`)
+)
+
+// 解析 RFC 3164 与 rsyslog 高精度时间格式的单行日志
+func parseSyslogLine(line string, now time.Time) (LogEvent, bool) {
+	var t time.Time
+	var m []string
+	if m = syslogISOPattern.FindStringSubmatch(line); m != nil {
+		var err error
+		if t, err = time.Parse(time.RFC3339Nano, m[1]); err != nil {
+			return LogEvent{}, false
+		}
+	} else if m = syslogBSDPattern.FindStringSubmatch(line); m != nil {
+		// BSD 格式没有年份，使用当前年份，落在未来时视为去年
+		parsed, err := time.ParseInLocation("Jan _2 15:04:05", strings.Join(strings.Fields(m[1]), " "), time.Local)
+		if err != nil {
+			return LogEvent{}, false
+		}
+		t = parsed.AddDate(now.Year(), 0, 0)
+		if t.After(now.Add(24 * time.Hour)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+	} else {
+		return LogEvent{}, false
+	}
+
+	return LogEvent{
+		Time:    t,
+		Level:   guessLevel(m[4]),
+		Source:  m[2],
+		Message: m[4],
+	}, true
+}
+
+// 根据关键字推断纯文本日志的等级
+func guessLevel(msg string) string {
+	lower := strings.ToLower(msg)
+	switch {
+	case strings.Contains(lower, "panic") || strings.Contains(lower, "fatal") || strings.Contains(lower, "critical"):
+		return LevelCritical
+	case strings.Contains(lower, "error") || strings.Contains(lower, "fail"):
+		return LevelError
+	case strings.Contains(lower, "warn"):
+		return LevelWarning
+	default:
+		return LevelInformation
+	}
+}
@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSyslogLine(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		line string
+		now  time.Time
+		want LogEvent
+		ok   bool
+	}{
+		{
+			"2025-03-01T10:12:03.123456+08:00 myhost sshd[1234]: Failed password for root from 10.0.0.1 port 22 ssh2", now,
+			LogEvent{Time: time.Date(2025, 3, 1, 10, 12, 3, 123456000, time.FixedZone("", 8*3600)), Level: LevelError, Source: "sshd", Message: "Failed password for root from 10.0.0.1 port 22 ssh2"},
+			true,
+		},
+		{
+			"Mar  1 10:15:00 myhost kernel: usb 1-1: new high-speed USB device", now,
+			LogEvent{Time: time.Date(2025, 3, 1, 10, 15, 0, 0, time.Local), Level: LevelInformation, Source: "kernel", Message: "usb 1-1: new high-speed USB device"},
+			true,
+		},
+		{
+			"Mar 12 08:00:01 myhost myapp[77]: WARNING: low memory", now,
+			// 落在未来（超过一天）的时间视为去年
+			LogEvent{Time: time.Date(2024, 3, 12, 8, 0, 1, 0, time.Local), Level: LevelWarning, Source: "myapp", Message: "WARNING: low memory"},
+			true,
+		},
+		{
+			"Dec 31 23:59:59 myhost app: kernel panic - not syncing", time.Date(2025, 1, 1, 0, 10, 0, 0, time.Local),
+			LogEvent{Time: time.Date(2024, 12, 31, 23, 59, 59, 0, time.Local), Level: LevelCritical, Source: "app", Message: "kernel panic - not syncing"},
+			true,
+		},
+		{
+			"Mar  2 01:00:00 myhost app: clock skew", now,
+			// 一天之内的未来时间仍视为今年（时钟偏差）
+			LogEvent{Time: time.Date(2025, 3, 2, 1, 0, 0, 0, time.Local), Level: LevelInformation, Source: "app", Message: "clock skew"},
+			true,
+		},
+		{"-- rotated --", now, LogEvent{}, false},
+		{"2025-13-45T99:00:00Z myhost app: bad time", now, LogEvent{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseSyslogLine(tt.line, tt.now)
+		if ok != tt.ok {
+			t.Errorf("parseSyslogLine(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			continue
+		}
+		if ok && (!got.Time.Equal(tt.want.Time) || got.Level != tt.want.Level || got.Source != tt.want.Source || got.Message != tt.want.Message) {
+			t.Errorf("parseSyslogLine(%q) =\n%+v\nwant\n%+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestSyslogFileEventSource(t *testing.T) {
+	fixture := filepath.Join("testdata", "syslog", "syslog")
+	s := &SyslogFileEventSource{Files: map[string][]string{
+		"application": {filepath.Join(t.TempDir(), "missing"), fixture}, // 使用第一个存在的文件
+		"security":    {filepath.Join(t.TempDir(), "missing")},
+	}}
+
+	got, err := s.Query(EventQuery{LogName: "Application", EventFilter: EventFilter{Levels: []string{"warning", "critical"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 从新到旧
+	var summary []string
+	for _, e := range got {
+		summary = append(summary, e.Level+" "+e.Source+": "+e.Message)
+	}
+	want := []string{"Critical myapp: kernel panic - not syncing", "Warning myapp: WARNING: low memory"}
+	if strings.Join(summary, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Query() =\n%s\nwant\n%s", strings.Join(summary, "\n"), strings.Join(want, "\n"))
+	}
+
+	got, err = s.Query(EventQuery{LogName: "application", EventFilter: EventFilter{MaxEvents: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Source != "CRON" || got[1].Message != "kernel panic - not syncing" {
+		t.Errorf("Query(MaxEvents: 2) = %+v, want the last 2 lines newest first", got)
+	}
+
+	// 不在 Files 中的日志名（包括文件路径）被拒绝
+	for _, name := range []string{"/etc/shadow", "../syslog", "kern"} {
+		if _, err := s.Query(EventQuery{LogName: name}); err == nil || !strings.Contains(err.Error(), "unknown log") {
+			t.Errorf("Query(%q) error = %v, want unknown log", name, err)
+		}
+	}
+	if _, err := s.Query(EventQuery{LogName: "security"}); err == nil {
+		t.Error("Query(security) succeeded without a readable file")
+	}
+}
@@ -0,0 +1,770 @@
+// EVTX 文件格式参考：
+// https://github.com/libyal/libevtx/blob/main/documentation/Windows%20XML%20Event%20Log%20(EVTX).asciidoc
+
+package tools
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	evtxFileHeaderSize  = 4096
+	evtxChunkSize       = 65536
+	evtxChunkHeaderSize = 512
+	evtxRecordHeader    = 24
+)
+
+var (
+	evtxFileMagic   = []byte("ElfFile\x00")
+	evtxChunkMagic  = []byte("ElfChnk\x00")
+	evtxRecordMagic = []byte{0x2a, 0x2a, 0x00, 0x00}
+)
+
+// BinXML 标记
+const (
+	bxEOF               = 0x00
+	bxOpenStartElement  = 0x01
+	bxCloseStartElement = 0x02
+	bxCloseEmptyElement = 0x03
+	bxEndElement        = 0x04
+	bxValue             = 0x05
+	bxAttribute         = 0x06
+	bxCDATA             = 0x07
+	bxCharRef           = 0x08
+	bxEntityRef         = 0x09
+	bxPITarget          = 0x0a
+	bxPIData            = 0x0b
+	bxTemplateInstance  = 0x0c
+	bxNormalSubst       = 0x0d
+	bxOptionalSubst     = 0x0e
+	bxFragmentHeader    = 0x0f
+	bxHasMoreFlag       = 0x40
+)
+
+// BinXML 值类型
+const (
+	vtNull      = 0x00
+	vtString    = 0x01
+	vtAnsi      = 0x02
+	vtInt8      = 0x03
+	vtUInt8     = 0x04
+	vtInt16     = 0x05
+	vtUInt16    = 0x06
+	vtInt32     = 0x07
+	vtUInt32    = 0x08
+	vtInt64     = 0x09
+	vtUInt64    = 0x0a
+	vtReal32    = 0x0b
+	vtReal64    = 0x0c
+	vtBool      = 0x0d
+	vtBinary    = 0x0e
+	vtGUID      = 0x0f
+	vtSizeT     = 0x10
+	vtFileTime  = 0x11
+	vtSysTime   = 0x12
+	vtSID       = 0x13
+	vtHexInt32  = 0x14
+	vtHexInt64  = 0x15
+	vtBinXML    = 0x21
+	vtArrayFlag = 0x80
+)
+
+// ** 解析后的 XML 树 **
+type xmlNode interface{}
+
+type xmlElement struct {
+	Name     string
+	Attrs    []xmlAttr
+	Children []xmlNode
+}
+
+type xmlAttr struct {
+	Name  string
+	Value []xmlNode
+}
+
+type xmlText string
+
+// 模板中的占位符，实例化时替换为对应的值
+type xmlSubst struct {
+	Index    int
+	Optional bool
+}
+
+// 模板实例：模板定义 + 替换值
+type xmlTemplateInstance struct {
+	Def    []xmlNode
+	Values []xmlNode
+}
+
+// EVTX 中的一条事件记录
+type EvtxRecord struct {
+	RecordID uint64
+	Written  time.Time
+	Root     *xmlElement // 展开模板后的 <Event> 元素
+}
+
+// EVTX 读取器，按块依次解析事件记录
+type EvtxReader struct {
+	file       *os.File
+	chunkCount int
+	Skipped    int // 解析失败而跳过的记录数
+}
+
+// 打开 EVTX 文件并校验文件头
+func OpenEvtx(path string) (*EvtxReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, evtxFileHeaderSize)
+	if _, err := io.ReadFull(file, header); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("read evtx header failed: %w", err)
+	}
+	if !bytes.Equal(header[:8], evtxFileMagic) {
+		file.Close()
+		return nil, fmt.Errorf("%s is not an evtx file", path)
+	}
+
+	// 文件头中的块数在日志写满循环后可能不准确，以文件大小为准
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &EvtxReader{
+		file:       file,
+		chunkCount: int((info.Size() - evtxFileHeaderSize) / evtxChunkSize),
+	}, nil
+}
+
+func (r *EvtxReader) Close() error {
+	return r.file.Close()
+}
+
+// 遍历所有记录，fn 返回 false 时停止
+func (r *EvtxReader) Records(fn func(EvtxRecord) bool) error {
+	chunk := make([]byte, evtxChunkSize)
+	for i := 0; i < r.chunkCount; i++ {
+		if _, err := r.file.ReadAt(chunk, evtxFileHeaderSize+int64(i)*evtxChunkSize); err != nil {
+			return fmt.Errorf("read chunk %d failed: %w", i, err)
+		}
+		if !bytes.Equal(chunk[:8], evtxChunkMagic) {
+			continue // 未使用的空块
+		}
+		if !r.chunkRecords(chunk, fn) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// 解析单个块中的记录
+func (r *EvtxReader) chunkRecords(chunk []byte, fn func(EvtxRecord) bool) bool {
+	freeSpace := int(binary.LittleEndian.Uint32(chunk[48:]))
+	if freeSpace > len(chunk) || freeSpace < evtxChunkHeaderSize {
+		freeSpace = len(chunk)
+	}
+	templates := map[uint32][]xmlNode{}
+	expanding := map[uint32]bool{}
+
+	for pos := evtxChunkHeaderSize; pos+evtxRecordHeader <= freeSpace; {
+		if !bytes.Equal(chunk[pos:pos+4], evtxRecordMagic) {
+			break
+		}
+		size := int(binary.LittleEndian.Uint32(chunk[pos+4:]))
+		if size < evtxRecordHeader+4 || pos+size > len(chunk) {
+			break
+		}
+		p := &bxParser{chunk: chunk, pos: pos + evtxRecordHeader, templates: templates, expanding: expanding}
+		record, err := p.parseRecord(pos, size)
+		if err != nil || record.Root == nil {
+			r.Skipped++
+		} else if !fn(record) {
+			return false
+		}
+		pos += size
+	}
+	return true
+}
+
+// 解析位于 pos、大小为 size 的单条记录；损坏的记录即使触发 panic 也只跳过该条
+func (p *bxParser) parseRecord(pos, size int) (record EvtxRecord, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("record at %d: %v", pos, r)
+		}
+	}()
+	record = EvtxRecord{
+		RecordID: binary.LittleEndian.Uint64(p.chunk[pos+8:]),
+		Written:  fileTimeToTime(binary.LittleEndian.Uint64(p.chunk[pos+16:])),
+	}
+	nodes, err := p.parseNodes(pos + size - 4)
+	if err != nil {
+		return record, err
+	}
+	record.Root = findRoot(expandNodes(nodes, nil))
+	return record, nil
+}
+
+// ** BinXML 解析 **
+// 所有偏移都相对于块起始位置，名称和模板定义可能内联在当前位置，也可能引用块中更早的位置
+type bxParser struct {
+	chunk     []byte
+	pos       int
+	templates map[uint32][]xmlNode
+	expanding map[uint32]bool // 正在解析的模板定义偏移，防止模板互相引用导致无限递归
+}
+
+func (p *bxParser) need(n int) error {
+	if p.pos+n > len(p.chunk) {
+		return fmt.Errorf("binxml out of range at %d", p.pos)
+	}
+	return nil
+}
+
+func (p *bxParser) u8() (byte, error) {
+	if err := p.need(1); err != nil {
+		return 0, err
+	}
+	b := p.chunk[p.pos]
+	p.pos++
+	return b, nil
+}
+
+func (p *bxParser) u16() (uint16, error) {
+	if err := p.need(2); err != nil {
+		return 0, err
+	}
+	v := binary.LittleEndian.Uint16(p.chunk[p.pos:])
+	p.pos += 2
+	return v, nil
+}
+
+func (p *bxParser) u32() (uint32, error) {
+	if err := p.need(4); err != nil {
+		return 0, err
+	}
+	v := binary.LittleEndian.Uint32(p.chunk[p.pos:])
+	p.pos += 4
+	return v, nil
+}
+
+// 读取 UTF-16 字符串（长度为字符数）
+func (p *bxParser) utf16(count int) (string, error) {
+	if err := p.need(count * 2); err != nil {
+		return "", err
+	}
+	s := decodeUTF16(p.chunk[p.pos : p.pos+count*2])
+	p.pos += count * 2
+	return s, nil
+}
+
+// 读取名称：偏移等于当前位置时名称内联在此处，否则到块中对应位置读取
+func (p *bxParser) name(offset uint32) (string, error) {
+	if int(offset) == p.pos {
+		s, err := readName(p.chunk, p.pos)
+		if err != nil {
+			return "", err
+		}
+		p.pos += 8 + int(binary.LittleEndian.Uint16(p.chunk[p.pos+6:]))*2 + 2
+		return s, nil
+	}
+	return readName(p.chunk, int(offset))
+}
+
+// 名称结构：下一个名称偏移(4) + 哈希(2) + 字符数(2) + UTF-16 字符 + 结尾 0(2)
+func readName(chunk []byte, offset int) (string, error) {
+	if offset+8 > len(chunk) {
+		return "", fmt.Errorf("name offset %d out of range", offset)
+	}
+	count := int(binary.LittleEndian.Uint16(chunk[offset+6:]))
+	end := offset + 8 + count*2
+	if end > len(chunk) {
+		return "", fmt.Errorf("name at %d out of range", offset)
+	}
+	return decodeUTF16(chunk[offset+8 : end]), nil
+}
+
+// 解析节点直到 EOF、EndElement 或到达 end
+func (p *bxParser) parseNodes(end int) ([]xmlNode, error) {
+	end = min(end, len(p.chunk)) // 大小字段来自文件，不可信
+	var nodes []xmlNode
+	for p.pos < end {
+		token := p.chunk[p.pos]
+		switch token &^ bxHasMoreFlag {
+		case bxEOF:
+			p.pos++
+			return nodes, nil
+		case bxEndElement:
+			return nodes, nil
+		case bxCloseStartElement, bxCloseEmptyElement:
+			return nodes, nil
+		case bxAttribute:
+			return nodes, nil
+		}
+
+		node, err := p.parseNode()
+		if err != nil {
+			return nil, err
+		}
+		if node != nil {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+// 解析单个节点
+func (p *bxParser) parseNode() (xmlNode, error) {
+	token, err := p.u8()
+	if err != nil {
+		return nil, err
+	}
+	switch token &^ bxHasMoreFlag {
+	case bxFragmentHeader:
+		p.pos += 3 // 主版本、次版本、标志
+		return nil, nil
+	case bxOpenStartElement:
+		return p.parseElement(token)
+	case bxValue:
+		vt, err := p.u8()
+		if err != nil {
+			return nil, err
+		}
+		if vt != vtString {
+			return nil, fmt.Errorf("unsupported value node type 0x%x", vt)
+		}
+		n, err := p.u16()
+		if err != nil {
+			return nil, err
+		}
+		s, err := p.utf16(int(n))
+		return xmlText(s), err
+	case bxCDATA:
+		n, err := p.u16()
+		if err != nil {
+			return nil, err
+		}
+		s, err := p.utf16(int(n))
+		return xmlText(s), err
+	case bxCharRef:
+		v, err := p.u16()
+		return xmlText(string(rune(v))), err
+	case bxEntityRef:
+		offset, err := p.u32()
+		if err != nil {
+			return nil, err
+		}
+		name, err := p.name(offset)
+		return xmlText(entityText(name)), err
+	case bxPITarget:
+		offset, err := p.u32()
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.name(offset)
+		return nil, err
+	case bxPIData:
+		n, err := p.u16()
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.utf16(int(n))
+		return nil, err
+	case bxNormalSubst, bxOptionalSubst:
+		index, err := p.u16()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.u8(); err != nil { // 值类型
+			return nil, err
+		}
+		return xmlSubst{Index: int(index), Optional: token == bxOptionalSubst}, nil
+	case bxTemplateInstance:
+		return p.parseTemplateInstance()
+	}
+	return nil, fmt.Errorf("unknown binxml token 0x%x at %d", token, p.pos-1)
+}
+
+// 解析元素（标记已读取）
+func (p *bxParser) parseElement(token byte) (xmlNode, error) {
+	if _, err := p.u16(); err != nil { // 依赖标识
+		return nil, err
+	}
+	size, err := p.u32()
+	if err != nil {
+		return nil, err
+	}
+	end := min(p.pos+int(size), len(p.chunk))
+	offset, err := p.u32()
+	if err != nil {
+		return nil, err
+	}
+	elem := &xmlElement{}
+	if elem.Name, err = p.name(offset); err != nil {
+		return nil, err
+	}
+
+	// 属性列表
+	if token&bxHasMoreFlag != 0 {
+		if _, err := p.u32(); err != nil { // 属性列表大小
+			return nil, err
+		}
+		for p.pos < end && p.chunk[p.pos]&^bxHasMoreFlag == bxAttribute {
+			p.pos++
+			offset, err := p.u32()
+			if err != nil {
+				return nil, err
+			}
+			attr := xmlAttr{}
+			if attr.Name, err = p.name(offset); err != nil {
+				return nil, err
+			}
+			if attr.Value, err = p.parseNodes(end); err != nil {
+				return nil, err
+			}
+			elem.Attrs = append(elem.Attrs, attr)
+		}
+	}
+
+	closing, err := p.u8()
+	if err != nil {
+		return nil, err
+	}
+	switch closing {
+	case bxCloseEmptyElement:
+		return elem, nil
+	case bxCloseStartElement:
+		if elem.Children, err = p.parseNodes(len(p.chunk)); err != nil {
+			return nil, err
+		}
+		if endToken, err := p.u8(); err != nil || endToken != bxEndElement {
+			return nil, fmt.Errorf("element <%s> not closed at %d", elem.Name, p.pos-1)
+		}
+		return elem, nil
+	}
+	return nil, fmt.Errorf("unexpected token 0x%x after <%s>", closing, elem.Name)
+}
+
+// 解析模板实例：模板定义（内联或引用）+ 替换值数组
+func (p *bxParser) parseTemplateInstance() (xmlNode, error) {
+	if _, err := p.u8(); err != nil {
+		return nil, err
+	}
+	if _, err := p.u32(); err != nil { // 模板 ID
+		return nil, err
+	}
+	defOffset, err := p.u32()
+	if err != nil {
+		return nil, err
+	}
+
+	def, ok := p.templates[defOffset]
+	if !ok {
+		if p.expanding[defOffset] {
+			return nil, fmt.Errorf("template at %d references itself", defOffset)
+		}
+		p.expanding[defOffset] = true
+		defer delete(p.expanding, defOffset)
+	}
+	if int(defOffset) == p.pos {
+		// 内联定义：下一个模板偏移(4) + GUID(16) + 数据大小(4) + 数据
+		if err := p.need(24); err != nil {
+			return nil, err
+		}
+		size := int(binary.LittleEndian.Uint32(p.chunk[p.pos+20:]))
+		p.pos += 24
+		end := min(p.pos+size, len(p.chunk))
+		if def, err = p.parseNodes(end); err != nil {
+			return nil, err
+		}
+		p.pos = end
+		p.templates[defOffset] = def
+	} else if !ok {
+		sub := &bxParser{chunk: p.chunk, pos: int(defOffset), templates: p.templates, expanding: p.expanding}
+		if err := sub.need(24); err != nil {
+			return nil, err
+		}
+		size := int(binary.LittleEndian.Uint32(p.chunk[sub.pos+20:]))
+		sub.pos += 24
+		if def, err = sub.parseNodes(sub.pos + size); err != nil {
+			return nil, err
+		}
+		p.templates[defOffset] = def
+	}
+
+	// 替换值：数量(4) + 描述符(大小 2 + 类型 1 + 保留 1) * 数量 + 值数据
+	count, err := p.u32()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.need(int(count) * 4); err != nil {
+		return nil, err
+	}
+	type descriptor struct {
+		size int
+		vt   byte
+	}
+	descs := make([]descriptor, count)
+	for i := range descs {
+		descs[i].size = int(binary.LittleEndian.Uint16(p.chunk[p.pos:]))
+		descs[i].vt = p.chunk[p.pos+2]
+		p.pos += 4
+	}
+
+	values := make([]xmlNode, count)
+	for i, d := range descs {
+		if err := p.need(d.size); err != nil {
+			return nil, err
+		}
+		if d.vt == vtBinXML {
+			sub := &bxParser{chunk: p.chunk, pos: p.pos, templates: p.templates, expanding: p.expanding}
+			nodes, err := sub.parseNodes(p.pos + d.size)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = nodes
+		} else if d.size > 0 && d.vt != vtNull {
+			values[i] = xmlText(formatBXValue(d.vt, p.chunk[p.pos:p.pos+d.size]))
+		}
+		p.pos += d.size
+	}
+	return xmlTemplateInstance{Def: def, Values: values}, nil
+}
+
+// ** 模板展开 **
+// 将模板实例中的占位符替换为对应的值，得到只包含元素和文本的树
+func expandNodes(nodes []xmlNode, values []xmlNode) []xmlNode {
+	var out []xmlNode
+	for _, n := range nodes {
+		switch v := n.(type) {
+		case *xmlElement:
+			elem := &xmlElement{Name: v.Name}
+			for _, a := range v.Attrs {
+				elem.Attrs = append(elem.Attrs, xmlAttr{Name: a.Name, Value: expandNodes(a.Value, values)})
+			}
+			elem.Children = expandNodes(v.Children, values)
+			out = append(out, elem)
+		case xmlText:
+			out = append(out, v)
+		case xmlSubst:
+			if v.Index < len(values) && values[v.Index] != nil {
+				switch value := values[v.Index].(type) {
+				case []xmlNode:
+					out = append(out, expandNodes(value, nil)...)
+				default:
+					out = append(out, value)
+				}
+			}
+		case xmlTemplateInstance:
+			out = append(out, expandNodes(v.Def, v.Values)...)
+		}
+	}
+	return out
+}
+
+func findRoot(nodes []xmlNode) *xmlElement {
+	for _, n := range nodes {
+		if e, ok := n.(*xmlElement); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// 子元素
+func (e *xmlElement) Child(name string) *xmlElement {
+	if e == nil {
+		return nil
+	}
+	for _, c := range e.Children {
+		if ce, ok := c.(*xmlElement); ok && ce.Name == name {
+			return ce
+		}
+	}
+	return nil
+}
+
+// 属性值
+func (e *xmlElement) Attr(name string) string {
+	if e == nil {
+		return ""
+	}
+	for _, a := range e.Attrs {
+		if a.Name == name {
+			return nodesText(a.Value)
+		}
+	}
+	return ""
+}
+
+// 元素的直接文本内容
+func (e *xmlElement) Text() string {
+	if e == nil {
+		return ""
+	}
+	return nodesText(e.Children)
+}
+
+func nodesText(nodes []xmlNode) string {
+	var sb strings.Builder
+	for _, n := range nodes {
+		if t, ok := n.(xmlText); ok {
+			sb.WriteString(string(t))
+		}
+	}
+	return sb.String()
+}
+
+// ** 值格式化 **
+// 定长类型的字节数（SizeT、HexInt 为 4 或 8 字节，单独处理）
+var bxValueSizes = map[byte]int{
+	vtInt8: 1, vtUInt8: 1, vtInt16: 2, vtUInt16: 2, vtInt32: 4, vtUInt32: 4,
+	vtInt64: 8, vtUInt64: 8, vtReal32: 4, vtReal64: 8, vtBool: 4, vtGUID: 16,
+	vtFileTime: 8, vtSysTime: 16, vtHexInt32: 4, vtHexInt64: 8,
+}
+
+func formatBXValue(vt byte, b []byte) string {
+	if vt&vtArrayFlag != 0 {
+		return formatBXArray(vt&^vtArrayFlag, b)
+	}
+	if size := bxValueSizes[vt]; len(b) < size && vt != vtHexInt32 && vt != vtHexInt64 {
+		return "?" // 值的长度与类型不符
+	}
+	le := binary.LittleEndian
+	switch vt {
+	case vtString:
+		return strings.TrimRight(decodeUTF16(b), "\x00")
+	case vtAnsi:
+		return strings.TrimRight(string(b), "\x00")
+	case vtInt8:
+		return fmt.Sprint(int8(b[0]))
+	case vtUInt8:
+		return fmt.Sprint(b[0])
+	case vtInt16:
+		return fmt.Sprint(int16(le.Uint16(b)))
+	case vtUInt16:
+		return fmt.Sprint(le.Uint16(b))
+	case vtInt32:
+		return fmt.Sprint(int32(le.Uint32(b)))
+	case vtUInt32:
+		return fmt.Sprint(le.Uint32(b))
+	case vtInt64:
+		return fmt.Sprint(int64(le.Uint64(b)))
+	case vtUInt64:
+		return fmt.Sprint(le.Uint64(b))
+	case vtReal32:
+		return fmt.Sprint(math.Float32frombits(le.Uint32(b)))
+	case vtReal64:
+		return fmt.Sprint(math.Float64frombits(le.Uint64(b)))
+	case vtBool:
+		return fmt.Sprint(le.Uint32(b) != 0)
+	case vtGUID:
+		return fmt.Sprintf("{%08X-%04X-%04X-%X-%X}", le.Uint32(b), le.Uint16(b[4:]), le.Uint16(b[6:]), b[8:10], b[10:16])
+	case vtSizeT, vtHexInt32, vtHexInt64:
+		if len(b) == 4 {
+			return fmt.Sprintf("0x%x", le.Uint32(b))
+		}
+		if len(b) == 8 {
+			return fmt.Sprintf("0x%x", le.Uint64(b))
+		}
+	case vtFileTime:
+		return fileTimeToTime(le.Uint64(b)).UTC().Format(time.RFC3339Nano)
+	case vtSysTime:
+		return time.Date(int(le.Uint16(b)), time.Month(le.Uint16(b[2:])), int(le.Uint16(b[6:])),
+			int(le.Uint16(b[8:])), int(le.Uint16(b[10:])), int(le.Uint16(b[12:])), int(le.Uint16(b[14:]))*int(time.Millisecond),
+			time.UTC).Format(time.RFC3339Nano)
+	case vtSID:
+		return formatSID(b)
+	}
+	return strings.ToUpper(hex.EncodeToString(b))
+}
+
+// 数组：字符串数组以 0 分隔，其余类型按固定长度切分
+func formatBXArray(vt byte, b []byte) string {
+	var items []string
+	switch vt {
+	case vtString:
+		for _, s := range strings.Split(decodeUTF16(b), "\x00") {
+			if s != "" {
+				items = append(items, s)
+			}
+		}
+	case vtAnsi:
+		for _, s := range strings.Split(string(b), "\x00") {
+			if s != "" {
+				items = append(items, s)
+			}
+		}
+	default:
+		size := bxValueSizes[vt]
+		if size == 0 {
+			return strings.ToUpper(hex.EncodeToString(b))
+		}
+		for i := 0; i+size <= len(b); i += size {
+			items = append(items, formatBXValue(vt, b[i:i+size]))
+		}
+	}
+	return strings.Join(items, ", ")
+}
+
+// SID：修订号(1) + 子授权数(1) + 标识符授权(6, 大端) + 子授权(4 * n, 小端)
+func formatSID(b []byte) string {
+	if len(b) < 8 {
+		return strings.ToUpper(hex.EncodeToString(b))
+	}
+	var authority uint64
+	for _, c := range b[2:8] {
+		authority = authority<<8 | uint64(c)
+	}
+	s := fmt.Sprintf("S-%d-%d", b[0], authority)
+	for i := 0; i < int(b[1]) && 8+i*4+4 <= len(b); i++ {
+		s += fmt.Sprintf("-%d", binary.LittleEndian.Uint32(b[8+i*4:]))
+	}
+	return s
+}
+
+// FILETIME：自 1601-01-01 起的 100 纳秒数
+func fileTimeToTime(ft uint64) time.Time {
+	const epochDiff = 116444736000000000
+	if ft < epochDiff {
+		return time.Time{}
+	}
+	ticks := ft - epochDiff
+	return time.Unix(int64(ticks/10000000), int64(ticks%10000000)*100)
+}
+
+func decodeUTF16(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(u))
+}
+
+func entityText(name string) string {
+	switch name {
+	case "lt":
+		return "<"
+	case "gt":
+		return ">"
+	case "amp":
+		return "&"
+	case "quot":
+		return "\""
+	case "apos":
+		return "'"
+	}
+	return "&" + name + ";"
+}
@@ -0,0 +1,409 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+var updateEvtx = flag.Bool("update-evtx", false, "regenerate testdata/evtx/system.evtx")
+
+// ** 合成 EVTX 块 **
+// 只写出读取器用到的字段，偏移均相对于块起始位置
+type evtxChunkBuilder struct {
+	buf []byte
+}
+
+func newEvtxChunk() *evtxChunkBuilder {
+	b := &evtxChunkBuilder{buf: make([]byte, evtxChunkHeaderSize)}
+	copy(b.buf, evtxChunkMagic)
+	return b
+}
+
+func (b *evtxChunkBuilder) pos() int     { return len(b.buf) }
+func (b *evtxChunkBuilder) u8(v byte)    { b.buf = append(b.buf, v) }
+func (b *evtxChunkBuilder) u16(v int)    { b.buf = binary.LittleEndian.AppendUint16(b.buf, uint16(v)) }
+func (b *evtxChunkBuilder) u32(v int)    { b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(v)) }
+func (b *evtxChunkBuilder) u64(v uint64) { b.buf = binary.LittleEndian.AppendUint64(b.buf, v) }
+
+// 先写 4 字节占位，返回的函数把从占位之后到当前位置的长度填回去
+func (b *evtxChunkBuilder) sizeField() func() {
+	at := b.pos()
+	b.u32(0)
+	return func() { binary.LittleEndian.PutUint32(b.buf[at:], uint32(b.pos()-at-4)) }
+}
+
+func (b *evtxChunkBuilder) chars(s string) {
+	for _, c := range utf16.Encode([]rune(s)) {
+		b.u16(int(c))
+	}
+}
+
+// 内联名称，返回名称结构的偏移供之后引用
+func (b *evtxChunkBuilder) inlineName(s string) int {
+	offset := b.pos() + 4
+	b.u32(offset)
+	b.u32(0) // 下一个名称偏移
+	b.u16(0) // 哈希
+	b.u16(len(utf16.Encode([]rune(s))))
+	b.chars(s)
+	b.u16(0)
+	return offset
+}
+
+// 元素：name 写出名称，attrs / children 为 nil 表示没有属性 / 子节点
+func (b *evtxChunkBuilder) element(name func(), attrs, children func()) {
+	if attrs != nil {
+		b.u8(bxOpenStartElement | bxHasMoreFlag)
+	} else {
+		b.u8(bxOpenStartElement)
+	}
+	b.u16(0)
+	done := b.sizeField()
+	name()
+	if attrs != nil {
+		attrsDone := b.sizeField()
+		attrs()
+		attrsDone()
+	}
+	if children == nil {
+		b.u8(bxCloseEmptyElement)
+	} else {
+		b.u8(bxCloseStartElement)
+		children()
+		b.u8(bxEndElement)
+	}
+	done()
+}
+
+func (b *evtxChunkBuilder) attr(name func(), value func()) {
+	b.u8(bxAttribute)
+	name()
+	value()
+}
+
+func (b *evtxChunkBuilder) text(s string) {
+	b.u8(bxValue)
+	b.u8(vtString)
+	b.u16(len(utf16.Encode([]rune(s))))
+	b.chars(s)
+}
+
+func (b *evtxChunkBuilder) subst(index int, vt byte) {
+	b.u8(bxNormalSubst)
+	b.u16(index)
+	b.u8(vt)
+}
+
+func (b *evtxChunkBuilder) fragmentHeader() {
+	b.buf = append(b.buf, bxFragmentHeader, 1, 1, 0)
+}
+
+// 事件模板：Provider/@Name、EventID、Level、TimeCreated/@SystemTime、Data 分别对应替换值 0-4
+func (b *evtxChunkBuilder) eventTemplate() {
+	b.u32(0)                                   // 下一个模板偏移
+	b.buf = append(b.buf, make([]byte, 16)...) // GUID
+	done := b.sizeField()
+	b.fragmentHeader()
+	var nameAttr int
+	b.element(func() { b.inlineName("Event") }, nil, func() {
+		b.element(func() { b.inlineName("System") }, nil, func() {
+			b.element(func() { b.inlineName("Provider") }, func() {
+				b.attr(func() { nameAttr = b.inlineName("Name") }, func() { b.subst(0, vtString) })
+			}, nil)
+			b.element(func() { b.inlineName("EventID") }, nil, func() { b.subst(1, vtUInt16) })
+			b.element(func() { b.inlineName("Level") }, nil, func() { b.subst(2, vtUInt8) })
+			b.element(func() { b.inlineName("TimeCreated") }, func() {
+				b.attr(func() { b.inlineName("SystemTime") }, func() { b.subst(3, vtFileTime) })
+			}, nil)
+		})
+		b.element(func() { b.inlineName("EventData") }, nil, func() {
+			b.element(func() { b.inlineName("Data") }, func() {
+				b.attr(func() { b.u32(nameAttr) }, func() { b.text("param1") }) // 引用前面的名称
+			}, func() { b.subst(4, vtString) })
+		})
+	})
+	b.u8(bxEOF)
+	done()
+}
+
+type evtxTestEvent struct {
+	provider string
+	id       int
+	level    byte
+	time     time.Time
+	data     string
+}
+
+func fileTime(t time.Time) uint64 {
+	return uint64(t.UnixNano()/100) + 116444736000000000
+}
+
+// 追加一条记录；template 为 0 时内联模板定义并返回其偏移，否则引用该偏移处的模板
+func (b *evtxChunkBuilder) record(id uint64, e evtxTestEvent, template int) int {
+	start := b.pos()
+	b.buf = append(b.buf, evtxRecordMagic...)
+	b.u32(0) // 大小，最后填写
+	b.u64(id)
+	b.u64(fileTime(e.time))
+
+	b.fragmentHeader()
+	b.u8(bxTemplateInstance)
+	b.u8(1)
+	b.u32(1) // 模板 ID
+	if template == 0 {
+		template = b.pos() + 4
+		b.u32(template)
+		b.eventTemplate()
+	} else {
+		b.u32(template)
+	}
+
+	provider := utf16.Encode([]rune(e.provider))
+	data := utf16.Encode([]rune(e.data))
+	values := []struct {
+		size int
+		vt   byte
+	}{{len(provider) * 2, vtString}, {2, vtUInt16}, {1, vtUInt8}, {8, vtFileTime}, {len(data) * 2, vtString}}
+	b.u32(len(values))
+	for _, v := range values {
+		b.u16(v.size)
+		b.u8(v.vt)
+		b.u8(0)
+	}
+	b.chars(e.provider)
+	b.u16(e.id)
+	b.u8(e.level)
+	b.u64(fileTime(e.time))
+	b.chars(e.data)
+	b.u8(bxEOF)
+
+	size := b.pos() + 4 - start
+	b.u32(size)
+	binary.LittleEndian.PutUint32(b.buf[start+4:], uint32(size))
+	return template
+}
+
+func (b *evtxChunkBuilder) bytes() []byte {
+	chunk := make([]byte, evtxChunkSize)
+	copy(chunk, b.buf)
+	binary.LittleEndian.PutUint32(chunk[48:], uint32(len(b.buf))) // 空闲空间起始偏移
+	return chunk
+}
+
+var evtxTestEvents = []evtxTestEvent{
+	{"Service Control Manager", 7036, 4, time.Date(2025, 3, 1, 2, 0, 0, 0, time.UTC), "Windows Update"},
+	{"Disk", 7, 2, time.Date(2025, 3, 1, 2, 5, 30, 500000000, time.UTC), `\Device\Harddisk1\DR1`},
+	{"Microsoft-Windows-Kernel-Power", 41, 1, time.Date(2025, 3, 1, 3, 0, 0, 0, time.UTC), "0"},
+}
+
+// 两个块：第一块两条记录（第二条引用第一条的模板），第二块一条记录
+func buildTestEvtx() []byte {
+	file := make([]byte, evtxFileHeaderSize)
+	copy(file, evtxFileMagic)
+
+	c0 := newEvtxChunk()
+	template := c0.record(1, evtxTestEvents[0], 0)
+	c0.record(2, evtxTestEvents[1], template)
+	c1 := newEvtxChunk()
+	c1.record(3, evtxTestEvents[2], 0)
+	return append(append(file, c0.bytes()...), c1.bytes()...)
+}
+
+// 测试用的 EVTX 文件内容；-update-evtx 时重新生成
+func evtxFixture(t *testing.T) []byte {
+	t.Helper()
+	path := filepath.Join("testdata", "evtx", "system.evtx")
+	if *updateEvtx {
+		if err := os.WriteFile(path, buildTestEvtx(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func writeTemp(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.evtx")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// 第 i 个块在文件中的偏移
+func evtxChunkOffset(i int) int {
+	return evtxFileHeaderSize + i*evtxChunkSize
+}
+
+func TestReadEvtxEvents(t *testing.T) {
+	fixture := evtxFixture(t)
+	if !bytes.Equal(fixture, buildTestEvtx()) {
+		t.Fatal("testdata/evtx/system.evtx is out of date, run go test -run TestReadEvtxEvents -update-evtx")
+	}
+	want := []LogEvent{
+		{Time: evtxTestEvents[2].time.Local(), Level: LevelCritical, Source: "Microsoft-Windows-Kernel-Power", ID: "41", Message: "param1=0"},
+		{Time: evtxTestEvents[1].time.Local(), Level: LevelError, Source: "Disk", ID: "7", Message: `param1=\Device\Harddisk1\DR1`},
+		{Time: evtxTestEvents[0].time.Local(), Level: LevelInformation, Source: "Service Control Manager", ID: "7036", Message: "param1=Windows Update"},
+	}
+
+	// 第一块中第二条记录的偏移
+	firstRecord := evtxChunkOffset(0) + evtxChunkHeaderSize
+	secondRecord := firstRecord + int(binary.LittleEndian.Uint32(fixture[firstRecord+4:]))
+	tests := []struct {
+		name    string
+		data    func() []byte
+		want    []LogEvent
+		skipped int
+	}{
+		{"intact", func() []byte { return fixture }, want, 0},
+		{"truncated in the second chunk", func() []byte { return fixture[:evtxChunkOffset(1)+1000] }, want[1:], 0},
+		{"empty chunk", func() []byte {
+			return append(bytes.Clone(fixture), make([]byte, evtxChunkSize)...)
+		}, want, 0},
+		{"corrupt chunk magic", func() []byte {
+			data := bytes.Clone(fixture)
+			data[evtxChunkOffset(0)] = 'X'
+			return data
+		}, want[:1], 0},
+		{"corrupt record", func() []byte {
+			data := bytes.Clone(fixture)
+			data[secondRecord+evtxRecordHeader] = 0xff // 未知的 BinXML 标记
+			return data
+		}, []LogEvent{want[0], want[2]}, 1},
+		{"corrupt record size", func() []byte {
+			data := bytes.Clone(fixture)
+			binary.LittleEndian.PutUint32(data[secondRecord+4:], evtxChunkSize) // 超出块的范围，该块之后的记录无法定位
+			return data
+		}, []LogEvent{want[0], want[2]}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, skipped, err := ReadEvtxEvents(writeTemp(t, tt.data()), EventFilter{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if skipped != tt.skipped {
+				t.Errorf("skipped = %d, want %d", skipped, tt.skipped)
+			}
+			checkEvents(t, got, tt.want)
+		})
+	}
+
+	got, _, err := ReadEvtxEvents(writeTemp(t, fixture), EventFilter{Levels: []string{"error", "critical"}, MaxEvents: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEvents(t, got, want[:1])
+}
+
+func checkEvents(t *testing.T, got, want []LogEvent) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d events %+v, want %d", len(got), got, len(want))
+	}
+	for i, e := range got {
+		w := want[i]
+		if !e.Time.Equal(w.Time) || e.Level != w.Level || e.Source != w.Source || e.ID != w.ID || e.Message != w.Message {
+			t.Errorf("event %d = %+v, want %+v", i, e, w)
+		}
+	}
+}
+
+func TestOpenEvtxRejectsInvalidFiles(t *testing.T) {
+	fixture := evtxFixture(t)
+	notEvtx := bytes.Clone(fixture)
+	copy(notEvtx, "PK\x03\x04")
+	for name, data := range map[string][]byte{
+		"truncated header": fixture[:100],
+		"wrong magic":      notEvtx,
+	} {
+		if _, _, err := ReadEvtxEvents(writeTemp(t, data), EventFilter{}); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+// 逐字节破坏第一块中的记录，读取器不应 panic，也不应因单条记录失败而报错
+func TestEvtxCorruptBytes(t *testing.T) {
+	fixture := evtxFixture(t)
+	chunk := fixture[evtxChunkOffset(0):evtxChunkOffset(1)]
+	end := int(binary.LittleEndian.Uint32(chunk[48:]))
+	for i := evtxChunkHeaderSize; i < end; i++ {
+		for _, mask := range []byte{0x01, 0x80, 0xff} {
+			corrupt := bytes.Clone(chunk)
+			corrupt[i] ^= mask
+			r := &EvtxReader{}
+			r.chunkRecords(corrupt, func(rec EvtxRecord) bool {
+				defer func() { recover() }() // 与 ReadEvtxEvents 一致，转换失败只跳过该条
+				rec.LogEvent()
+				return true
+			})
+		}
+	}
+}
+
+func TestFormatBXValue(t *testing.T) {
+	le := binary.LittleEndian
+	tests := []struct {
+		vt   byte
+		b    []byte
+		want string
+	}{
+		{vtString, []byte{'h', 0, 'i', 0, 0, 0}, "hi"},
+		{vtInt16, le.AppendUint16(nil, 0xfffe), "-2"},
+		{vtUInt32, le.AppendUint32(nil, 4000000000), "4000000000"},
+		{vtBool, le.AppendUint32(nil, 1), "true"},
+		{vtHexInt64, le.AppendUint64(nil, 0x8000000000000000), "0x8000000000000000"},
+		{vtSizeT, le.AppendUint32(nil, 0x10), "0x10"},
+		{vtFileTime, le.AppendUint64(nil, fileTime(time.Date(2025, 3, 1, 2, 5, 30, 500000000, time.UTC))), "2025-03-01T02:05:30.5Z"},
+		{vtSID, []byte{1, 2, 0, 0, 0, 0, 0, 5, 32, 0, 0, 0, 0x20, 0x02, 0, 0}, "S-1-5-32-544"},
+		{vtGUID, []byte{0x78, 0x56, 0x34, 0x12, 0x34, 0x12, 0x78, 0x56, 0xaa, 0xbb, 1, 2, 3, 4, 5, 6}, "{12345678-1234-5678-AABB-010203040506}"},
+		{vtUInt16 | vtArrayFlag, []byte{1, 0, 2, 0, 3}, "1, 2"},
+		{vtString | vtArrayFlag, []byte{'a', 0, 0, 0, 'b', 0, 0, 0}, "a, b"},
+		{vtInt64, []byte{1, 2}, "?"}, // 长度不足
+	}
+	for _, tt := range tests {
+		if got := formatBXValue(tt.vt, tt.b); got != tt.want {
+			t.Errorf("formatBXValue(0x%x, % x) = %q, want %q", tt.vt, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestGetEvtxFileStrAllowDirs(t *testing.T) {
+	allowed := t.TempDir()
+	if err := os.WriteFile(filepath.Join(allowed, "System.evtx"), evtxFixture(t), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outside, err := filepath.Abs(filepath.Join("testdata", "evtx", "system.evtx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	denied := []string{outside, filepath.Join(allowed, "..", filepath.Base(allowed)+"-other.evtx"), ""}
+	// 白名单目录内指向目录外的符号链接
+	link := filepath.Join(allowed, "link.evtx")
+	if err := os.Symlink(outside, link); err == nil {
+		denied = append(denied, link)
+	}
+	cfg := EvtxFileToolCfg{AllowDirs: []string{allowed}}
+
+	for _, path := range []string{filepath.Join(allowed, "System.evtx"), "System.evtx"} {
+		if _, err := GetEvtxFileStr(cfg, path, EventFilter{}); err != nil {
+			t.Errorf("GetEvtxFileStr(%s): %v", path, err)
+		}
+	}
+	for _, path := range denied {
+		if _, err := GetEvtxFileStr(cfg, path, EventFilter{}); err == nil {
+			t.Errorf("GetEvtxFileStr(%q) read a file outside allow_dirs", path)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"winds-assistant/common"
+	"winds-assistant/utils"
+)
+
+// 异常查询参数
+type AnomalyQuery struct {
+	Start      time.Time // 零值表示当天 0 点
+	End        time.Time // 零值表示当前时间
+	Metrics    []string  // 为空表示全部指标
+	Severity   string    // 最低严重程度：warning（默认）或 critical
+	MaxResults int       // 最多列出的异常条数（按偏离程度从大到小），默认 50
+}
+
+var anomalySeverityRank = map[string]int{"warning": 1, "critical": 2}
+
+// 查询时间范围内监控守护进程检测到的异常：先按指标汇总，再按偏离程度列出明细
+func GetAnomaliesStr(q AnomalyQuery) (string, error) {
+	end := q.End
+	if end.IsZero() {
+		end = time.Now()
+	}
+	start := q.Start
+	if start.IsZero() {
+		y, m, d := end.Date()
+		start = time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	}
+	minRank := anomalySeverityRank[strings.ToLower(q.Severity)]
+	if q.Severity != "" && minRank == 0 {
+		return "", fmt.Errorf("unknown severity %q, available: warning, critical", q.Severity)
+	}
+	if q.MaxResults <= 0 {
+		q.MaxResults = 50
+	}
+
+	store, err := utils.DefaultAnomalyStore()
+	if err != nil {
+		return "", err
+	}
+	all, err := store.Query(start, end)
+	if err != nil {
+		return "", err
+	}
+	var anomalies []common.Anomaly
+	for _, a := range all {
+		if anomalySeverityRank[a.Severity] < minRank || (len(q.Metrics) > 0 && !slices.Contains(q.Metrics, a.Name)) {
+			continue
+		}
+		anomalies = append(anomalies, a)
+	}
+
+	out := fmt.Sprintf("%s ~ %s 共检测到 %d 条异常。\n", start.Format(time.DateTime), end.Format(time.DateTime), len(anomalies))
+	if len(anomalies) == 0 {
+		return out, nil
+	}
+
+	// 按序列汇总：次数、最严重程度、首次和最近时间
+	type summary struct {
+		name, labels    string
+		count, critical int
+		first, last     int64
+		maxScore        float64
+		detectors       map[string]bool
+	}
+	byKey := map[string]*summary{}
+	var keys []string
+	for _, a := range anomalies {
+		labels := common.FormatLabels(a.Labels)
+		key := a.Name + "{" + labels + "}"
+		s, ok := byKey[key]
+		if !ok {
+			s = &summary{name: a.Name, labels: labels, first: a.Time, detectors: map[string]bool{}}
+			byKey[key] = s
+			keys = append(keys, key)
+		}
+		s.count++
+		if a.Severity == "critical" {
+			s.critical++
+		}
+		s.last = a.Time
+		s.maxScore = max(s.maxScore, a.Score)
+		s.detectors[a.Detector] = true
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return byKey[keys[i]].maxScore > byKey[keys[j]].maxScore })
+
+	out += "按指标汇总:\n指标 | 标签 | 次数 | 其中严重 | 首次 ~ 最近 | 最大偏离(σ) | 检测器\n"
+	for _, key := range keys {
+		s := byKey[key]
+		var detectors []string
+		for d := range s.detectors {
+			detectors = append(detectors, d)
+		}
+		sort.Strings(detectors)
+		labels := s.labels
+		if labels == "" {
+			labels = "-"
+		}
+		out += fmt.Sprintf("%s | %s | %d | %d | %s ~ %s | %.1f | %s\n", s.name, labels, s.count, s.critical,
+			time.Unix(s.first, 0).Format(time.DateTime), time.Unix(s.last, 0).Format(time.DateTime), s.maxScore, strings.Join(detectors, ","))
+	}
+
+	sort.SliceStable(anomalies, func(i, j int) bool { return anomalies[i].Score > anomalies[j].Score })
+	if len(anomalies) > q.MaxResults {
+		out += fmt.Sprintf("偏离最大的 %d 条明细:\n", q.MaxResults)
+		anomalies = anomalies[:q.MaxResults]
+	} else {
+		out += "明细:\n"
+	}
+	out += "时间 | 严重程度 | 指标 | 标签 | 检测器 | 实际值 | 期望值 | 偏离(σ)\n"
+	for _, a := range anomalies {
+		labels := common.FormatLabels(a.Labels)
+		if labels == "" {
+			labels = "-"
+		}
+		out += fmt.Sprintf("%s | %s | %s | %s | %s | %.2f %s | %.2f %s | %.1f\n", time.Unix(a.Time, 0).Format(time.DateTime),
+			a.Severity, a.Name, labels, a.Detector, a.Value, a.Unit, a.Expected, a.Unit, a.Score)
+	}
+	return out, nil
+}
@@ -7,9 +7,6 @@ import (
 	"io"
 	"net/http"
 	"time"
-	"os"
-	"gopkg.in/yaml.v3"
-	"path/filepath"
 )
 
 // B站响应体定义
@@ -53,7 +50,12 @@ type RcmdReasonInfo struct {
 
 // 工具配置
 type BiliToolCfg struct {
-	Cookie 			string			`json:"cookie"`
+	Cookie 			string			`yaml:"cookie"`
+}
+
+// Cookie 仅在个性化推荐时需要，因此没有必填字段
+func (c *BiliToolCfg) Missing() []string {
+	return nil
 }
 
 func GetBiliRcmd(ctx context.Context, cookie string) (biliresp *BiliResponse, err error) {
@@ -106,20 +108,10 @@ func GetBiliRcmd(ctx context.Context, cookie string) (biliresp *BiliResponse, er
 	return &result, nil
 }
 
-func GetBiliRcmdStr(enable_cookie bool, rounds int) (r string) {
-	// 读取工具配置文件
-    data, err := os.ReadFile(filepath.Clean("config/agent_get_bili_rcmd.yaml"))
-    if err != nil {
-        fmt.Printf("error read yaml: %v", err)
-        return
-    }
-
-    // 解析 Yaml
-	var config BiliToolCfg
-    if err := yaml.Unmarshal(data, &config); err != nil {
-        fmt.Printf("error extract yaml: %v", err)
-        return
-    }
+func GetBiliRcmdStr(config BiliToolCfg, enable_cookie bool, rounds int) (r string, err error) {
+	if enable_cookie && config.Cookie == "" {
+		return "", fmt.Errorf("个性化推荐需要在配置中填写 <get_bili_rcmd> 的 cookie")
+	}
 
 	// 从结构体中提取关键字，并拼接成字符串
 	// rounds 表示获取几轮推荐
@@ -128,7 +120,6 @@ func GetBiliRcmdStr(enable_cookie bool, rounds int) (r string) {
 		defer cancel()
 
 		var biliresp *BiliResponse
-		var err error
 		if enable_cookie {
 			biliresp, err = GetBiliRcmd(ctx, config.Cookie)
 		}else{
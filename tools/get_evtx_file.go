@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// <get_evtx_file> 的配置，与 <get_log_file> 一样只允许读取白名单目录下的文件
+type EvtxFileToolCfg struct {
+	AllowDirs []string `yaml:"allow_dirs"`
+}
+
+func (c *EvtxFileToolCfg) Missing() []string {
+	if len(c.AllowDirs) == 0 {
+		return []string{"allow_dirs"}
+	}
+	return nil
+}
+
+// 将 EVTX 记录转换为统一的事件结构
+func (r EvtxRecord) LogEvent() LogEvent {
+	system := r.Root.Child("System")
+
+	t := r.Written
+	if st := system.Child("TimeCreated").Attr("SystemTime"); st != "" {
+		if parsed, err := time.Parse(time.RFC3339Nano, st); err == nil {
+			t = parsed
+		}
+	}
+	l, _ := strconv.Atoi(system.Child("Level").Text())
+	level, ok := winEventLevels[l]
+	if !ok {
+		level = LevelInformation
+	}
+	return LogEvent{
+		Time:    t.Local(),
+		Level:   level,
+		Source:  system.Child("Provider").Attr("Name"),
+		ID:      system.Child("EventID").Text(),
+		Message: evtxMessage(r.Root),
+	}
+}
+
+// EVTX 中不保存渲染后的消息文本，这里用 EventData / UserData 中的字段拼出消息
+func evtxMessage(root *xmlElement) string {
+	var parts []string
+	if data := root.Child("EventData"); data != nil {
+		for _, c := range data.Children {
+			e, ok := c.(*xmlElement)
+			if !ok {
+				continue
+			}
+			value := strings.TrimSpace(flattenText(e))
+			if value == "" {
+				continue
+			}
+			if name := e.Attr("Name"); name != "" {
+				parts = append(parts, name+"="+value)
+			} else {
+				parts = append(parts, value)
+			}
+		}
+	}
+	if data := root.Child("UserData"); data != nil {
+		collectLeaves(data, &parts)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// 元素及其所有子孙的文本
+func flattenText(e *xmlElement) string {
+	var sb strings.Builder
+	for _, c := range e.Children {
+		switch v := c.(type) {
+		case xmlText:
+			sb.WriteString(string(v))
+		case *xmlElement:
+			sb.WriteString(flattenText(v))
+		}
+	}
+	return sb.String()
+}
+
+// 以 "名称=值" 的形式收集叶子元素
+func collectLeaves(e *xmlElement, parts *[]string) {
+	hasChild := false
+	for _, c := range e.Children {
+		if ce, ok := c.(*xmlElement); ok {
+			hasChild = true
+			collectLeaves(ce, parts)
+		}
+	}
+	if !hasChild {
+		if value := strings.TrimSpace(e.Text()); value != "" {
+			*parts = append(*parts, e.Name+"="+value)
+		}
+	}
+}
+
+// 读取 EVTX 文件中满足过滤条件的事件，按时间从新到旧返回最新的 MaxEvents 条
+func ReadEvtxEvents(path string, filter EventFilter) (events []LogEvent, skipped int, err error) {
+	reader, err := OpenEvtx(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer reader.Close()
+
+	err = reader.Records(func(r EvtxRecord) bool {
+		// 解析出的记录结构异常时只跳过该条，不影响其余记录
+		defer func() {
+			if recover() != nil {
+				reader.Skipped++
+			}
+		}()
+		if e := r.LogEvent(); filter.Match(e) {
+			events = append(events, e)
+		}
+		return true
+	})
+
+	// 记录顺序不一定严格按时间，排序后截取
+	sortEventsDesc(events)
+	if filter.MaxEvents > 0 && len(events) > filter.MaxEvents {
+		events = events[:filter.MaxEvents]
+	}
+	return events, reader.Skipped, err
+}
+
+// 按时间从新到旧排序
+func sortEventsDesc(events []LogEvent) {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.After(events[j].Time) })
+}
+
+// 返回 EVTX 文件分析结果的字符串表示，文件须位于 cfg.AllowDirs 内
+func GetEvtxFileStr(cfg EvtxFileToolCfg, path string, filter EventFilter) (string, error) {
+	path, err := resolveAllowedPath(cfg.AllowDirs, path)
+	if err != nil {
+		return "", err
+	}
+	events, skipped, err := ReadEvtxEvents(path, filter)
+	if err != nil {
+		return "", err
+	}
+	out := fmt.Sprintf("文件 %s 中符合条件的事件 %d 条", path, len(events))
+	if skipped > 0 {
+		out += fmt.Sprintf("（%d 条记录无法解析已跳过）", skipped)
+	}
+	return out + ":\n" + SummarizeEvents(events), nil
+}
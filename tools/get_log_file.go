@@ -0,0 +1,316 @@
+package tools
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// <get_log_file> 的配置，只允许读取白名单目录下的文件
+type LogFileToolCfg struct {
+	AllowDirs []string `yaml:"allow_dirs"`
+}
+
+func (c *LogFileToolCfg) Missing() []string {
+	if len(c.AllowDirs) == 0 {
+		return []string{"allow_dirs"}
+	}
+	return nil
+}
+
+// 日志文件查询条件
+type LogFileQuery struct {
+	Path      string    // 文件路径，相对路径基于第一个白名单目录
+	Tail      int       // 返回匹配结果的最后 N 行
+	Grep      string    // 正则表达式，为空表示不过滤
+	StartTime time.Time // 零值表示不限
+	EndTime   time.Time // 零值表示不限
+	Rotated   bool      // 是否同时读取轮转文件（.1、.2.gz 等）
+	Summarize bool      // 是否按日志模式归纳
+	MaxChars  int       // 输出的字符数上限，超出时丢弃最早的行，0 表示不限
+}
+
+const (
+	defaultLogTail = 200
+	maxLogLineLen  = 2000
+)
+
+// 解析路径并检查其是否位于白名单目录内（会展开符号链接，防止借此跳出目录）
+// 相对路径基于第一个白名单目录，get_evtx_file 同样使用该检查
+func resolveAllowedPath(allowDirs []string, path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("未指定文件路径")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(allowDirs[0], path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", fmt.Errorf("无法访问文件 %s: %v", path, err)
+	}
+	resolved, _ = filepath.Abs(resolved)
+
+	for _, dir := range allowDirs {
+		allowed, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			continue
+		}
+		allowed, _ = filepath.Abs(allowed)
+		if rel, err := filepath.Rel(allowed, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("文件 %s 不在允许的目录中 (见 allow_dirs)", path)
+}
+
+var rotatedSuffixPattern = regexp.MustCompile(`^\.(\d+)(\.gz)?$`)
+
+// 返回日志文件及其轮转文件，按从旧到新排列（app.log.3.gz, app.log.2, app.log.1, app.log）
+// 轮转文件同样要通过白名单检查，指向目录外的符号链接会被跳过
+func logFileChain(cfg LogFileToolCfg, path string, rotated bool) []string {
+	if !rotated {
+		return []string{path}
+	}
+	type rotatedFile struct {
+		path string
+		n    int
+	}
+	var files []rotatedFile
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	base := filepath.Base(path)
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), base) {
+			continue
+		}
+		if sub := rotatedSuffixPattern.FindStringSubmatch(strings.TrimPrefix(entry.Name(), base)); sub != nil {
+			file := filepath.Join(filepath.Dir(path), entry.Name())
+			if _, err := resolveAllowedPath(cfg.AllowDirs, file); err != nil {
+				continue
+			}
+			n, _ := strconv.Atoi(sub[1])
+			files = append(files, rotatedFile{file, n})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].n > files[j].n })
+
+	var chain []string
+	for _, f := range files {
+		chain = append(chain, f.path)
+	}
+	return append(chain, path)
+}
+
+// 打开日志文件，.gz 文件自动解压
+func openLogFile(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return file, nil
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gz, file}, nil
+}
+
+// ** 常见日志格式的时间戳 **
+type logTimeFormat struct {
+	pattern *regexp.Regexp
+	parse   func(s string, now time.Time) (time.Time, error)
+}
+
+var logTimeFormats = []logTimeFormat{
+	// 2025-03-01T10:12:03.123+08:00, 2025-03-01 10:12:03,123, 2025/03/01 10:12:03
+	{regexp.MustCompile(`\d{4}[-/]\d{2}[-/]\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`), func(s string, _ time.Time) (time.Time, error) {
+		s = strings.NewReplacer("/", "-", ",", ".", "T", " ").Replace(s)
+		for _, layout := range []string{"2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999Z0700", "2006-01-02 15:04:05.999999999"} {
+			if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("unknown time %s", s)
+	}},
+	// [01/Mar/2025:10:12:03 +0800]（Apache / Nginx）
+	{regexp.MustCompile(`\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`), func(s string, _ time.Time) (time.Time, error) {
+		return time.Parse("02/Jan/2006:15:04:05 -0700", s)
+	}},
+	// Mar  1 10:12:03（syslog，没有年份）
+	{regexp.MustCompile(`^[A-Z][a-z]{2}\s+\d{1,2} \d{2}:\d{2}:\d{2}`), func(s string, now time.Time) (time.Time, error) {
+		t, err := time.ParseInLocation("Jan _2 15:04:05", strings.Join(strings.Fields(s), " "), time.Local)
+		if err != nil {
+			return t, err
+		}
+		t = t.AddDate(now.Year(), 0, 0)
+		if t.After(now.Add(24 * time.Hour)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+		return t, nil
+	}},
+}
+
+// 解析行首附近的时间戳，没有时返回零值
+func parseLogTime(line string, now time.Time) time.Time {
+	head := line
+	if len(head) > 64 {
+		head = head[:64]
+	}
+	for _, f := range logTimeFormats {
+		if s := f.pattern.FindString(head); s != "" {
+			if t, err := f.parse(s, now); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// 按条件读取日志文件，返回最后 Tail 条匹配的行
+// 没有时间戳的行（如堆栈）沿用上一行的时间
+func ReadLogLines(cfg LogFileToolCfg, q LogFileQuery) (path string, files []string, lines []LogEvent, matched int, err error) {
+	if path, err = resolveAllowedPath(cfg.AllowDirs, q.Path); err != nil {
+		return
+	}
+	var grep *regexp.Regexp
+	if q.Grep != "" {
+		if grep, err = regexp.Compile(q.Grep); err != nil {
+			err = fmt.Errorf("无效的正则表达式 %q: %v", q.Grep, err)
+			return
+		}
+	}
+	if q.Tail <= 0 {
+		q.Tail = defaultLogTail
+	}
+	filter := EventFilter{StartTime: q.StartTime, EndTime: q.EndTime}
+	windowed := !q.StartTime.IsZero() || !q.EndTime.IsZero()
+
+	now := time.Now()
+	files = logFileChain(cfg, path, q.Rotated)
+	for _, file := range files {
+		reader, e := openLogFile(file)
+		if e != nil {
+			err = fmt.Errorf("读取 %s 失败: %v", file, e)
+			return
+		}
+		var last time.Time
+		br := bufio.NewReaderSize(reader, 64*1024)
+		for {
+			text, cut, e := readLogLine(br)
+			if e == io.EOF {
+				break
+			}
+			if e != nil {
+				reader.Close()
+				err = fmt.Errorf("读取 %s 失败: %v", file, e)
+				return
+			}
+			if t := parseLogTime(text, now); !t.IsZero() {
+				last = t
+			}
+			line := LogEvent{Time: last, Level: guessLevel(text), Source: filepath.Base(file), Message: text}
+			if windowed && (last.IsZero() || !filter.Match(line)) {
+				continue
+			}
+			if grep != nil && !grep.MatchString(text) {
+				continue
+			}
+			if r := []rune(text); cut || len(r) > maxLogLineLen {
+				line.Message = string(r[:min(len(r), maxLogLineLen)]) + "..."
+			}
+			matched++
+			lines = append(lines, line)
+			if len(lines) > q.Tail {
+				lines = lines[1:]
+			}
+		}
+		reader.Close()
+	}
+	return
+}
+
+// 读取一行（不含换行符），只保留开头的 maxLogLineLen 个字符左右，超长部分直接丢弃并将 cut 置为 true，
+// 避免压缩后的 JSON、堆栈转储等超长行占用大量内存或中断整个查询；时间、等级和 grep 只看保留的部分
+func readLogLine(r *bufio.Reader) (text string, cut bool, err error) {
+	const maxBytes = maxLogLineLen * 4 // UTF-8 每个字符最多 4 字节
+	var buf []byte
+	for {
+		part, isPrefix, e := r.ReadLine()
+		if e != nil {
+			if e == io.EOF && (len(buf) > 0 || cut) {
+				return string(buf), cut, nil
+			}
+			return "", false, e
+		}
+		if room := maxBytes - len(buf); len(part) > room {
+			part, cut = part[:room], true
+		}
+		buf = append(buf, part...)
+		if !isPrefix {
+			return string(buf), cut, nil
+		}
+	}
+}
+
+// 读取日志文件并格式化为工具输出
+func GetLogFileStr(cfg LogFileToolCfg, q LogFileQuery) (string, error) {
+	path, files, lines, matched, err := ReadLogLines(cfg, q)
+	if err != nil {
+		return "", err
+	}
+
+	// 超出长度上限时从最早的行开始丢弃，保证保留最新的行（预留标题的长度）
+	budgetDropped := 0
+	if q.MaxChars > 0 && !q.Summarize {
+		remain, keep := q.MaxChars-200, 0
+		for i := len(lines) - 1; i >= 0; i-- {
+			remain -= utf8.RuneCountInString(lines[i].Message) + 1
+			if remain < 0 {
+				break
+			}
+			keep++
+		}
+		budgetDropped = len(lines) - keep
+		lines = lines[budgetDropped:]
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("文件 %s", path))
+	if len(files) > 1 {
+		sb.WriteString(fmt.Sprintf(" (含 %d 个轮转文件)", len(files)-1))
+	}
+	sb.WriteString(fmt.Sprintf(" 中匹配 %d 行", matched))
+	if len(lines) < matched {
+		sb.WriteString(fmt.Sprintf(", 以下为最后 %d 行", len(lines)))
+	}
+	if budgetDropped > 0 {
+		sb.WriteString(fmt.Sprintf("(超出长度上限, 已省略较早的 %d 行, 如需查看请缩小时间范围或使用 grep)", budgetDropped))
+	}
+	sb.WriteString(":\n")
+
+	if q.Summarize {
+		// 归纳时按从新到旧排列，与事件日志一致
+		sortEventsDesc(lines)
+		sb.WriteString(SummarizeEvents(lines))
+		return sb.String(), nil
+	}
+	for _, line := range lines {
+		sb.WriteString(line.Message)
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,202 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"winds-assistant/common"
+	"winds-assistant/utils"
+)
+
+// 进程历史查询参数
+type ProcessHistoryQuery struct {
+	Minutes    int       // 未指定 Start 时查询最近多少分钟，默认 10
+	Start      time.Time // 绝对时间范围，零值表示不限
+	End        time.Time // 零值表示当前时间
+	By         string    // 排序依据：cpu（默认）、mem、io
+	TopN       int       // 列出的进程数，默认 10
+	Name       string    // 只看进程名包含该字符串的进程（忽略大小写）
+	Resolution string    // raw、1m、1h，为空按时间跨度自动选择
+}
+
+// 监控守护进程记录的进程指标（见 workers 中的 processCollector），同名同路径的进程合并为一条序列
+var processHistoryMetrics = []string{"proc_cpu_percent", "proc_mem_rss", "proc_io_read_bytes_rate", "proc_io_write_bytes_rate", "proc_count"}
+
+// 一个进程在查询窗口内的资源占用，均值只统计进程处于前 N 名的采样
+type processHistory struct {
+	labels      map[string]string
+	count       int
+	first, last int64
+	avg, max    map[string]float64
+	pid         int64 // 最近一次采样中占用 CPU 最高的实例的 PID
+	pidTime     int64
+}
+
+// 序列在查询窗口内的点，原始采样的均值和峰值都是采样值
+type processPoint struct {
+	time     int64
+	avg, max float64
+}
+
+// 查询时间范围内资源占用最高的进程：按进程名 + 执行路径汇总各指标的均值和峰值
+func GetProcessHistoryStr(q ProcessHistoryQuery) (string, error) {
+	if q.Minutes <= 0 {
+		q.Minutes = 10
+	}
+	if q.TopN <= 0 {
+		q.TopN = 10
+	}
+	by := strings.ToLower(q.By)
+	if by == "" {
+		by = "cpu"
+	}
+	var rank func(h *processHistory) float64
+	switch by {
+	case "cpu":
+		rank = func(h *processHistory) float64 { return h.avg["proc_cpu_percent"] }
+	case "mem", "memory":
+		rank = func(h *processHistory) float64 { return h.avg["proc_mem_rss"] }
+	case "io":
+		rank = func(h *processHistory) float64 {
+			return h.avg["proc_io_read_bytes_rate"] + h.avg["proc_io_write_bytes_rate"]
+		}
+	default:
+		return "", fmt.Errorf("unknown sort key %q, available: cpu, mem, io", q.By)
+	}
+	start, end, err := queryTimeRange(q.Minutes, q.Start, q.End)
+	if err != nil {
+		return "", err
+	}
+
+	store, err := utils.DefaultTSStore()
+	if err != nil {
+		return "", err
+	}
+	rollup, err := chooseRollup(q.Resolution, start, end)
+	if err != nil {
+		return "", err
+	}
+
+	byKey := map[string]*processHistory{}
+	get := func(labels map[string]string) *processHistory {
+		key := common.FormatLabels(labels)
+		h, ok := byKey[key]
+		if !ok {
+			h = &processHistory{labels: labels, first: math.MaxInt64, avg: map[string]float64{}, max: map[string]float64{}}
+			byKey[key] = h
+		}
+		return h
+	}
+	// 指标在窗口内各序列的点，以标签串为键
+	query := func(name string) (map[string][]processPoint, map[string]map[string]string, error) {
+		points, labels := map[string][]processPoint{}, map[string]map[string]string{}
+		if rollup != nil {
+			series, err := rollup.QueryWithRecent(store, name, nil, start, end)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, rs := range series {
+				key := common.FormatLabels(rs.Labels)
+				labels[key] = rs.Labels
+				for _, p := range rs.Points {
+					points[key] = append(points[key], processPoint{p.Time, p.Avg, p.Max})
+				}
+			}
+			return points, labels, nil
+		}
+		series, err := store.Query(name, nil, start, end)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, ts := range series {
+			key := common.FormatLabels(ts.Labels)
+			labels[key] = ts.Labels
+			for _, p := range ts.Points {
+				points[key] = append(points[key], processPoint{p.Time, p.Value, p.Value})
+			}
+		}
+		return points, labels, nil
+	}
+
+	for _, m := range processHistoryMetrics {
+		points, labels, err := query(m)
+		if err != nil {
+			return "", err
+		}
+		for key, ps := range points {
+			if len(ps) == 0 {
+				continue
+			}
+			h := get(labels[key])
+			sum := 0.0
+			for _, p := range ps {
+				sum += p.avg
+				h.max[m] = math.Max(h.max[m], p.max)
+				h.first, h.last = min(h.first, p.time), max(h.last, p.time)
+			}
+			h.avg[m] = sum / float64(len(ps))
+			h.count = max(h.count, len(ps))
+		}
+	}
+	// PID 取窗口内最后一个原始采样；汇总数据中只有各时段 PID 的最大值，没有意义，不显示
+	if rollup == nil {
+		points, _, err := query("proc_pid")
+		if err != nil {
+			return "", err
+		}
+		for key, ps := range points {
+			h, ok := byKey[key]
+			if !ok {
+				continue
+			}
+			for _, p := range ps {
+				if p.time >= h.pidTime {
+					h.pid, h.pidTime = int64(p.max), p.time
+				}
+			}
+		}
+	}
+
+	var list []*processHistory
+	for _, h := range byKey {
+		if q.Name == "" || strings.Contains(strings.ToLower(h.labels["name"]), strings.ToLower(q.Name)) {
+			list = append(list, h)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return rank(list[i]) > rank(list[j]) })
+
+	resolution := "原始采样"
+	if rollup != nil {
+		resolution = "每 " + rollup.Name + " 汇总"
+	}
+	out := fmt.Sprintf("%s ~ %s 共记录到 %d 个进程(%s)", start.Format(time.DateTime), end.Format(time.DateTime), len(list), resolution)
+	if len(list) == 0 {
+		return out + "。监控守护进程只记录每次采集时资源占用前几名的进程, 该时段可能没有运行监控。\n", nil
+	}
+	if len(list) > q.TopN {
+		list = list[:q.TopN]
+	}
+	out += fmt.Sprintf(", 按 %s 排序的前 %d 个如下(同名同路径的多个进程合并统计, 资源占用为各实例之和; 均值只统计进程处于前几名期间的采样):\n", by, len(list))
+	out += "进程 | 实例数(峰值) | 最近 PID | 执行路径 | 采样数 | 首次 ~ 最近 | CPU 均值/峰值(%) | 内存 均值/峰值(MB) | 读 均值/峰值(KB/s) | 写 均值/峰值(KB/s)\n"
+	for _, h := range list {
+		exe := h.labels["exe"]
+		if exe == "" {
+			exe = "-"
+		}
+		pid := "-"
+		if h.pid > 0 {
+			pid = fmt.Sprint(h.pid)
+		}
+		instances := max(h.max["proc_count"], 1)
+		out += fmt.Sprintf("%s | %.0f | %s | %s | %d | %s ~ %s | %.1f / %.1f | %.1f / %.1f | %.1f / %.1f | %.1f / %.1f\n",
+			h.labels["name"], instances, pid, exe, h.count,
+			time.Unix(h.first, 0).Format(time.DateTime), time.Unix(h.last, 0).Format(time.DateTime),
+			h.avg["proc_cpu_percent"], h.max["proc_cpu_percent"],
+			h.avg["proc_mem_rss"]/(1<<20), h.max["proc_mem_rss"]/(1<<20),
+			h.avg["proc_io_read_bytes_rate"]/1024, h.max["proc_io_read_bytes_rate"]/1024,
+			h.avg["proc_io_write_bytes_rate"]/1024, h.max["proc_io_write_bytes_rate"]/1024)
+	}
+	return out, nil
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package tools
+
+import (
+    "fmt"
+    "runtime"
+)
+
+type DriverStatus struct {
+    DeviceName    string
+    Manufacturer  string
+    DriverVersion string
+    Status        string
+    IsSigned      bool
+}
+
+// 驱动信息依赖 WMI，仅 Windows 可用
+func GetSysDriver() (drivers []DriverStatus, err error) {
+    return nil, fmt.Errorf("get_sys_driver is not supported on %s", runtime.GOOS)
+}
+
+// 返回系统驱动的字符串表示，非 Windows 平台返回错误说明
+func GetSysDriverStr() (r string) {
+    _, err := GetSysDriver()
+    return err.Error()
+}
@@ -4,13 +4,13 @@ import (
 	"github.com/shirou/gopsutil/v4/cpu"
 	"github.com/shirou/gopsutil/v4/mem"
 	"github.com/shirou/gopsutil/v4/disk"
-	"winds-assistant/utils"
 	"fmt"
-	"strings"
 	"winds-assistant/common"
+	"winds-assistant/utils"
 	"time"
-	"os"
-	"encoding/csv"
+	"math"
+	"slices"
+	"strings"
 )
 
 // 获取CPU信息
@@ -29,42 +29,6 @@ func GetCPUInfo() (common.CPUInfoStat, error) {
 	return ret, nil
 }
 
-// 获取NVIDIA GPU信息
-func GetNVGPUInfo() ([]common.GPUInfoStat, error){
-	out, _, err := utils.RunCommand("nvidia-smi", 
-        "--query-gpu=index,name,utilization.gpu,memory.used,memory.total,clocks.current.graphics,clocks.current.memory,temperature.gpu,power.draw",
-        "--format=csv,noheader,nounits")
-	if err != nil {
-		return nil, fmt.Errorf("执行nvidia-smi失败: %v\n输出: %s", err, out)
-	}
-
-	lines := strings.Split(strings.TrimSpace(out), "\n")
-    var stats []common.GPUInfoStat
-
-    for _, line := range lines {
-        fields := strings.Split(line, ", ")
-        if len(fields) < 8 { // 包含index后字段数应为8
-            continue // 跳过格式异常行
-        }
-
-        stat := common.GPUInfoStat{
-			Index:			utils.ParseUint(fields[0]),    // 字段0: index
-			Name:			fields[1],                     // 字段1: name
-            Utilization:    utils.ParseFloat(fields[2]),   // 字段2: utilization.gpu
-            MemUsed:        utils.ParseUint(fields[3]),    // 字段3: memory.used
-            MemTotal:       utils.ParseUint(fields[4]),    // 字段4: memory.total
-            CoreClock:      utils.ParseUint(fields[5]),    // 字段5: core_clock
-            MemClock:       utils.ParseUint(fields[6]),    // 字段6: mem_clock
-            Temperature:    utils.ParseUint(fields[7]),    // 字段7: temperature
-            PowerDraw:      utils.ParseFloat(fields[8]),   // 字段8: power
-            Vendor:         "NVIDIA",
-        }
-        stats = append(stats, stat)
-    }
-
-	return stats, nil
-}
-
 // 获取内存利用率
 func GetMemInfo() (*mem.VirtualMemoryStat, error) {
 	var ret *mem.VirtualMemoryStat
@@ -75,79 +39,278 @@ func GetMemInfo() (*mem.VirtualMemoryStat, error) {
 	return memInfo, nil
 }
 
-// 获取磁盘利用率
-func GetDiskInfo() (*disk.UsageStat, error) {
-	var ret *disk.UsageStat
-	usage, err := disk.Usage("C://")
+// 获取指定文件系统类型的所有分区的使用量，按挂载点去重
+// 个别分区（如未插入介质的光驱）读取失败时跳过，全部失败时返回错误
+func GetDiskUsages(fsTypes []string) ([]*disk.UsageStat, error) {
+	partitions, err := disk.Partitions(false)
 	if err != nil {
-		return ret, err
+		return nil, err
 	}
-	return usage, nil
+
+	var usages []*disk.UsageStat
+	seen := map[string]bool{}
+	for _, p := range partitions {
+		if seen[p.Mountpoint] || !containsFold(fsTypes, p.Fstype) {
+			continue
+		}
+		seen[p.Mountpoint] = true
+		usage, e := disk.Usage(p.Mountpoint)
+		if e != nil {
+			err = e
+			continue
+		}
+		usage.Fstype = p.Fstype // 与配置中的类型保持一致（Linux 上 statfs 只能区分到 ext2/ext3）
+		usages = append(usages, usage)
+	}
+	if len(usages) == 0 {
+		if err == nil {
+			err = fmt.Errorf("no partition with file system %v", fsTypes)
+		}
+		return nil, err
+	}
+	return usages, nil
+}
+
+// 获取各磁盘设备的累计 I/O 计数
+func GetDiskIO() (map[string]disk.IOCountersStat, error) {
+	return disk.IOCounters()
 }
 
+// 趋势中展示的指标（只展示监控配置中启用的）
 var metrics = []string{
 	"cpu_percent", 
 	"mem_used", 
+	"mem_used_percent",
 	"disk_used",
+	"disk_used_percent",
+	"disk_read_bytes_rate",
+	"disk_write_bytes_rate",
+	"net_recv_bytes_rate",
+	"net_sent_bytes_rate",
+	"net_recv_packets_rate",
+	"net_sent_packets_rate",
+	"net_errors_rate",
+	"net_drops_rate",
+	"net_connections",
 	"gpu_util", 
 	"gpu_mem_used", 
+	"gpu_mem_used_percent",
 	"gpu_mem_clock",
 	"gpu_core_clock", 
 	"gpu_temp",
 	"gpu_power",
 }
 
-// 读取 CSV 文件的后 N 行中的某一列的值
-func readCSVLastNColumn(filename string, n int) ([]string, error) {
-	// 打开 CSV 文件
-	file, err := os.Open(filename)
+// 查询指标在 [start, end] 内包含 match 中全部标签的采样并计算统计量，includeRaw 时同时返回按时间从新到旧的采样
+// 同一指标可能有多条序列（如多块 GPU），通过标签区分
+func queryMetricPoints(store *utils.TSStore, name string, match map[string]string, threshold float64, start, end time.Time, includeRaw bool) ([]MetricStats, []string, error) {
+	series, err := store.Query(name, match, start, end)
 	if err != nil {
-		return nil, fmt.Errorf("cannot open file: %w", err)
+		return nil, nil, err
 	}
-	defer file.Close()
+	var stats []MetricStats
+	var values []string
+	for _, ts := range series {
+		st := ComputeStats(ts.Points, threshold)
+		st.Name, st.Labels, st.Unit = ts.Name, ts.Labels, ts.Unit
+		stats = append(stats, st)
+		if !includeRaw {
+			continue
+		}
+		labels := common.FormatLabels(ts.Labels)
+		for i := len(ts.Points) - 1; i >= 0; i-- {
+			p := ts.Points[i]
+			point := fmt.Sprintf("Time: %s, Value: %.2f, Unit: %s", time.Unix(p.Time, 0).Format(time.RFC3339), p.Value, ts.Unit)
+			if labels != "" {
+				point += ", Labels: " + labels
+			}
+			values = append(values, point+"\n")
+		}
+	}
+	return stats, values, nil
+}
 
-	// 创建 CSV 读取器
-	reader := csv.NewReader(file)
-	rows, err := reader.ReadAll()
+// 查询指标在 [start, end] 内的汇总数据并计算统计量（尚未汇总的最近数据由原始采样补上），参数同 queryMetricPoints
+func queryRollupPoints(rollup *utils.Rollup, store *utils.TSStore, name string, match map[string]string, threshold float64, start, end time.Time, includeRaw bool) ([]MetricStats, []string, error) {
+	series, err := rollup.QueryWithRecent(store, name, match, start, end)
 	if err != nil {
-		return nil, fmt.Errorf("read csv failed: %w", err)
+		return nil, nil, err
+	}
+	var stats []MetricStats
+	var values []string
+	for _, rs := range series {
+		st := ComputeRollupStats(rs.Points, threshold)
+		st.Name, st.Labels, st.Unit = rs.Name, rs.Labels, rs.Unit
+		stats = append(stats, st)
+		if !includeRaw {
+			continue
+		}
+		labels := common.FormatLabels(rs.Labels)
+		for i := len(rs.Points) - 1; i >= 0; i-- {
+			p := rs.Points[i]
+			point := fmt.Sprintf("Time: %s, Avg: %.2f, Min: %.2f, Max: %.2f, P95: %.2f, Unit: %s",
+				time.Unix(p.Time, 0).Format(time.RFC3339), p.Avg, p.Min, p.Max, p.P95, rs.Unit)
+			if labels != "" {
+				point += ", Labels: " + labels
+			}
+			values = append(values, point+"\n")
+		}
 	}
+	return stats, values, nil
+}
+
+// 系统健康查询参数
+type SysHealthQuery struct {
+	Minutes    int               // 未指定 Start 时查询最近多少分钟
+	Start      time.Time         // 绝对时间范围，零值表示不限
+	End        time.Time         // 零值表示当前时间
+	Metrics    []string          // 指标子集，为空表示全部启用的指标
+	Labels     map[string]string // 只查询包含这些标签的序列（如 gpu=0, mount=C:）
+	Resolution string            // raw、1m、1h，为空按时间跨度自动选择
+	IncludeRaw bool              // 同时返回逐条数据
+}
 
-	// 计算起始行
-	start := len(rows) - n
-	if start < 0 {
-		start = 0
+// 选择数据精度：指定时使用指定精度，否则按时间跨度选择——
+// 1 小时内用原始采样，6 小时内用 1 分钟汇总，更长用 1 小时汇总；返回 nil 表示使用原始采样。
+// 各精度只保留一段时间（见 retention 配置），自动选择时跳过已清理了起始时间数据的精度，
+// 指定的精度或最粗的精度也不覆盖起始时间时返回错误，避免查询结果不完整却看不出来
+func chooseRollup(resolution string, start, end time.Time) (*utils.Rollup, error) {
+	retention := utils.LoadMonitorCfg().Retention
+	keep := map[string]time.Duration{"raw": retention.Raw, "1m": retention.Minute, "1h": retention.Hour}
+	covers := func(name string) error {
+		// 清理按周期进行，留一分钟余量，避免"最近 3 天"这类恰好等于保留时长的查询被拒绝
+		if oldest := time.Now().Add(-keep[name] - time.Minute); start.Before(oldest) {
+			return fmt.Errorf("start time %s is older than the %s data retention (%v), the oldest available data is from %s",
+				start.Format(time.DateTime), name, keep[name], oldest.Format(time.DateTime))
+		}
+		return nil
 	}
 
-	// 提取指定列的值
-	var values []string
-	for i := len(rows) - 1; i >= start; i-- {
-		point := fmt.Sprintf("Time: %s, Value: %s, Unit: %s\n", rows[i][1], rows[i][3], rows[i][4])
-		values = append(values, point)
+	span := end.Sub(start)
+	if resolution == "raw" {
+		return nil, covers("raw")
+	}
+	if resolution == "" && span <= time.Hour && covers("raw") == nil {
+		return nil, nil
+	}
+	rollups, err := utils.DefaultRollups()
+	if err != nil {
+		return nil, err
 	}
-	return values, nil
+	for _, r := range rollups {
+		if r.Name == resolution {
+			return r, covers(r.Name)
+		}
+		if resolution == "" && span <= 360*r.Step && covers(r.Name) == nil {
+			return r, nil
+		}
+	}
+	if resolution != "" {
+		return nil, fmt.Errorf("unknown resolution %q, available: raw, 1m, 1h", resolution)
+	}
+	last := rollups[len(rollups)-1]
+	return last, covers(last.Name)
 }
 
-const dateFormat = "20060102"
+// 确定查询的时间范围：end 为零值或晚于当前时间时截止到当前时间，start 为零值时取 end 前 minutes 分钟（默认 1）
+func queryTimeRange(minutes int, start, end time.Time) (time.Time, time.Time, error) {
+	if end.IsZero() || end.After(time.Now()) {
+		end = time.Now()
+	}
+	if start.IsZero() {
+		if minutes <= 0 {
+			minutes = 1
+		}
+		start = end.Add(-time.Duration(minutes) * time.Minute)
+	}
+	if !start.Before(end) {
+		return start, end, fmt.Errorf("start time %s is not before end time %s", start.Format(time.DateTime), end.Format(time.DateTime))
+	}
+	return start, end, nil
+}
 
-func GetSysHealthData(minutes int) (out string, err error){
-	cpuInfo , _ := GetCPUInfo()
-	gpuInfo, _ := GetNVGPUInfo()
-	memInfo, _ := GetMemInfo()
-	diskInfo, _ := GetDiskInfo()
+// 检查指标名称，为空时返回全部指标
+func selectMetrics(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return metrics, nil
+	}
+	for _, name := range names {
+		if !slices.Contains(metrics, name) {
+			return nil, fmt.Errorf("unknown metric %q, available: %s", name, strings.Join(metrics, ", "))
+		}
+	}
+	return names, nil
+}
 
-	out += "CPU 当前信息: " + fmt.Sprintf("%+v\n", cpuInfo)
-	out += "GPU 当前信息: " + fmt.Sprintf("%+v\n", gpuInfo)
-	out += "MEM 当前信息: " + fmt.Sprintf("%+v\n", memInfo)
-	out += "C:/ 当前信息: " + fmt.Sprintf("%+v\n", diskInfo)
+// 获取指定时间范围内各指标的统计表，includeRaw 时附带原始采样
+// 查询截止到当前时间时，同时附带系统当前状态
+func GetSysHealthData(q SysHealthQuery) (out string, err error){
+	config := utils.LoadMonitorCfg()
+	names, err := selectMetrics(q.Metrics)
+	if err != nil {
+		return "", err
+	}
+	start, end, err := queryTimeRange(q.Minutes, q.Start, q.End)
+	if err != nil {
+		return "", err
+	}
 
-	currentDay := time.Now().Local().Format(dateFormat)
-	for _, m := range metrics {
-		values, err := readCSVLastNColumn(fmt.Sprintf("data/%s_%s.csv", m, currentDay), minutes*6)
+	if q.End.IsZero() {
+		cpuInfo , _ := GetCPUInfo()
+		gpuInfo, _ := GetGPUInfo()
+		memInfo, _ := GetMemInfo()
+		diskInfo, _ := GetDiskUsages(config.Disk.FsTypes)
+
+		out += "CPU 当前信息: " + fmt.Sprintf("%+v\n", cpuInfo)
+		out += "GPU 当前信息: " + fmt.Sprintf("%+v\n", gpuInfo)
+		out += "MEM 当前信息: " + fmt.Sprintf("%+v\n", memInfo)
+		for _, d := range diskInfo {
+			out += fmt.Sprintf("磁盘 %s 当前信息: %+v\n", d.Path, *d)
+		}
+		out += GetNetworkSummary()
+	}
+
+	store, err := utils.DefaultTSStore()
+	if err != nil {
+		return "", err
+	}
+	rollup, err := chooseRollup(q.Resolution, start, end)
+	if err != nil {
+		return "", err
+	}
+
+	resolution := "原始采样"
+	if rollup != nil {
+		resolution = "每 " + rollup.Name + " 汇总"
+	}
+	table := statsHeader
+	var raw string
+	for _, m := range names {
+		if !utils.MetricEnabled(config, m) {
+			continue
+		}
+		threshold, ok := utils.MetricThreshold(config, m)
+		if !ok {
+			threshold = math.NaN()
+		}
+		var stats []MetricStats
+		var values []string
+		if rollup != nil {
+			stats, values, err = queryRollupPoints(rollup, store, m, q.Labels, threshold, start, end, q.IncludeRaw)
+		} else {
+			stats, values, err = queryMetricPoints(store, m, q.Labels, threshold, start, end, q.IncludeRaw)
+		}
 		if err != nil {
 			return "", err
 		}
-		out += fmt.Sprintf("%s 利用情况趋势如下: \n%s\n", m, values)
+		for _, st := range stats { // 没有对应硬件或采集器未运行时为空
+			table += FormatStats(st)
+		}
+		if len(values) > 0 {
+			raw += fmt.Sprintf("%s 利用情况趋势如下: \n%s\n", m, values)
+		}
 	}
+	out += fmt.Sprintf("%s ~ %s 各指标统计(%s):\n%s", start.Format(time.DateTime), end.Format(time.DateTime), resolution, table)
+	out += raw
 	return
-}
\ No newline at end of file
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// 是否为回环网卡（Linux 的 lo、Windows 的 Loopback Pseudo-Interface）
+func IsLoopbackInterface(name string) bool {
+	return name == "lo" || strings.HasPrefix(strings.ToLower(name), "loopback")
+}
+
+// 获取各网卡的累计收发计数（不含回环网卡）
+func GetNetIO() (map[string]net.IOCountersStat, error) {
+	counters, err := net.IOCounters(true)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]net.IOCountersStat, len(counters))
+	for _, c := range counters {
+		if !IsLoopbackInterface(c.Name) {
+			ret[c.Name] = c
+		}
+	}
+	return ret, nil
+}
+
+// 按状态统计 TCP/UDP 连接数（UDP 没有状态，记为 NONE）
+func GetConnectionCounts() (map[string]int, error) {
+	conns, err := net.Connections("inet")
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, c := range conns {
+		state := c.Status
+		if state == "" {
+			state = "NONE"
+		}
+		counts[state]++
+	}
+	return counts, nil
+}
+
+// 网络当前状态：各网卡累计收发量与按状态统计的连接数
+func GetNetworkSummary() string {
+	var sb strings.Builder
+	if counters, err := GetNetIO(); err == nil {
+		var names []string
+		for name := range counters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			c := counters[name]
+			sb.WriteString(fmt.Sprintf("网卡 %s 累计: 接收 %d 字节/%d 包, 发送 %d 字节/%d 包, 错误 %d, 丢包 %d\n",
+				name, c.BytesRecv, c.PacketsRecv, c.BytesSent, c.PacketsSent, c.Errin+c.Errout, c.Dropin+c.Dropout))
+		}
+	}
+	if counts, err := GetConnectionCounts(); err == nil {
+		var states []string
+		for state := range counts {
+			states = append(states, state)
+		}
+		sort.Strings(states)
+		var parts []string
+		for _, state := range states {
+			parts = append(parts, fmt.Sprintf("%s=%d", state, counts[state]))
+		}
+		sb.WriteString("网络连接数(按状态): " + strings.Join(parts, ", ") + "\n")
+	}
+	return sb.String()
+}
@@ -1,56 +1,284 @@
 package tools
 
 import (
+	"context"
 	"fmt"
-    "github.com/shirou/gopsutil/v4/process"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+	"winds-assistant/common"
+
+	"github.com/shirou/gopsutil/v4/process"
 )
 
-// 获取系统中所有进程的关键信息
-func GetSysProcess() (r []map[string]interface{}) {
-	// 获取所有进程列表
-    processes, _ := process.Processes()
-
-	// 获取关于进程的关键信息，结合生产环境分析系统问题
-    for _, p := range processes {
-		pid := p.Pid						// 进程ID
-		name, _ := p.Name()					// 进程名
-		exePath, _ := p.Exe()         		// 执行路径
-		// cmdline, _ := p.Cmdline()     		// 启动命令
-		createTime, _ := p.CreateTime() 	// 创建时间戳（毫秒）
-		status, _ := p.Status()       		// 运行状态（Running, Sleeping等）
-		cpuPercent, _ := p.CPUPercent()		// CPU使用率
-		memPercent, _ := p.MemoryPercent()	// 内存使用率
-		memInfo, _ := p.MemoryInfo()  		// 内存详细信息（RSS/VMS）
-		// username, _ := p.Username()   		// 运行用户（可能需要权限）
-		numThreads, _ := p.NumThreads()		// 线程数
-		ioCounters, _ := p.IOCounters()		// IO统计（读写次数、字节数）
-		ppid, _ := p.Ppid()					// 父进程ID
-
-		formatMessage := map[string]interface{}{
-			"pid": 			pid,
-			"name": 		name,
-			"exePath": 		exePath,
-			// "cmdline": 		cmdline,
-			"createTime": 	createTime,
-			"status": 		status,
-			"cpuPercent": 	cpuPercent,
-			"memPercent": 	memPercent,
-			"memInfo": 		memInfo,
-			// "username": 	username,
-			"numThreads": 	numThreads,
-			"ioCounters": 	ioCounters,
-			"ppid": 		ppid,
-		}
-		r = append(r, formatMessage)
-    }
-	return
+// 进程查询参数
+type ProcessQuery struct {
+	SortBy string // 排序依据：cpu（默认）、mem、io、threads
+	TopN   int    // 最多列出的进程数，默认 20
+	Name   string // 进程名的正则表达式
+	User   string // 运行用户（忽略大小写，可省略域名）
+	Ppid   int32  // 父进程 ID，0 表示不限；Tree 时包含全部子孙进程
+	Tree   bool   // 按父子关系以树状列出
+}
+
+// 进程的当前状态，CPU 利用率和 I/O 速率为采样间隔内的平均值
+type ProcessInfo struct {
+	Pid, Ppid  int32
+	Name, Exe  string
+	User       string
+	Status     string
+	CreateTime int64   // 创建时间戳（毫秒）
+	CPUPercent float64 // 按全部逻辑核归一化，与 cpu_percent 一致
+	RSS        uint64
+	MemPercent float32
+	Threads    int32
+	ReadRate   float64 // 字节/秒
+	WriteRate  float64
+}
+
+// 计算 CPU 利用率和 I/O 速率的采样间隔
+const processSampleInterval = time.Second
+
+// 获取全部进程的当前状态：间隔 processSampleInterval 读取两次累计计数计算 CPU 利用率和 I/O 速率
+// withUser 为 false 时不读取运行用户（Windows 上需要逐个查询账户，较慢）
+func GetProcessList(ctx context.Context, withUser bool) ([]ProcessInfo, error) {
+	processes, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	type counter struct {
+		cpu         float64
+		read, write uint64
+	}
+	readCounter := func(p *process.Process) (c counter) {
+		if times, err := p.TimesWithContext(ctx); err == nil {
+			c.cpu = times.User + times.System
+		}
+		if io, err := p.IOCountersWithContext(ctx); err == nil {
+			c.read, c.write = io.ReadBytes, io.WriteBytes
+		}
+		return
+	}
+	before := make([]counter, len(processes))
+	for i, p := range processes {
+		before[i] = readCounter(p)
+	}
+	start := time.Now()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(processSampleInterval):
+	}
+	elapsed := time.Since(start).Seconds()
+
+	var list []ProcessInfo
+	for i, p := range processes {
+		createTime, err := p.CreateTimeWithContext(ctx)
+		if err != nil {
+			continue // 已退出
+		}
+		after := readCounter(p)
+		info := ProcessInfo{Pid: p.Pid, CreateTime: createTime}
+		info.Ppid, _ = p.PpidWithContext(ctx)
+		info.Name, _ = p.NameWithContext(ctx)
+		info.Exe, _ = p.ExeWithContext(ctx)
+		if status, err := p.StatusWithContext(ctx); err == nil && len(status) > 0 {
+			info.Status = status[0]
+		}
+		if memInfo, err := p.MemoryInfoWithContext(ctx); err == nil {
+			info.RSS = memInfo.RSS
+		}
+		info.MemPercent, _ = p.MemoryPercentWithContext(ctx)
+		info.Threads, _ = p.NumThreadsWithContext(ctx)
+		if withUser {
+			info.User, _ = p.UsernameWithContext(ctx)
+		}
+		if after.cpu > before[i].cpu {
+			info.CPUPercent = (after.cpu - before[i].cpu) / elapsed / float64(runtime.NumCPU()) * 100
+		}
+		if after.read > before[i].read {
+			info.ReadRate = float64(after.read-before[i].read) / elapsed
+		}
+		if after.write > before[i].write {
+			info.WriteRate = float64(after.write-before[i].write) / elapsed
+		}
+		list = append(list, info)
+	}
+	return list, nil
+}
+
+// 运行用户是否匹配，Windows 上的用户名带域名（如 DESKTOP-1\winds），可以只写用户名
+func matchProcessUser(user, want string) bool {
+	if strings.EqualFold(user, want) {
+		return true
+	}
+	_, name, ok := strings.Cut(user, `\`)
+	return ok && strings.EqualFold(name, want)
+}
+
+// 按条件过滤、排序后以紧凑的表格返回进程列表
+func GetSysProcessStr(ctx context.Context, q ProcessQuery) (string, error) {
+	if q.TopN <= 0 {
+		q.TopN = 20
+	}
+	sortBy := strings.ToLower(q.SortBy)
+	if sortBy == "" {
+		sortBy = "cpu"
+	}
+	var key func(p ProcessInfo) float64
+	switch sortBy {
+	case "cpu":
+		key = func(p ProcessInfo) float64 { return p.CPUPercent }
+	case "mem", "memory":
+		key = func(p ProcessInfo) float64 { return float64(p.RSS) }
+	case "io":
+		key = func(p ProcessInfo) float64 { return p.ReadRate + p.WriteRate }
+	case "threads":
+		key = func(p ProcessInfo) float64 { return float64(p.Threads) }
+	default:
+		return "", fmt.Errorf("unknown sort key %q, available: cpu, mem, io, threads", q.SortBy)
+	}
+	var nameRe *regexp.Regexp
+	if q.Name != "" {
+		var err error
+		if nameRe, err = regexp.Compile(q.Name); err != nil {
+			return "", fmt.Errorf("invalid name pattern %q: %w", q.Name, err)
+		}
+	}
+
+	all, err := GetProcessList(ctx, q.User != "")
+	if err != nil {
+		return "", err
+	}
+
+	// 指定父进程且按树状列出时，包含全部子孙进程
+	inScope := func(p ProcessInfo) bool { return q.Ppid == 0 || p.Ppid == q.Ppid }
+	if q.Ppid != 0 && q.Tree {
+		children := map[int32][]int32{}
+		for _, p := range all {
+			if p.Pid != p.Ppid {
+				children[p.Ppid] = append(children[p.Ppid], p.Pid)
+			}
+		}
+		descendants := map[int32]bool{}
+		queue := []int32{q.Ppid}
+		for len(queue) > 0 {
+			pid := queue[0]
+			queue = queue[1:]
+			for _, c := range children[pid] {
+				if !descendants[c] {
+					descendants[c] = true
+					queue = append(queue, c)
+				}
+			}
+		}
+		inScope = func(p ProcessInfo) bool { return descendants[p.Pid] }
+	}
+	var matched []ProcessInfo
+	for _, p := range all {
+		if !inScope(p) || (nameRe != nil && !nameRe.MatchString(p.Name)) || (q.User != "" && !matchProcessUser(p.User, q.User)) {
+			continue
+		}
+		matched = append(matched, p)
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return key(matched[i]) > key(matched[j]) })
+
+	out := fmt.Sprintf("共 %d 个进程, 符合条件 %d 个", len(all), len(matched))
+	if len(matched) == 0 {
+		return out + "。\n", nil
+	}
+	if len(matched) > q.TopN {
+		matched = matched[:q.TopN]
+	}
+	out += fmt.Sprintf(", 按 %s 排序的前 %d 个如下(CPU 为 %v 内按全部核心归一化的利用率):\n", sortBy, len(matched), processSampleInterval)
+
+	rows, prefixes := matched, map[int32]string{}
+	if q.Tree {
+		rows, prefixes = processTree(matched)
+	}
+	withUser := q.User != ""
+	header := "PID | PPID | 进程 | 状态 | CPU(%) | 内存(MB) | 内存(%) | 线程 | 读(KB/s) | 写(KB/s) | 启动时间 | 执行路径"
+	if withUser {
+		header = strings.Replace(header, "进程 | ", "进程 | 用户 | ", 1)
+	}
+	out += header + "\n"
+	for _, p := range rows {
+		exe := p.Exe
+		if exe == "" {
+			exe = "-"
+		}
+		name := prefixes[p.Pid] + p.Name
+		if withUser {
+			name += " | " + p.User
+		}
+		out += fmt.Sprintf("%d | %d | %s | %s | %.1f | %.1f | %.1f | %d | %.1f | %.1f | %s | %s\n",
+			p.Pid, p.Ppid, name, p.Status, p.CPUPercent, float64(p.RSS)/(1<<20), p.MemPercent, p.Threads,
+			p.ReadRate/1024, p.WriteRate/1024, time.UnixMilli(p.CreateTime).Format(time.DateTime), exe)
+	}
+	return out, nil
+}
+
+// 将进程按父子关系排成树：父进程不在列表中的作为根，同级保持原有顺序，返回各进程名称前的缩进
+func processTree(list []ProcessInfo) ([]ProcessInfo, map[int32]string) {
+	inList := map[int32]bool{}
+	for _, p := range list {
+		inList[p.Pid] = true
+	}
+	children := map[int32][]ProcessInfo{}
+	var roots []ProcessInfo
+	for _, p := range list {
+		if inList[p.Ppid] && p.Ppid != p.Pid {
+			children[p.Ppid] = append(children[p.Ppid], p)
+		} else {
+			roots = append(roots, p)
+		}
+	}
+	var rows []ProcessInfo
+	prefixes := map[int32]string{}
+	var walk func(p ProcessInfo, depth int)
+	walk = func(p ProcessInfo, depth int) {
+		if depth > 0 {
+			prefixes[p.Pid] = strings.Repeat("  ", depth-1) + "└─ "
+		}
+		rows = append(rows, p)
+		for _, c := range children[p.Pid] {
+			walk(c, depth+1)
+		}
+	}
+	for _, p := range roots {
+		walk(p, 0)
+	}
+	return rows, prefixes
 }
 
-// 返回系统进程的字符串表示形式
-func GetSysProcessStr() (r string) {
-	raw := GetSysProcess()
-	for _, v := range raw {
-		r += fmt.Sprintf("%+v\n", v)
+// 获取所有进程的累计 CPU 时间、内存和 I/O 计数，供监控守护进程计算各进程的资源占用
+// 单个进程的信息读取失败（如已退出或无权限）时对应字段为零值
+func GetProcessCounters(ctx context.Context) ([]common.ProcessCounterStat, error) {
+	processes, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var counters []common.ProcessCounterStat
+	for _, p := range processes {
+		createTime, err := p.CreateTimeWithContext(ctx)
+		if err != nil {
+			continue // 已退出
+		}
+		c := common.ProcessCounterStat{Pid: p.Pid, CreateTime: createTime}
+		c.Name, _ = p.NameWithContext(ctx)
+		c.Exe, _ = p.ExeWithContext(ctx)
+		if times, err := p.TimesWithContext(ctx); err == nil {
+			c.CPUTime = times.User + times.System
+		}
+		if memInfo, err := p.MemoryInfoWithContext(ctx); err == nil {
+			c.RSS = memInfo.RSS
+		}
+		if io, err := p.IOCountersWithContext(ctx); err == nil {
+			c.ReadBytes, c.WriteBytes = io.ReadBytes, io.WriteBytes
+		}
+		counters = append(counters, c)
 	}
-	return
-}
\ No newline at end of file
+	return counters, nil
+}
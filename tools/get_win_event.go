@@ -6,29 +6,133 @@ package tools
 import (
 	"fmt"
 	"log"
-	"winds-assistant/utils"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
 )
 
-// QueryEvents 根据给定的事件查询条件 q，查询指定日志中的事件。
-func QueryEvents(logName string, startTime int, maxEvents int) (string, error) {
-    
-    startTimeFormat := fmt.Sprintf("(Get-Date).AddDays(%v)", -startTime)
-    // 安全创建命令对象（拆分命令和参数）
-    out, _, err := utils.RunCommand("PowerShell", "-Command", 
-        "Get-WinEvent", 
-        "-FilterHashtable",
-        fmt.Sprintf("@{ LogName='%s'; StartTime=%s }", logName, startTimeFormat,),
-        "-MaxEvents", fmt.Sprint(maxEvents),
-        "| Out-String -Width 4096",
-    )
-    
-    // 执行并检查错误
-    if err != nil {
-        log.Printf("[%s] Query Error: %v\n",
-        logName,
-            err,
-        );return "", err
-    }
-
-    return out, nil
+// 各事件源统一后的事件结构
+type LogEvent struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`  // Critical / Error / Warning / Information / Verbose
+	Source  string    `json:"source"` // 事件提供程序、进程名等
+	ID      string    `json:"id"`     // 事件 ID（没有时为空）
+	Message string    `json:"message"`
+}
+
+// 事件查询条件
+type EventQuery struct {
+	LogName string // 日志名称（Application / System / Security 等）
+	EventFilter
+}
+
+// 事件过滤条件，实时查询与离线 EVTX 分析共用
+type EventFilter struct {
+	Levels    []string  // 等级（Critical / Error / Warning / Information / Verbose），为空表示不限
+	Providers []string  // 事件来源，忽略大小写，为空表示不限
+	EventIDs  []string  // 事件 ID，为空表示不限
+	Keywords  []string  // 消息中包含任一关键字（忽略大小写），为空表示不限
+	StartTime time.Time // 零值表示不限
+	EndTime   time.Time // 零值表示不限
+	MaxEvents int       // 最多返回的事件数，0 表示不限
+}
+
+// 判断事件是否满足过滤条件（不含 MaxEvents）
+func (f EventFilter) Match(e LogEvent) bool {
+	if len(f.Levels) > 0 && !containsFold(f.Levels, e.Level) {
+		return false
+	}
+	if len(f.Providers) > 0 && !containsFold(f.Providers, e.Source) {
+		return false
+	}
+	if len(f.EventIDs) > 0 && !containsFold(f.EventIDs, e.ID) {
+		return false
+	}
+	if len(f.Keywords) > 0 && !containsAnyFold(e.Message, f.Keywords) {
+		return false
+	}
+	if !f.StartTime.IsZero() && e.Time.Before(f.StartTime) {
+		return false
+	}
+	if !f.EndTime.IsZero() && e.Time.After(f.EndTime) {
+		return false
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAnyFold(s string, keywords []string) bool {
+	s = strings.ToLower(s)
+	for _, k := range keywords {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" && strings.Contains(s, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// 事件源接口，返回的事件按时间从新到旧排列
+type EventSource interface {
+	Name() string
+	Query(q EventQuery) ([]LogEvent, error)
+}
+
+// 统一的事件等级
+const (
+	LevelCritical    = "Critical"
+	LevelError       = "Error"
+	LevelWarning     = "Warning"
+	LevelInformation = "Information"
+	LevelVerbose     = "Verbose"
+)
+
+// 根据平台自动选择事件源：Windows 使用 PowerShell，Linux 优先使用 journald，否则读取 syslog 文件
+func DefaultEventSource() EventSource {
+	if runtime.GOOS == "windows" {
+		return &PowerShellEventSource{}
+	}
+	if _, err := exec.LookPath("journalctl"); err == nil {
+		return &JournaldEventSource{}
+	}
+	return NewSyslogFileEventSource()
+}
+
+// QueryEvents 查询指定日志中满足过滤条件的事件。
+func QueryEvents(logName string, filter EventFilter) (string, error) {
+	source := DefaultEventSource()
+	events, err := source.Query(EventQuery{
+		LogName:     logName,
+		EventFilter: filter,
+	})
+
+	// 执行并检查错误
+	if err != nil {
+		log.Printf("[%s/%s] Query Error: %v\n", source.Name(), logName, err)
+		return "", err
+	}
+	return SummarizeEvents(events), nil
+}
+
+// 将事件格式化为每行一条的文本
+func FormatEvents(events []LogEvent) string {
+	var sb strings.Builder
+	for _, e := range events {
+		sb.WriteString(fmt.Sprintf("%s | %s | %s | %s | %s\n",
+			e.Time.Format(time.DateTime), e.Level, e.Source, e.ID, oneLine(e.Message)))
+	}
+	return sb.String()
+}
+
+// 合并多行消息中的空白
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
 }
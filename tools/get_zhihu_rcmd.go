@@ -9,9 +9,6 @@ import (
 	"strconv"
 	"encoding/json"
 	"strings"
-	"path/filepath"
-	"gopkg.in/yaml.v3"
-	"os"
 )
 
 // 定义一些响应体
@@ -34,7 +31,15 @@ type DataZop struct {
 
 // 工具配置
 type ZhihuToolCfg struct {
-	Cookie 			string			`json:"cookie"`
+	Cookie 			string			`yaml:"cookie"`
+}
+
+// 知乎首页推荐必须登录，Cookie 为必填
+func (c *ZhihuToolCfg) Missing() (missing []string) {
+	if c.Cookie == "" {
+		missing = append(missing, "cookie")
+	}
+	return
 }
 
 // 根据 Cookie 获取知乎首页推荐
@@ -116,28 +121,14 @@ func GetZhihuRcmd(ctx context.Context, cookie string) (items []ZhihuResponse, er
 	return
 }
 
-// 根据提供的 cookie 和轮数获取知乎推荐内容的字符串
-func GetZhihuRcmdStr(rounds int) (r string){
-	// 读取工具配置文件
-    data, err := os.ReadFile(filepath.Clean("config/agent_get_zhihu_rcmd.yaml"))
-    if err != nil {
-        fmt.Printf("error read yaml: %v", err)
-        return
-    }
-
-    // 解析 Yaml
-	var config ZhihuToolCfg
-    if err := yaml.Unmarshal(data, &config); err != nil {
-        fmt.Printf("error extract yaml: %v", err)
-        return
-    }
-
+// 根据提供的配置和轮数获取知乎推荐内容的字符串
+func GetZhihuRcmdStr(config ZhihuToolCfg, rounds int) (r string, err error){
 	ctx, cancel := context.WithTimeout(context.Background(), 10 * time.Second)
 	defer cancel()
 	for i := 0; i < rounds; i++ {
 		zhihuresp, err := GetZhihuRcmd(ctx, config.Cookie)
 		if err != nil {
-			return
+			return r, err
 		}
 
 		for _, item := range zhihuresp {
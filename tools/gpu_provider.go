@@ -0,0 +1,385 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"winds-assistant/common"
+	"winds-assistant/utils"
+)
+
+// GPU 信息提供者，每个厂商一个实现
+type GPUProvider interface {
+	Name() string
+	Available() bool // 当前系统上是否能使用该提供者（命令或 sysfs 是否存在）
+	Query() ([]common.GPUInfoStat, error)
+}
+
+// ** 注册 GPU 提供者，同一厂商的多个提供者按顺序使用第一个可用的
+var GPUProviderRegister = []GPUProvider{
+	&NvidiaSMIProvider{},
+	&ROCmSMIProvider{},
+	&AMDSysfsProvider{Root: drmSysfsRoot},
+	&IntelGPUProvider{Root: drmSysfsRoot},
+}
+
+const drmSysfsRoot = "/sys/class/drm"
+
+// 查询所有可用提供者的 GPU，全部失败或没有 GPU 时返回错误
+func GetGPUInfo() ([]common.GPUInfoStat, error) {
+	var stats []common.GPUInfoStat
+	var errs []string
+	vendors := map[string]bool{}
+	for _, p := range GPUProviderRegister {
+		if !p.Available() {
+			continue
+		}
+		gpus, err := p.Query()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", p.Name(), err))
+			continue
+		}
+		// 同一厂商已有提供者返回结果时跳过（如 rocm-smi 与 sysfs）
+		if len(gpus) == 0 || vendors[gpus[0].Vendor] {
+			continue
+		}
+		vendors[gpus[0].Vendor] = true
+		stats = append(stats, gpus...)
+	}
+
+	if len(stats) == 0 {
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+		}
+		return nil, fmt.Errorf("no supported GPU found")
+	}
+	// 只要有 GPU 数据就正常返回，失败的提供者只记录日志
+	for _, e := range errs {
+		log.Printf("[gpu] %s", e)
+	}
+	return stats, nil
+}
+
+func commandExists(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// ** NVIDIA：nvidia-smi **
+type NvidiaSMIProvider struct{}
+
+func (p *NvidiaSMIProvider) Name() string    { return "nvidia-smi" }
+func (p *NvidiaSMIProvider) Available() bool { return commandExists("nvidia-smi") }
+
+func (p *NvidiaSMIProvider) Query() ([]common.GPUInfoStat, error) {
+	out, _, err := utils.RunCommand("nvidia-smi",
+		"--query-gpu=index,name,utilization.gpu,memory.used,memory.total,clocks.current.graphics,clocks.current.memory,temperature.gpu,power.draw",
+		"--format=csv,noheader,nounits")
+	if err != nil {
+		return nil, fmt.Errorf("执行nvidia-smi失败: %v\n输出: %s", err, out)
+	}
+	return parseNvidiaSMI(out), nil
+}
+
+// 解析 nvidia-smi --format=csv,noheader,nounits 的输出，每行一块 GPU
+func parseNvidiaSMI(out string) []common.GPUInfoStat {
+	var stats []common.GPUInfoStat
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) < 9 { // 包含index后字段数应为9
+			continue // 跳过格式异常行
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		stats = append(stats, common.GPUInfoStat{
+			Index:       utils.ParseUint(fields[0]),  // 字段0: index
+			Name:        fields[1],                   // 字段1: name
+			Utilization: gpuFloat(fields[2]),         // 字段2: utilization.gpu
+			MemUsed:     gpuInt(fields[3]),           // 字段3: memory.used
+			MemTotal:    utils.ParseUint(fields[4]),  // 字段4: memory.total
+			CoreClock:   gpuInt(fields[5]),           // 字段5: core_clock
+			MemClock:    gpuInt(fields[6]),           // 字段6: mem_clock
+			Temperature: gpuInt(fields[7]),           // 字段7: temperature
+			PowerDraw:   gpuFloat(fields[8]),         // 字段8: power（不支持时为 [N/A]，记为 -1）
+			Vendor:      "NVIDIA",
+		})
+	}
+	return stats
+}
+
+// ** AMD：rocm-smi **
+type ROCmSMIProvider struct{}
+
+func (p *ROCmSMIProvider) Name() string    { return "rocm-smi" }
+func (p *ROCmSMIProvider) Available() bool { return commandExists("rocm-smi") }
+
+func (p *ROCmSMIProvider) Query() ([]common.GPUInfoStat, error) {
+	out, err := exec.Command("rocm-smi",
+		"--showuse", "--showmeminfo", "vram", "--showtemp", "--showpower", "--showclocks", "--showproductname",
+		"--json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("执行rocm-smi失败: %v", err)
+	}
+	return parseROCmSMIJSON(string(out))
+}
+
+var (
+	rocmCardPattern  = regexp.MustCompile(`^card(\d+)$`)
+	rocmClockPattern = regexp.MustCompile(`(\d+)\s*Mhz`)
+)
+
+// 解析 rocm-smi --json 的输出；不同版本的字段名略有差异，按关键字匹配
+func parseROCmSMIJSON(out string) ([]common.GPUInfoStat, error) {
+	// 部分版本会在 JSON 前输出警告信息
+	if i := strings.Index(out, "{"); i > 0 {
+		out = out[i:]
+	}
+	var cards map[string]map[string]string
+	if err := json.Unmarshal([]byte(out), &cards); err != nil {
+		return nil, fmt.Errorf("extract rocm-smi output failed: %w", err)
+	}
+
+	var stats []common.GPUInfoStat
+	for card, fields := range cards {
+		m := rocmCardPattern.FindStringSubmatch(card)
+		if m == nil { // 跳过 "system" 等非显卡条目
+			continue
+		}
+		index, _ := strconv.ParseUint(m[1], 10, 64)
+		stat := common.GPUInfoStat{Index: index, Utilization: -1, MemUsed: -1, CoreClock: -1, MemClock: -1, Temperature: -1, PowerDraw: -1, Vendor: "AMD"}
+		for key, value := range fields {
+			k := strings.ToLower(key)
+			switch {
+			case strings.Contains(k, "card series"):
+				stat.Name = value
+			case strings.Contains(k, "card model"):
+				if stat.Name == "" {
+					stat.Name = value
+				}
+			case strings.Contains(k, "gpu use"):
+				stat.Utilization = gpuFloat(value)
+			case strings.Contains(k, "vram total used memory"):
+				stat.MemUsed = scaleGPUValue(gpuInt(value), 1024*1024)
+			case strings.Contains(k, "vram total memory"):
+				stat.MemTotal = utils.ParseUint(value) / 1024 / 1024
+			case strings.Contains(k, "temperature") && strings.Contains(k, "edge"):
+				if t := gpuFloat(value); t >= 0 {
+					stat.Temperature = int64(t)
+				}
+			case strings.Contains(k, "power") && strings.Contains(k, "(w)"):
+				stat.PowerDraw = gpuFloat(value)
+			case strings.HasPrefix(k, "sclk"): // "sclk clock speed:" 和 "sclk clock level:"，只有前者带频率
+				if v := parseMhz(value); v >= 0 {
+					stat.CoreClock = v
+				}
+			case strings.HasPrefix(k, "mclk"):
+				if v := parseMhz(value); v >= 0 {
+					stat.MemClock = v
+				}
+			}
+		}
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Index < stats[j].Index })
+	return stats, nil
+}
+
+func parseMhz(s string) int64 {
+	if m := rocmClockPattern.FindStringSubmatch(s); m != nil {
+		v, _ := strconv.ParseInt(m[1], 10, 64)
+		return v
+	}
+	return -1
+}
+
+// 解析数值，无法获取时（nvidia-smi 的 [N/A]、[Not Supported]，缺失的 sysfs 文件等）返回 -1
+func gpuInt(s string) int64 {
+	v, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || v < 0 {
+		return -1
+	}
+	return v
+}
+
+func gpuFloat(s string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || v < 0 {
+		return -1
+	}
+	return v
+}
+
+// 按单位换算（如字节 -> MB），保留 -1
+func scaleGPUValue(v int64, unit int64) int64 {
+	if v < 0 {
+		return -1
+	}
+	return v / unit
+}
+
+// ** DRM sysfs（Linux） **
+// 返回 root 下指定厂商 ID 的显卡目录（cardN，不含 cardN-DP-1 等接口目录）
+func drmCards(root string, vendorID string) (cards map[uint64]string) {
+	cards = map[uint64]string{}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		m := rocmCardPattern.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		dir := filepath.Join(root, e.Name())
+		if readSysfs(filepath.Join(dir, "device", "vendor")) != vendorID {
+			continue
+		}
+		index, _ := strconv.ParseUint(m[1], 10, 64)
+		cards[index] = dir
+	}
+	return
+}
+
+func readSysfs(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// 读取 hwmon 下第一个存在的文件
+func readHwmon(device string, name string) string {
+	matches, _ := filepath.Glob(filepath.Join(device, "hwmon", "hwmon*", name))
+	for _, m := range matches {
+		if v := readSysfs(m); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// 当前 DPM 频率：pp_dpm_sclk 中带 * 的那一行，如 "1: 1800Mhz *"，没有时返回 -1
+func currentDPMClock(path string) int64 {
+	for _, line := range strings.Split(readSysfs(path), "\n") {
+		if strings.HasSuffix(strings.TrimSpace(line), "*") {
+			return parseMhz(line)
+		}
+	}
+	return -1
+}
+
+// ** AMD：amdgpu sysfs（没有安装 ROCm 时使用） **
+type AMDSysfsProvider struct {
+	Root string
+}
+
+func (p *AMDSysfsProvider) Name() string { return "amdgpu-sysfs" }
+
+func (p *AMDSysfsProvider) Available() bool {
+	return runtime.GOOS == "linux" && len(drmCards(p.Root, "0x1002")) > 0
+}
+
+func (p *AMDSysfsProvider) Query() ([]common.GPUInfoStat, error) {
+	var stats []common.GPUInfoStat
+	for index, dir := range drmCards(p.Root, "0x1002") {
+		device := filepath.Join(dir, "device")
+		stat := common.GPUInfoStat{Index: index, Name: "AMD GPU", Utilization: -1, Vendor: "AMD"}
+		if v := readSysfs(filepath.Join(device, "gpu_busy_percent")); v != "" {
+			stat.Utilization = utils.ParseFloat(v)
+		}
+		stat.MemUsed = scaleGPUValue(gpuInt(readSysfs(filepath.Join(device, "mem_info_vram_used"))), 1024*1024)
+		stat.MemTotal = utils.ParseUint(readSysfs(filepath.Join(device, "mem_info_vram_total"))) / 1024 / 1024
+		stat.CoreClock = currentDPMClock(filepath.Join(device, "pp_dpm_sclk"))
+		stat.MemClock = currentDPMClock(filepath.Join(device, "pp_dpm_mclk"))
+		// 没有 hwmon 时温度和功耗无法获取
+		stat.Temperature = scaleGPUValue(gpuInt(readHwmon(device, "temp1_input")), 1000) // 毫摄氏度
+		stat.PowerDraw = -1
+		if power := gpuFloat(readHwmon(device, "power1_average")); power >= 0 {
+			stat.PowerDraw = power / 1000000 // 微瓦
+		}
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Index < stats[j].Index })
+	return stats, nil
+}
+
+// ** Intel **
+// Linux 通过 i915 / xe 的 sysfs 读取频率；Windows 通过 WMI 列出显卡。
+// 两者都无法直接获取利用率（需要 intel_gpu_top 等特权工具）、显存占用、温度和功耗，均记为 -1
+type IntelGPUProvider struct {
+	Root string
+
+	adapters []common.GPUInfoStat // Windows 上的显卡列表不会变化，只查询一次
+}
+
+func (p *IntelGPUProvider) Name() string { return "intel" }
+
+func (p *IntelGPUProvider) Available() bool {
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	return len(drmCards(p.Root, "0x8086")) > 0
+}
+
+func (p *IntelGPUProvider) Query() ([]common.GPUInfoStat, error) {
+	if runtime.GOOS == "windows" {
+		if p.adapters == nil {
+			out, _, err := utils.RunCommand("PowerShell", "-Command",
+				"Get-CimInstance Win32_VideoController | Where-Object { $_.AdapterCompatibility -like '*Intel*' } | ForEach-Object { $_.Name }")
+			if err != nil {
+				return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(out))
+			}
+			p.adapters = parseIntelAdapters(out)
+		}
+		return p.adapters, nil
+	}
+
+	var stats []common.GPUInfoStat
+	for index, dir := range drmCards(p.Root, "0x8086") {
+		clock := readSysfs(filepath.Join(dir, "gt_cur_freq_mhz"))
+		if clock == "" {
+			clock = readSysfs(filepath.Join(dir, "device", "tile0", "gt0", "freq0", "cur_freq")) // xe 驱动
+		}
+		stat := unknownIntelGPU(index, "Intel GPU")
+		stat.CoreClock = gpuInt(clock)
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Index < stats[j].Index })
+	return stats, nil
+}
+
+// 每行一个显卡名称
+func parseIntelAdapters(out string) []common.GPUInfoStat {
+	stats := []common.GPUInfoStat{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if name := strings.TrimSpace(line); name != "" {
+			stats = append(stats, unknownIntelGPU(uint64(len(stats)), name))
+		}
+	}
+	return stats
+}
+
+// 各项数值均无法获取的 Intel 显卡
+func unknownIntelGPU(index uint64, name string) common.GPUInfoStat {
+	return common.GPUInfoStat{
+		Index:       index,
+		Name:        name,
+		Utilization: -1,
+		MemUsed:     -1,
+		CoreClock:   -1,
+		MemClock:    -1,
+		Temperature: -1,
+		PowerDraw:   -1,
+		Vendor:      "Intel",
+	}
+}
@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"winds-assistant/common"
+)
+
+func readFixture(t *testing.T, path ...string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(append([]string{"testdata"}, path...)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// 在 root 下写入文件，路径中的目录自动创建
+func writeSysfs(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func checkGPUs(t *testing.T, got, want []common.GPUInfoStat) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d GPUs, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("GPU %d =\n%+v\nwant\n%+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseNvidiaSMI(t *testing.T) {
+	got := parseNvidiaSMI(readFixture(t, "gpu", "nvidia-smi.csv") + "\ngarbage line\n")
+	checkGPUs(t, got, []common.GPUInfoStat{
+		{Index: 0, Name: "NVIDIA GeForce RTX 3090", Utilization: 35, MemUsed: 2048, MemTotal: 24576, CoreClock: 1695, MemClock: 9751, Temperature: 62, PowerDraw: 215.43, Vendor: "NVIDIA"},
+		{Index: 1, Name: "NVIDIA GeForce GTX 1080", Utilization: -1, MemUsed: 512, MemTotal: 8192, CoreClock: 139, MemClock: 405, Temperature: 38, PowerDraw: -1, Vendor: "NVIDIA"},
+	})
+}
+
+func TestParseROCmSMIJSON(t *testing.T) {
+	got, err := parseROCmSMIJSON(readFixture(t, "gpu", "rocm-smi.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGPUs(t, got, []common.GPUInfoStat{
+		{Index: 0, Name: "Navi 21 [Radeon RX 6800/6800 XT / 6900 XT]", Utilization: 12, MemUsed: 1024, MemTotal: 16368, CoreClock: 1800, MemClock: 1000, Temperature: 45, PowerDraw: 35, Vendor: "AMD"},
+		{Index: 1, Name: "0x1636", Utilization: 0, MemUsed: 32, MemTotal: 512, CoreClock: -1, MemClock: -1, Temperature: -1, PowerDraw: -1, Vendor: "AMD"},
+	})
+
+	if _, err := parseROCmSMIJSON("ERROR: No AMD GPUs found"); err == nil {
+		t.Error("parseROCmSMIJSON accepted output without JSON")
+	}
+}
+
+func TestParseIntelAdapters(t *testing.T) {
+	got := parseIntelAdapters("Intel(R) UHD Graphics 770\r\n\r\nIntel(R) Arc(TM) A770 Graphics\r\n")
+	checkGPUs(t, got, []common.GPUInfoStat{
+		unknownIntelGPU(0, "Intel(R) UHD Graphics 770"),
+		unknownIntelGPU(1, "Intel(R) Arc(TM) A770 Graphics"),
+	})
+	if got := parseIntelAdapters(""); len(got) != 0 {
+		t.Errorf("parseIntelAdapters(\"\") = %+v, want none", got)
+	}
+}
+
+func TestCurrentDPMClock(t *testing.T) {
+	root := t.TempDir()
+	writeSysfs(t, root, map[string]string{
+		"pp_dpm_sclk": "0: 500Mhz\n1: 1800Mhz *\n2: 2100Mhz\n",
+		"pp_dpm_mclk": "0: 96Mhz\n1: 456Mhz\n",
+	})
+	tests := []struct {
+		file string
+		want int64
+	}{
+		{"pp_dpm_sclk", 1800},
+		{"pp_dpm_mclk", -1}, // 没有当前等级
+		{"missing", -1},
+	}
+	for _, tt := range tests {
+		if got := currentDPMClock(filepath.Join(root, tt.file)); got != tt.want {
+			t.Errorf("currentDPMClock(%s) = %d, want %d", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestDRMSysfsProviders(t *testing.T) {
+	root := t.TempDir()
+	writeSysfs(t, root, map[string]string{
+		// AMD 独显，带 hwmon
+		"card0/device/vendor":                      "0x1002\n",
+		"card0/device/gpu_busy_percent":            "23\n",
+		"card0/device/mem_info_vram_used":          "2147483648\n",
+		"card0/device/mem_info_vram_total":         "8589934592\n",
+		"card0/device/pp_dpm_sclk":                 "0: 500Mhz\n1: 2400Mhz *\n",
+		"card0/device/pp_dpm_mclk":                 "0: 96Mhz\n1: 1000Mhz *\n",
+		"card0/device/hwmon/hwmon3/temp1_input":    "51000\n",
+		"card0/device/hwmon/hwmon3/power1_average": "42000000\n",
+		"card0-DP-1/status":                        "connected\n", // 接口目录，不是显卡
+		// AMD 核显，没有 hwmon 和显存占用
+		"card2/device/vendor":           "0x1002\n",
+		"card2/device/gpu_busy_percent": "5\n",
+		// Intel 核显（i915）
+		"card1/device/vendor":   "0x8086\n",
+		"card1/gt_cur_freq_mhz": "1300\n",
+		// Intel 独显（xe）
+		"card3/device/vendor":                   "0x8086\n",
+		"card3/device/tile0/gt0/freq0/cur_freq": "2000\n",
+	})
+
+	amd, err := (&AMDSysfsProvider{Root: root}).Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGPUs(t, amd, []common.GPUInfoStat{
+		{Index: 0, Name: "AMD GPU", Utilization: 23, MemUsed: 2048, MemTotal: 8192, CoreClock: 2400, MemClock: 1000, Temperature: 51, PowerDraw: 42, Vendor: "AMD"},
+		{Index: 2, Name: "AMD GPU", Utilization: 5, MemUsed: -1, MemTotal: 0, CoreClock: -1, MemClock: -1, Temperature: -1, PowerDraw: -1, Vendor: "AMD"},
+	})
+
+	if runtime.GOOS == "windows" { // Windows 上 Intel 显卡通过 WMI 查询
+		return
+	}
+	intel, err := (&IntelGPUProvider{Root: root}).Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []common.GPUInfoStat{unknownIntelGPU(1, "Intel GPU"), unknownIntelGPU(3, "Intel GPU")}
+	want[0].CoreClock, want[1].CoreClock = 1300, 2000
+	checkGPUs(t, intel, want)
+}
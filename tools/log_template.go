@@ -0,0 +1,233 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ** 日志模式归纳 **
+// 参考 Drain 算法：按词数和前几个词构建固定深度的前缀树，叶子节点中按相似度归入已有模式或新建模式，
+// 不同的位置替换为通配符 <*>，从而把大量相似的日志压缩为少量模式及其出现次数
+const templateWildcard = "<*>"
+
+// 一个日志模式
+type LogTemplate struct {
+	Tokens    []string
+	Count     int
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Examples  [][]string // 通配符位置上出现过的取值，最多保留 maxTemplateExamples 组
+	Sample    LogEvent   // 第一条原始事件
+}
+
+const maxTemplateExamples = 3
+
+func (t *LogTemplate) String() string {
+	return strings.Join(t.Tokens, " ")
+}
+
+type templateNode struct {
+	children  map[string]*templateNode
+	templates []*LogTemplate
+}
+
+func newTemplateNode() *templateNode {
+	return &templateNode{children: map[string]*templateNode{}}
+}
+
+type TemplateMiner struct {
+	Depth        int     // 前缀树深度（含词数层），至少为 3
+	SimThreshold float64 // 归入已有模式所需的最低相似度
+	MaxChildren  int     // 每个节点的最大子节点数，超出后归入通配符子节点
+
+	root      *templateNode
+	templates []*LogTemplate
+}
+
+func NewTemplateMiner() *TemplateMiner {
+	return &TemplateMiner{
+		Depth:        4,
+		SimThreshold: 0.5,
+		MaxChildren:  100,
+		root:         newTemplateNode(),
+	}
+}
+
+// 拆分消息并把明显的变量（数字、十六进制、GUID、路径等）预先替换为通配符
+func templateTokens(msg string) []string {
+	tokens := strings.Fields(msg)
+	for i, tok := range tokens {
+		if isVariableToken(tok) {
+			tokens[i] = templateWildcard
+		}
+	}
+	return tokens
+}
+
+func isVariableToken(tok string) bool {
+	for _, r := range tok {
+		if unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return strings.ContainsAny(tok, `/\`) && len(tok) > 1
+}
+
+// 加入一条事件，返回其所属的模式
+func (m *TemplateMiner) Add(e LogEvent) *LogTemplate {
+	raw := strings.Fields(e.Message)
+	tokens := templateTokens(e.Message)
+
+	leaf := m.leaf(tokens)
+	best, bestSim := (*LogTemplate)(nil), -1.0
+	for _, t := range leaf.templates {
+		if sim := templateSimilarity(t.Tokens, tokens); sim > bestSim {
+			best, bestSim = t, sim
+		}
+	}
+
+	if best == nil || bestSim < m.SimThreshold {
+		best = &LogTemplate{Tokens: tokens, FirstSeen: e.Time, LastSeen: e.Time, Sample: e}
+		leaf.templates = append(leaf.templates, best)
+		m.templates = append(m.templates, best)
+	} else {
+		for i, tok := range best.Tokens {
+			if tok != tokens[i] {
+				best.Tokens[i] = templateWildcard
+			}
+		}
+	}
+
+	best.Count++
+	if e.Time.Before(best.FirstSeen) {
+		best.FirstSeen = e.Time
+	}
+	if e.Time.After(best.LastSeen) {
+		best.LastSeen = e.Time
+	}
+	if len(best.Examples) < maxTemplateExamples {
+		var vars []string
+		for i, tok := range best.Tokens {
+			if tok == templateWildcard && i < len(raw) {
+				vars = append(vars, raw[i])
+			}
+		}
+		if len(vars) > 0 {
+			best.Examples = append(best.Examples, vars)
+		}
+	}
+	return best
+}
+
+// 沿前缀树找到（必要时创建）消息对应的叶子节点：第一层为词数，之后每层为一个前缀词
+func (m *TemplateMiner) leaf(tokens []string) *templateNode {
+	node := m.child(m.root, fmt.Sprint(len(tokens)))
+	for i := 0; i < m.Depth-2 && i < len(tokens); i++ {
+		node = m.child(node, tokens[i])
+	}
+	return node
+}
+
+func (m *TemplateMiner) child(node *templateNode, key string) *templateNode {
+	if c, ok := node.children[key]; ok {
+		return c
+	}
+	if key != templateWildcard && len(node.children) >= m.MaxChildren {
+		key = templateWildcard
+		if c, ok := node.children[key]; ok {
+			return c
+		}
+	}
+	c := newTemplateNode()
+	node.children[key] = c
+	return c
+}
+
+// 相同位置上相同词的比例（词数相同才会进入同一叶子）
+func templateSimilarity(a, b []string) float64 {
+	if len(a) == 0 {
+		return 1
+	}
+	same := 0
+	for i := range a {
+		if a[i] == templateWildcard || a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(a))
+}
+
+// 所有模式，按出现次数从多到少排列
+func (m *TemplateMiner) Templates() []*LogTemplate {
+	templates := append([]*LogTemplate(nil), m.templates...)
+	sort.SliceStable(templates, func(i, j int) bool { return templates[i].Count > templates[j].Count })
+	return templates
+}
+
+// 事件数不超过该值时直接列出，不做归纳
+const summarizeMinEvents = 10
+
+// 把事件压缩为模式摘要：同一等级、来源和事件 ID 的消息分别归纳，重复的模式给出次数与时间范围，只出现一次的罕见事件原样列出
+func SummarizeEvents(events []LogEvent) string {
+	if len(events) <= summarizeMinEvents {
+		return FormatEvents(events)
+	}
+
+	miners := map[string]*TemplateMiner{}
+	var keys []string
+	for _, e := range events {
+		key := e.Level + "|" + e.Source + "|" + e.ID
+		miner, ok := miners[key]
+		if !ok {
+			miner = NewTemplateMiner()
+			miners[key] = miner
+			keys = append(keys, key)
+		}
+		miner.Add(e)
+	}
+
+	var repeated, rare []*LogTemplate
+	for _, key := range keys {
+		for _, t := range miners[key].Templates() {
+			if t.Count > 1 {
+				repeated = append(repeated, t)
+			} else {
+				rare = append(rare, t)
+			}
+		}
+	}
+	// 没有任何重复时归纳没有意义
+	if len(repeated) == 0 {
+		return FormatEvents(events)
+	}
+	sort.SliceStable(repeated, func(i, j int) bool { return repeated[i].Count > repeated[j].Count })
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("共 %d 条事件, 归纳为 %d 个重复模式和 %d 条罕见事件。\n", len(events), len(repeated), len(rare)))
+	sb.WriteString("重复模式(次数 | 首次 ~ 最近 | 等级 | 来源 | 事件ID | 模式 | 变量示例):\n")
+	for _, t := range repeated {
+		examples := []string{"-"}
+		if len(t.Examples) > 0 {
+			examples = examples[:0]
+		}
+		for _, vars := range t.Examples {
+			examples = append(examples, strings.Join(vars, ", "))
+		}
+		sb.WriteString(fmt.Sprintf("%d 次 | %s ~ %s | %s | %s | %s | %s | %s\n",
+			t.Count, t.FirstSeen.Format(time.DateTime), t.LastSeen.Format(time.DateTime),
+			t.Sample.Level, t.Sample.Source, t.Sample.ID, t.String(), strings.Join(examples, "; ")))
+	}
+	if len(rare) > 0 {
+		var samples []LogEvent
+		for _, t := range rare {
+			samples = append(samples, t.Sample)
+		}
+		sortEventsDesc(samples)
+		sb.WriteString("罕见事件:\n")
+		sb.WriteString(FormatEvents(samples))
+	}
+	return sb.String()
+}
@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateMinerAdd(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []string
+		want     []string // 模式及次数，按出现次数从多到少
+	}{
+		{
+			"numbers and addresses become wildcards",
+			[]string{"Connection from 10.0.0.1 closed", "Connection from 10.0.0.2 closed", "Connection from 192.168.1.7 closed"},
+			[]string{"3 Connection from <*> closed"},
+		},
+		{
+			"paths become wildcards",
+			[]string{`Cannot open C:\Windows\Temp\a.tmp for writing`, `Cannot open /var/tmp/b.lock for writing`},
+			[]string{"2 Cannot open <*> for writing"},
+		},
+		{
+			"differing words merge once similar enough",
+			[]string{"Service Spooler entered running state", "Service Spooler entered stopped state", "Service Spooler entered paused state"},
+			[]string{"3 Service Spooler entered <*> state"},
+		},
+		{
+			"dissimilar messages stay separate",
+			[]string{"Disk is full on volume", "Disk is back to normal", "Disk is full on volume"},
+			[]string{"2 Disk is full on volume", "1 Disk is back to normal"},
+		},
+		{
+			"different lengths never merge",
+			[]string{"Backup finished", "Backup finished with warnings", "Backup finished"},
+			[]string{"2 Backup finished", "1 Backup finished with warnings"},
+		},
+		{
+			"different prefixes go to different leaves",
+			[]string{"User alice logged in", "User bob logged in"},
+			[]string{"1 User alice logged in", "1 User bob logged in"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewTemplateMiner()
+			for _, msg := range tt.messages {
+				m.Add(LogEvent{Message: msg})
+			}
+			var got []string
+			for _, tmpl := range m.Templates() {
+				got = append(got, fmt.Sprintf("%d %s", tmpl.Count, tmpl))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("templates =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestTemplateMinerExamplesAndTimes(t *testing.T) {
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.Local)
+	m := NewTemplateMiner()
+	var tmpl *LogTemplate
+	for i, offset := range []int{5, 0, 9, 3, 7} { // 时间乱序
+		tmpl = m.Add(LogEvent{Time: base.Add(time.Duration(offset) * time.Minute), Message: fmt.Sprintf("Retry %d of request req-%d", i+1, 100+i)})
+	}
+	if tmpl.String() != "Retry <*> of request <*>" || tmpl.Count != 5 {
+		t.Fatalf("template = %d %s", tmpl.Count, tmpl)
+	}
+	if !tmpl.FirstSeen.Equal(base) || !tmpl.LastSeen.Equal(base.Add(9*time.Minute)) {
+		t.Errorf("seen %s ~ %s", tmpl.FirstSeen, tmpl.LastSeen)
+	}
+	want := [][]string{{"1", "req-100"}, {"2", "req-101"}, {"3", "req-102"}}
+	if fmt.Sprint(tmpl.Examples) != fmt.Sprint(want) {
+		t.Errorf("examples = %v, want %v", tmpl.Examples, want)
+	}
+	if tmpl.Sample.Message != "Retry 1 of request req-100" {
+		t.Errorf("sample = %q, want the first event", tmpl.Sample.Message)
+	}
+}
+
+func TestTemplateSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"a b c d", "a b c d", 1},
+		{"a b c d", "a b x d", 0.75},
+		{"a <*> c d", "a b x d", 0.75}, // 模式中的通配符匹配任意词
+		{"a b c d", "w x y z", 0},
+		{"", "", 1},
+	}
+	for _, tt := range tests {
+		if got := templateSimilarity(strings.Fields(tt.a), strings.Fields(tt.b)); got != tt.want {
+			t.Errorf("templateSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSummarizeEvents(t *testing.T) {
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.Local)
+	event := func(minute int, source, msg string) LogEvent {
+		return LogEvent{Time: base.Add(time.Duration(minute) * time.Minute), Level: LevelError, Source: source, ID: "1", Message: msg}
+	}
+
+	// 事件较少时直接列出
+	few := []LogEvent{event(0, "app", "Connection from 10.0.0.1 closed"), event(1, "app", "Connection from 10.0.0.2 closed")}
+	if got := SummarizeEvents(few); got != FormatEvents(few) {
+		t.Errorf("SummarizeEvents(2 events) =\n%s\nwant the plain list", got)
+	}
+
+	var events []LogEvent
+	for i := 0; i < 12; i++ {
+		events = append(events, event(i, "app", fmt.Sprintf("Connection from 10.0.0.%d closed", i)))
+	}
+	events = append(events,
+		event(20, "app", "Database schema upgraded to v7"),
+		event(21, "proxy", "Connection from 10.0.0.1 closed"), // 来源不同，不与 app 的模式合并
+		event(22, "proxy", "Connection from 10.0.0.2 closed"),
+	)
+	got := SummarizeEvents(events)
+	for _, want := range []string{
+		"共 15 条事件, 归纳为 2 个重复模式和 1 条罕见事件。",
+		"12 次 | 2025-03-01 10:00:00 ~ 2025-03-01 10:11:00 | Error | app | 1 | Connection from <*> closed | 10.0.0.0; 10.0.0.1; 10.0.0.2\n",
+		"2 次 | 2025-03-01 10:21:00 ~ 2025-03-01 10:22:00 | Error | proxy | 1 | Connection from <*> closed |",
+		"罕见事件:\n",
+		"Database schema upgraded to v7",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("summary missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Index(got, "\n12 次") > strings.Index(got, "\n2 次 |") {
+		t.Errorf("patterns not ordered by count:\n%s", got)
+	}
+
+	// 没有重复时归纳没有意义
+	var unique []LogEvent
+	for i, word := range strings.Fields("alpha bravo charlie delta echo foxtrot golf hotel india juliet kilo") {
+		unique = append(unique, event(i, "app", "Started "+word+" service now"))
+	}
+	if got := SummarizeEvents(unique); got != FormatEvents(unique) {
+		t.Errorf("SummarizeEvents(unique events) =\n%s\nwant the plain list", got)
+	}
+}
@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// 支持的进程操作和优先级
+var (
+	ProcessActions    = []string{"terminate", "kill", "suspend", "resume", "set_priority"}
+	ProcessPriorities = []string{"idle", "below_normal", "normal", "above_normal", "high"}
+)
+
+// 操作的目标进程，创建时间用于确认执行时仍是同一个进程（PID 会被复用）
+type ProcessTarget struct {
+	Pid        int32
+	CreateTime int64 // 创建时间戳（毫秒）
+	Name       string
+	Exe        string
+	User       string
+	Cmdline    string
+}
+
+// 检查操作和优先级参数
+func CheckProcessAction(action, priority string) error {
+	if !slices.Contains(ProcessActions, action) {
+		return fmt.Errorf("unknown action %q, available: %s", action, strings.Join(ProcessActions, ", "))
+	}
+	if action == "set_priority" && !slices.Contains(ProcessPriorities, priority) {
+		return fmt.Errorf("unknown priority %q, available: %s", priority, strings.Join(ProcessPriorities, ", "))
+	}
+	return nil
+}
+
+// 读取目标进程的信息，用于向用户确认；不允许操作 PID 0 和本程序自身
+func GetProcessTarget(ctx context.Context, pid int32) (ProcessTarget, error) {
+	if pid <= 0 || int(pid) == os.Getpid() {
+		return ProcessTarget{}, fmt.Errorf("process %d can not be managed", pid)
+	}
+	p, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return ProcessTarget{}, fmt.Errorf("process %d not found: %w", pid, err)
+	}
+	createTime, err := p.CreateTimeWithContext(ctx)
+	if err != nil {
+		return ProcessTarget{}, fmt.Errorf("process %d not found: %w", pid, err)
+	}
+	t := ProcessTarget{Pid: pid, CreateTime: createTime}
+	t.Name, _ = p.NameWithContext(ctx)
+	t.Exe, _ = p.ExeWithContext(ctx)
+	t.User, _ = p.UsernameWithContext(ctx)
+	t.Cmdline, _ = p.CmdlineWithContext(ctx)
+	return t, nil
+}
+
+// 对目标进程执行操作，执行前确认进程仍是用户确认时的那一个
+func ManageProcess(ctx context.Context, target ProcessTarget, action, priority string) error {
+	if err := CheckProcessAction(action, priority); err != nil {
+		return err
+	}
+	p, err := process.NewProcessWithContext(ctx, target.Pid)
+	if err != nil {
+		return fmt.Errorf("process %d has exited", target.Pid)
+	}
+	if createTime, err := p.CreateTimeWithContext(ctx); err != nil || createTime != target.CreateTime {
+		return fmt.Errorf("process %d has exited or its PID was reused", target.Pid)
+	}
+	switch action {
+	case "terminate":
+		return p.TerminateWithContext(ctx)
+	case "kill":
+		return p.KillWithContext(ctx)
+	case "suspend":
+		return p.SuspendWithContext(ctx)
+	case "resume":
+		return p.ResumeWithContext(ctx)
+	default:
+		return setProcessPriority(target.Pid, priority)
+	}
+}
@@ -0,0 +1,241 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"winds-assistant/common"
+	"winds-assistant/utils"
+)
+
+// 一条序列在查询窗口内的统计结果
+type MetricStats struct {
+	Name         string
+	Labels       map[string]string
+	Unit         string
+	Count        int
+	Min          float64
+	Max          float64
+	Mean         float64
+	P50          float64
+	P95          float64
+	StdDev       float64
+	Slope        float64       // 线性回归斜率，单位/小时
+	Threshold    float64       // NaN 表示该指标没有阈值
+	AboveTime    time.Duration // 超过阈值的累计时长
+	ChangePoints []ChangePoint
+}
+
+// 均值发生明显跳变的位置
+type ChangePoint struct {
+	Time   int64
+	Before float64 // 跳变前一段的均值
+	After  float64 // 跳变后一段的均值
+}
+
+const (
+	changeMinSegment = 5   // 变化点两侧至少需要的采样数
+	changeMinScore   = 5.0 // 均值差相对标准误差的最小倍数
+	changeMaxPoints  = 3
+)
+
+// 按值排序后取第 q 分位（最近秩）
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// 计算一条序列的统计量，threshold 为 NaN 时不统计超阈值时长
+func ComputeStats(points []utils.TSPoint, threshold float64) MetricStats {
+	st := MetricStats{Count: len(points), Threshold: threshold}
+	if len(points) == 0 {
+		return st
+	}
+
+	values := make([]float64, len(points))
+	sum := 0.0
+	for i, p := range points {
+		values[i] = p.Value
+		sum += p.Value
+	}
+	st.Mean = sum / float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - st.Mean) * (v - st.Mean)
+	}
+	st.StdDev = math.Sqrt(variance / float64(len(values)))
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	st.Min, st.Max = sorted[0], sorted[len(sorted)-1]
+	st.P50, st.P95 = quantile(sorted, 0.5), quantile(sorted, 0.95)
+
+	st.Slope = linearSlope(points)
+	if !math.IsNaN(threshold) {
+		st.AboveTime = aboveDuration(points, threshold)
+	}
+	st.ChangePoints = findChangePoints(points, changeMaxPoints)
+	return st
+}
+
+// 最小二乘拟合的斜率（单位/小时）
+func linearSlope(points []utils.TSPoint) float64 {
+	if len(points) < 2 {
+		return 0
+	}
+	n := float64(len(points))
+	t0 := points[0].Time
+	var sx, sy, sxx, sxy float64
+	for _, p := range points {
+		x := float64(p.Time-t0) / 3600
+		sx += x
+		sy += p.Value
+		sxx += x * x
+		sxy += x * p.Value
+	}
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return 0
+	}
+	return (n*sxy - sx*sy) / den
+}
+
+// 超过阈值的累计时长：相邻两次采样间前一次超过阈值时计入该间隔
+// 间隔超过采样周期中位数 3 倍时视为监控中断，只计一个周期
+func aboveDuration(points []utils.TSPoint, threshold float64) time.Duration {
+	if len(points) < 2 {
+		return 0
+	}
+	gaps := make([]int64, 0, len(points)-1)
+	for i := 1; i < len(points); i++ {
+		gaps = append(gaps, points[i].Time-points[i-1].Time)
+	}
+	sorted := append([]int64(nil), gaps...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	typical := sorted[len(sorted)/2]
+
+	var total int64
+	for i, gap := range gaps {
+		if points[i].Value <= threshold {
+			continue
+		}
+		if gap > 3*typical {
+			gap = typical
+		}
+		total += gap
+	}
+	return time.Duration(total) * time.Second
+}
+
+// 二分切割法查找均值跳变：每次在均值差最显著的位置切开，直到不再显著或达到数量上限
+func findChangePoints(points []utils.TSPoint, limit int) []ChangePoint {
+	type segment struct{ lo, hi int }
+	var result []ChangePoint
+	queue := []segment{{0, len(points)}}
+	for len(queue) > 0 && len(result) < limit {
+		seg := queue[0]
+		queue = queue[1:]
+		at, before, after, ok := bestSplit(points[seg.lo:seg.hi])
+		if !ok {
+			continue
+		}
+		result = append(result, ChangePoint{Time: points[seg.lo+at].Time, Before: before, After: after})
+		queue = append(queue, segment{seg.lo, seg.lo + at}, segment{seg.lo + at, seg.hi})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Time < result[j].Time })
+	return result
+}
+
+func bestSplit(points []utils.TSPoint) (at int, before, after float64, ok bool) {
+	n := len(points)
+	if n < 2*changeMinSegment {
+		return 0, 0, 0, false
+	}
+	prefix := make([]float64, n+1)
+	for i, p := range points {
+		prefix[i+1] = prefix[i] + p.Value
+	}
+	bestScore := 0.0
+	for i := changeMinSegment; i <= n-changeMinSegment; i++ {
+		left := prefix[i] / float64(i)
+		right := (prefix[n] - prefix[i]) / float64(n-i)
+		score := math.Abs(left-right) * math.Sqrt(float64(i*(n-i))/float64(n))
+		if score > bestScore {
+			bestScore, at, before, after = score, i, left, right
+		}
+	}
+	if at == 0 {
+		return 0, 0, 0, false
+	}
+
+	// 两段各自的离散程度作为噪声水平，避免把正常波动当作跳变
+	noise := 0.0
+	for i, p := range points {
+		mean := after
+		if i < at {
+			mean = before
+		}
+		noise += (p.Value - mean) * (p.Value - mean)
+	}
+	noise = math.Sqrt(noise / float64(n))
+	// 完全平稳的两段（噪声为 0）只要均值不同即为跳变
+	if noise == 0 {
+		return at, before, after, before != after
+	}
+	return at, before, after, bestScore/noise >= changeMinScore
+}
+
+// 汇总数据的统计：最小/最大取各桶的极值，P95 取各桶 P95 的 P95（近似值），其余按各桶均值计算
+func ComputeRollupStats(points []utils.RollupPoint, threshold float64) MetricStats {
+	avg := make([]utils.TSPoint, len(points))
+	var p95s []float64
+	for i, p := range points {
+		avg[i] = utils.TSPoint{Time: p.Time, Value: p.Avg}
+		p95s = append(p95s, p.P95)
+	}
+	st := ComputeStats(avg, threshold)
+	if len(points) == 0 {
+		return st
+	}
+	st.Min, st.Max = points[0].Min, points[0].Max
+	for _, p := range points {
+		st.Min = math.Min(st.Min, p.Min)
+		st.Max = math.Max(st.Max, p.Max)
+	}
+	sort.Float64s(p95s)
+	st.P95 = quantile(p95s, 0.95)
+	return st
+}
+
+// 统计表的表头
+const statsHeader = "指标 | 标签 | 单位 | 样本数 | 最小 | 最大 | 均值 | P50 | P95 | 标准差 | 斜率(/小时) | 超阈值时长 | 变化点\n"
+
+// 将统计结果格式化为一行
+func FormatStats(st MetricStats) string {
+	labels := common.FormatLabels(st.Labels)
+	if labels == "" {
+		labels = "-"
+	}
+	above := "-"
+	if !math.IsNaN(st.Threshold) {
+		above = fmt.Sprintf("%v(>%g)", st.AboveTime, st.Threshold)
+	}
+	changes := "-"
+	if len(st.ChangePoints) > 0 {
+		var parts []string
+		for _, c := range st.ChangePoints {
+			parts = append(parts, fmt.Sprintf("%s %.2f→%.2f", time.Unix(c.Time, 0).Format(time.DateTime), c.Before, c.After))
+		}
+		changes = strings.Join(parts, "; ")
+	}
+	return fmt.Sprintf("%s | %s | %s | %d | %.2f | %.2f | %.2f | %.2f | %.2f | %.2f | %.2f | %s | %s\n",
+		st.Name, labels, st.Unit, st.Count, st.Min, st.Max, st.Mean, st.P50, st.P95, st.StdDev, st.Slope, above, changes)
+}
@@ -0,0 +1,28 @@
+//go:build !windows
+
+package tools
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// 优先级对应的 nice 值，提高优先级（负值）通常需要 root 权限
+var processNiceValues = map[string]int{
+	"idle":         19,
+	"below_normal": 10,
+	"normal":       0,
+	"above_normal": -5,
+	"high":         -10,
+}
+
+func setProcessPriority(pid int32, priority string) error {
+	nice, ok := processNiceValues[priority]
+	if !ok {
+		return fmt.Errorf("unknown priority %q", priority)
+	}
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, int(pid), nice); err != nil {
+		return fmt.Errorf("set priority of process %d: %w", pid, err)
+	}
+	return nil
+}
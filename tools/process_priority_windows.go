@@ -0,0 +1,37 @@
+//go:build windows
+
+package tools
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// Windows 进程优先级类
+var processPriorityClasses = map[string]uint32{
+	"idle":         0x00000040, // IDLE_PRIORITY_CLASS
+	"below_normal": 0x00004000, // BELOW_NORMAL_PRIORITY_CLASS
+	"normal":       0x00000020, // NORMAL_PRIORITY_CLASS
+	"above_normal": 0x00008000, // ABOVE_NORMAL_PRIORITY_CLASS
+	"high":         0x00000080, // HIGH_PRIORITY_CLASS
+}
+
+const processSetInformation = 0x0200 // PROCESS_SET_INFORMATION
+
+var procSetPriorityClass = syscall.NewLazyDLL("kernel32.dll").NewProc("SetPriorityClass")
+
+func setProcessPriority(pid int32, priority string) error {
+	class, ok := processPriorityClasses[priority]
+	if !ok {
+		return fmt.Errorf("unknown priority %q", priority)
+	}
+	handle, err := syscall.OpenProcess(processSetInformation, false, uint32(pid))
+	if err != nil {
+		return fmt.Errorf("open process %d: %w", pid, err)
+	}
+	defer syscall.CloseHandle(handle)
+	if r, _, err := procSetPriorityClass.Call(uintptr(handle), uintptr(class)); r == 0 {
+		return fmt.Errorf("set priority of process %d: %w", pid, err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"winds-assistant/utils"
+)
+
+// 工具配置需要实现的接口，Missing 返回缺失的必填字段（yaml 键名）
+type ToolCfg interface {
+	Missing() []string
+}
+
+// ** 注册工具配置，键为工具名，与 config/agent_tools.yaml 中的小节名一致
+var ToolCfgRegister = map[string]func() ToolCfg{
+	"get_bili_rcmd":  func() ToolCfg { return &BiliToolCfg{} },
+	"get_zhihu_rcmd": func() ToolCfg { return &ZhihuToolCfg{} },
+	"get_log_file":   func() ToolCfg { return &LogFileToolCfg{} },
+	"get_evtx_file":  func() ToolCfg { return &EvtxFileToolCfg{} },
+}
+
+var toolCfgStore = utils.NewYamlSectionStore(utils.TOOL_CONFIG_FILE)
+
+// 读取指定工具的配置到 cfg 中，并校验必填字段
+func LoadToolCfg(name string, cfg ToolCfg) error {
+	if err := toolCfgStore.Decode(name, cfg); err != nil {
+		return err
+	}
+	if missing := cfg.Missing(); len(missing) > 0 {
+		return fmt.Errorf("工具 <%s> 缺少配置项: %s (见 %s)", name, strings.Join(missing, ", "), utils.TOOL_CONFIG_FILE)
+	}
+	return nil
+}
+
+// 返回指定工具缺失的必填字段，未注册配置的工具返回空
+func CheckToolCfg(name string) ([]string, error) {
+	newCfg, ok := ToolCfgRegister[name]
+	if !ok {
+		return nil, nil
+	}
+	cfg := newCfg()
+	if err := toolCfgStore.Decode(name, cfg); err != nil {
+		return nil, err
+	}
+	return cfg.Missing(), nil
+}
@@ -18,6 +18,12 @@ func StartAPP() {
     wApp := app.New()
     window := wApp.NewWindow(common.WIDGET_APP_NAME)
     window.Resize(fyne.NewSize(1024, 768))
+    workers.SetAlertNotifier(func(title, content string) {
+        wApp.SendNotification(fyne.NewNotification(title, content))
+    })
+    workers.SetProcessActionConfirmer(func(req workers.ProcessActionRequest) bool {
+        return confirmProcessAction(window, req)
+    })
 
     cfg, _ := utils.LoadLLMCfg()
     fastCliboard, _ := utils.ReadTxtFile("config/fast_cliboard.txt")
@@ -64,6 +70,7 @@ func StartAPP() {
             return
         }
         UpdateHistory(history, common.LLMMessage{Role: "user", Content: text})
+        settings.Messages = append(settings.Messages, common.LLMMessage{Role: "user", Content: text})
         widgets.InputEntry.SetText("")
 
         widgets.ChatChunk.Process(fmt.Sprintf("%s%s\n", common.CHAT_USER_INFO, text))
@@ -85,6 +92,23 @@ func StartAPP() {
 
     // **APP Start**
     window.SetContent(widgets.MainSplit)
+
+    // 凭据库已存在但未解锁时，启动后先解锁并重新展开配置中的引用
+    if utils.SecretsLocked() && utils.SecretVaultExists() {
+        withSecretsUnlocked(window, func() {
+            newCfg, err := utils.LoadLLMCfg()
+            if err != nil {
+                common.ShowErrorDialog(window, err)
+                return
+            }
+            *cfg = *newCfg
+            model := settings.BackendCfg.Model
+            settings.BackendCfg = cfg.Backend[settings.BackendName]
+            if model != "" {
+                settings.BackendCfg.Model = model
+            }
+        })
+    }
     window.ShowAndRun()
 }
 
@@ -5,22 +5,31 @@ import (
 	"context"
 	"fmt"
 	"winds-assistant/common"
+	"winds-assistant/utils"
 	"winds-assistant/workers"
 	"time"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"container/list"
+	"os"
 )
 
 const (
 	// 用于维护历史记录的链表（维护最近的 HISTORY_LIST_LENGTH 个记录）
 	HISTORY_LIST_LENGTH = 5
+	// 会话保存目录
+	DIALOG_DIR = "data/dialogs/"
 )
 
 // 处理来自 StreamMessage 通道的消息，并在聊天窗口中更新显示内容
 func ProcessStream(ctx context.Context, settings *common.Settings, widgets common.Widgets, history *list.List) {
 	settings.Running = true
 	var contentBuffer bytes.Buffer
+	sysPrompt := settings.SysPrompt
+	if settings.EnableAgent {
+		sysPrompt += workers.AgentTimePrompt()
+	}
 
     err := workers.ChatReqStream(
 		ctx,
@@ -36,11 +45,17 @@ func ProcessStream(ctx context.Context, settings *common.Settings, widgets commo
             // 累积内容分块
             contentBuffer.WriteString(content)
             if done {
-				useTool, midOutput := workers.AgentParser(contentBuffer.String())
+				useTool, midOutput, calls := workers.AgentParser(contentBuffer.String())
 				// 如果 useTool 为 True，则 midOutput 为使用工具搜索后的结果
 				if useTool{
 					// widgets.ChatChunk.Process(common.CHAT_AGENT_MID + midOutput)
         			// widgets.ChatDisplay.SetText(widgets.ChatChunk.RenderNextText())
+					// 只在聊天窗口显示调用摘要，完整输出见工具调用记录
+					for _, c := range calls {
+						widgets.ChatChunk.Process(fmt.Sprintf(common.CHAT_TOOL_CALL_INFO,
+							c.Name, c.Status, c.EndTime.Sub(c.StartTime).Round(time.Millisecond), formatBytes(c.Bytes)))
+					}
+					settings.ToolCalls = append(settings.ToolCalls, calls...)
 					UpdateHistory(history, common.LLMMessage{Role: "midresult", Content: midOutput})
 				// 否则，midOutput 为直接返回的 Assistant 的回答
 				}else{
@@ -48,7 +63,9 @@ func ProcessStream(ctx context.Context, settings *common.Settings, widgets commo
         			widgets.ChatDisplay.SetText(widgets.ChatChunk.RenderNextText())
 					
 					UpdateHistory(history, common.LLMMessage{Role: "assistant", Content: contentBuffer.String()})
+					settings.Messages = append(settings.Messages, common.LLMMessage{Role: "assistant", Content: contentBuffer.String()})
 				}
+				SaveDialog(settings)
 				contentBuffer.Reset() 
 				settings.Running = false
             }
@@ -56,7 +73,7 @@ func ProcessStream(ctx context.Context, settings *common.Settings, widgets commo
 			widgets.ChatScroll.ScrollToBottom()
         },
         // 输入对话历史
-        GenerateHistoryMessage(history, settings.SysPrompt),
+        GenerateHistoryMessage(history, sysPrompt),
 	)
 
 	if err != nil {
@@ -128,4 +145,41 @@ func GenerateHistoryMessage(history *list.List, systemPrompt string) map[string]
 	return map[string]interface{}{
 		"messages": historyMessage,
 	}
-}
\ No newline at end of file
+}
+// 将当前会话（完整对话与工具调用记录）保存到 data/dialogs/<会话 ID>.json
+func SaveDialog(settings *common.Settings) {
+	if len(settings.Messages) == 0 && len(settings.ToolCalls) == 0 {
+		return
+	}
+	if err := utils.EnsureDir(DIALOG_DIR); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	data, err := json.MarshalIndent(common.DialogRecord{
+		DialogID:  settings.DialogID,
+		Backend:   settings.BackendName,
+		Model:     settings.BackendCfg.Model,
+		Messages:  settings.Messages,
+		ToolCalls: settings.ToolCalls,
+	}, "", "  ")
+	if err != nil {
+		fmt.Printf("error marshaling dialog: %v\n", err)
+		return
+	}
+	if err := os.WriteFile(DIALOG_DIR+settings.DialogID+".json", data, 0644); err != nil {
+		fmt.Printf("error writing dialog: %v\n", err)
+	}
+}
+
+// 将字节数格式化为便于阅读的大小
+func formatBytes(n int) string {
+	switch {
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
@@ -2,8 +2,10 @@ package ui
 
 import (
 	"container/list"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 	"winds-assistant/common"
 	"winds-assistant/utils"
 	"winds-assistant/workers"
@@ -65,10 +67,22 @@ func MainWidgets(window fyne.Window, history *list.List, settings *common.Settin
 
     // 聊天窗口布局
     chatBottomSplit := container.NewVSplit(
-        widget.NewButton(common.WIDGET_SKIP_TO_BOTTOM, func() {
-            chatDisplay.SetText(chatChunk.RenderFinalText())
-            chatScroll.ScrollToBottom()
-        }),
+        container.NewVBox(
+            widget.NewButton(common.WIDGET_SKIP_TO_BOTTOM, func() {
+                chatDisplay.SetText(chatChunk.RenderFinalText())
+                chatScroll.ScrollToBottom()
+            }),
+            // 附加本地文件（如导出的 .evtx），把路径写入输入框供 Agent 使用
+            widget.NewButton(common.WIDGET_ATTACH, func() {
+                dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+                    if err != nil || reader == nil {
+                        return
+                    }
+                    defer reader.Close()
+                    inputEntry.SetText(inputEntry.Text + fmt.Sprintf(common.CHAT_ATTACHMENT, reader.URI().Path()))
+                }, window)
+            }),
+        ),
         widget.NewButton(common.WIDGET_SEND, func() {
             if inputEntry.Text != "" {
                 inputEntry.OnSubmitted(inputEntry.Text)
@@ -95,6 +109,8 @@ func MainWidgets(window fyne.Window, history *list.List, settings *common.Settin
             history.Init()
 
             settings.DialogID = GenerateID()
+            settings.Messages = nil
+            settings.ToolCalls = nil
             updateSidebarInfo(modelTitle, settings)
 
             chatChunk.ClearChunks()
@@ -125,20 +141,18 @@ func MainWidgets(window fyne.Window, history *list.List, settings *common.Settin
         widget.NewButton(common.WIDGET_AGENT_SETTING, func() {
             showAgentSetting(window, settings)
         }),
+        widget.NewButton(common.WIDGET_TOOL_ACTIVITY, func() {
+            showToolActivity(settings)
+        }),
+        widget.NewButton(common.WIDGET_ALERT_HISTORY, func() {
+            showAlertHistory(window)
+        }),
         widget.NewButton(common.WIDGET_AGENT_SWITCH, func() {
             if settings.EnableAgent{
                 settings.SysPrompt = workers.SYSTEM_PROMPT_DEFAULT
                 settings.EnableAgent = false
             }else{
-                var agentPrompt string
-                for _, content := range workers.ToolsPromptRegister {
-                    if v, ok := content.(map[string]interface{}); ok {
-                        if v["enable"].(bool) {
-                            agentPrompt += fmt.Sprintf("%s\n", v["prompt"])
-                        }
-                    }
-                }
-                settings.SysPrompt = workers.SYSTEM_PROMPT_WITH_TOOLS_BASE + agentPrompt
+                settings.SysPrompt = workers.BuildAgentSysPrompt()
                 settings.EnableAgent = true
             }
             updateSidebarInfo(modelTitle, settings)
@@ -165,6 +179,11 @@ func MainWidgets(window fyne.Window, history *list.List, settings *common.Settin
         widget.NewButton(common.WIDGET_FASTCLIBOARD, func() {
             showFastCliboard(window, settings)
         }),
+        widget.NewButton(common.WIDGET_SECRET_SETTING, func() {
+            withSecretsUnlocked(window, func() {
+                showSecretSetting(window)
+            })
+        }),
         widget.NewButton(common.WIDGET_BACKEND_SETTING, func() {
             if settings.Running{
                 common.ShowErrorDialog(window, fmt.Errorf("info: assistant is running, terminate it first"))
@@ -195,8 +214,9 @@ func showSettingsDialog(parent fyne.Window, modelTitle *widget.Label, settings *
     url := widget.NewEntry()
     url.SetText(settings.BackendCfg.BaseURL)
 
-    apikey := widget.NewEntry()
-    apikey.SetText(settings.BackendCfg.APIKey)
+    // 不回显已保存的密钥，留空表示保持不变
+    apikey := widget.NewPasswordEntry()
+    apikey.SetPlaceHolder(utils.MaskSecret(settings.BackendCfg.APIKey))
 
     modelSelect := widget.NewSelect([]string{common.WIDGET_LOADING}, func(s string) {})
     modelSelect.SetOptions(settings.ModelList)
@@ -227,7 +247,9 @@ func showSettingsDialog(parent fyne.Window, modelTitle *widget.Label, settings *
             if save {
                 // 获取选中的值
                 settings.BackendCfg.BaseURL = url.Text
-                settings.BackendCfg.APIKey = apikey.Text
+                if apikey.Text != "" {
+                    settings.BackendCfg.APIKey = apikey.Text
+                }
                 if settings.BackendName == "ollama" {
                     settings.BackendCfg.Model = modelSelect.Selected
                     model.SetText(settings.BackendCfg.Model)
@@ -237,10 +259,14 @@ func showSettingsDialog(parent fyne.Window, modelTitle *widget.Label, settings *
                 }
                 updateSidebarInfo(modelTitle, settings)
 
-                // 保存配置到文件中
+                // 保存配置到文件中（密钥写入凭据存储）
                 cfg.Backend[settings.BackendName] = settings.BackendCfg
                 cfg.Default = settings.BackendName
-                utils.SaveLLMCfg(cfg)
+                withSecretsUnlocked(parent, func() {
+                    if err := utils.SaveLLMCfg(cfg); err != nil {
+                        common.ShowErrorDialog(parent, err)
+                    }
+                })
             }
         }, parent)
 }
@@ -303,7 +329,11 @@ func showBackendSettingDialog(parent fyne.Window, modelTitle *widget.Label, sett
 
                 // 保存配置文件
                 cfg.Default = choice
-                utils.SaveLLMCfg(cfg)
+                withSecretsUnlocked(parent, func() {
+                    if err := utils.SaveLLMCfg(cfg); err != nil {
+                        common.ShowErrorDialog(parent, err)
+                    }
+                })
                 // 刷新
                 updateSidebarInfo(modelTitle, settings)
             }
@@ -353,20 +383,234 @@ func showAgentSetting(parent fyne.Window, settings *common.Settings) {
         
         itemContainer := container.NewHBox(nameLabel, layout.NewSpacer(), controlButton, statusLabel)
         controls = append(controls, itemContainer)
+
+        // 提示缺失的工具配置项
+        missing, err := workers.ToolCfgMissing(name)
+        if err != nil {
+            controls = append(controls, widget.NewLabel(fmt.Sprintf(common.WIDGET_TOOL_CFG_ERROR, err)))
+        }else if len(missing) > 0 {
+            controls = append(controls, widget.NewLabel(fmt.Sprintf(common.WIDGET_TOOL_CFG_MISSING, strings.Join(missing, ", "))))
+        }
     }
     vbox := container.NewVBox(controls...)
     dialog.ShowCustomConfirm(common.WIDGET_AGENT_SETTING, "Reload", "Confirm", vbox, func(save bool) {
         if settings.EnableAgent{
-            var agentPrompt string
-            for _, content := range workers.ToolsPromptRegister {
-                if v, ok := content.(map[string]interface{}); ok {
-                    if v["enable"].(bool) {
-                        agentPrompt += fmt.Sprintf("%s\n", v["prompt"])
+            settings.SysPrompt = workers.BuildAgentSysPrompt()
+        }
+    },parent)
+}
+    
+// 凭据库未解锁时先要求输入口令，解锁后执行 next
+func withSecretsUnlocked(parent fyne.Window, next func()) {
+    if !utils.SecretsLocked() {
+        next()
+        return
+    }
+
+    title := common.WIDGET_SECRET_UNLOCK
+    if !utils.SecretVaultExists() {
+        title = common.WIDGET_SECRET_CREATE
+    }
+    passEntry := widget.NewPasswordEntry()
+    form := widget.NewForm(widget.NewFormItem(common.WIDGET_FORM_PASSPHRASE, passEntry))
+    dialog.ShowCustomConfirm(title, common.WIDGET_DIALOG_CONFIRM, common.WIDGET_DIALOG_CANCEL,
+        form, func(ok bool) {
+            if !ok {
+                return
+            }
+            if err := utils.UnlockSecrets(passEntry.Text); err != nil {
+                common.ShowErrorDialog(parent, err)
+                return
+            }
+            next()
+        }, parent)
+}
+
+// 凭据管理：只显示遮盖后的凭据，新值通过密码框写入
+func showSecretSetting(parent fyne.Window) {
+    store := utils.Secrets()
+
+    // 已保存的凭据列表，删除后重新读取
+    rows := container.NewVBox()
+    var refresh func() error
+    refresh = func() error {
+        names, err := store.List()
+        if err != nil {
+            return err
+        }
+        rows.RemoveAll()
+        for _, name := range names {
+            name := name
+            value, _ := store.Get(name)
+            rows.Add(container.NewHBox(
+                widget.NewLabel(name),
+                layout.NewSpacer(),
+                widget.NewLabel(utils.MaskSecret(value)),
+                widget.NewButton(common.WIDGET_DELETE, func() {
+                    err := store.Delete(name)
+                    if err == nil {
+                        err = refresh()
                     }
-                }
+                    if err != nil {
+                        common.ShowErrorDialog(parent, err)
+                    }
+                }),
+            ))
+        }
+        return nil
+    }
+    if err := refresh(); err != nil {
+        common.ShowErrorDialog(parent, err)
+        return
+    }
+
+    var controls []fyne.CanvasObject
+    controls = append(controls, widget.NewLabel(fmt.Sprintf(common.WIDGET_SECRET_PROVIDER, store.Name())), rows)
+
+    nameEntry := widget.NewEntry()
+    nameEntry.SetPlaceHolder("bili_cookie")
+    valueEntry := widget.NewPasswordEntry()
+    controls = append(controls, widget.NewForm(
+        widget.NewFormItem(common.WIDGET_FORM_SECRET_NAME, nameEntry),
+        widget.NewFormItem(common.WIDGET_FORM_SECRET_VALUE, valueEntry),
+    ))
+    controls = append(controls, widget.NewLabel(common.WIDGET_SECRET_HINT))
+
+    dialog.ShowCustomConfirm(common.WIDGET_SECRET_SETTING, common.WIDGET_DIALOG_SAVE, common.WIDGET_DIALOG_CANCEL,
+        container.NewVBox(controls...), func(save bool) {
+            if !save || nameEntry.Text == "" || valueEntry.Text == "" {
+                return
             }
-            settings.SysPrompt = workers.SYSTEM_PROMPT_WITH_TOOLS_BASE + agentPrompt
+            if err := store.Set(strings.TrimSpace(nameEntry.Text), valueEntry.Text); err != nil {
+                common.ShowErrorDialog(parent, err)
+            }
+        }, parent)
+}
+
+// 工具调用记录面板：每次调用可展开查看参数、起止时间、大小、状态和原始输出
+func showToolActivity(settings *common.Settings) {
+    activityWindow := fyne.CurrentApp().NewWindow(common.WIDGET_TOOL_ACTIVITY + " - " + settings.DialogID)
+    activityWindow.Resize(fyne.NewSize(720, 640))
+
+    calls := append([]common.ToolCallRecord(nil), settings.ToolCalls...)
+    if len(calls) == 0 {
+        activityWindow.SetContent(widget.NewLabel(common.WIDGET_TOOL_ACTIVITY_EMPTY))
+        activityWindow.Show()
+        return
+    }
+
+    accordion := widget.NewAccordion()
+    for _, c := range calls {
+        c := c
+        args, _ := json.MarshalIndent(c.Args, "", "  ")
+        info := widget.NewLabel(fmt.Sprintf("参数: %s\n开始: %s\n结束: %s\n耗时: %v\n大小: %s\n状态: %s",
+            args,
+            c.StartTime.Format("2006-01-02 15:04:05.000"),
+            c.EndTime.Format("2006-01-02 15:04:05.000"),
+            c.EndTime.Sub(c.StartTime).Round(time.Millisecond),
+            formatBytes(c.Bytes),
+            c.Status,
+        ))
+        info.Wrapping = fyne.TextWrapWord
+
+        outputEntry := widget.NewMultiLineEntry()
+        outputEntry.Wrapping = fyne.TextWrapWord
+        outputEntry.SetText(c.Output)
+        outputEntry.SetMinRowsVisible(12)
+
+        copyButton := widget.NewButton(common.WIDGET_COPY, func() {
+            activityWindow.Clipboard().SetContent(c.Output)
+        })
+
+        title := fmt.Sprintf("[%s] <%s> %s  %s", c.StartTime.Format("15:04:05"), c.Name, c.Status, formatBytes(c.Bytes))
+        accordion.Append(widget.NewAccordionItem(title, container.NewVBox(info, copyButton, outputEntry)))
+    }
+
+    activityWindow.SetContent(container.NewVScroll(accordion))
+    activityWindow.Show()
+}
+
+// 告警记录面板：最近的告警触发与恢复事件，触发事件可展开查看自动诊断结果
+func showAlertHistory(parent fyne.Window) {
+    events, err := utils.LoadAlertHistory(200)
+    if err != nil {
+        common.ShowErrorDialog(parent, err)
+        return
+    }
+    historyWindow := fyne.CurrentApp().NewWindow(common.WIDGET_ALERT_HISTORY)
+    historyWindow.Resize(fyne.NewSize(720, 640))
+    if len(events) == 0 {
+        historyWindow.SetContent(widget.NewLabel(common.WIDGET_ALERT_HISTORY_EMPTY))
+        historyWindow.Show()
+        return
+    }
+
+    accordion := widget.NewAccordion()
+    for _, e := range events {
+        series := e.Name
+        if labels := common.FormatLabels(e.Labels); labels != "" {
+            series += "{" + labels + "}"
         }
-    },parent)
+        state := common.ALERT_FIRING_TITLE
+        if e.State == "resolved" {
+            state = common.ALERT_RESOLVED_TITLE
+        }
+        text := fmt.Sprintf("规则: %s\n序列: %s\n值: %.2f %s\n条件开始满足: %s\n时间: %s",
+            e.Expr, series, e.Value, e.Unit,
+            time.Unix(e.Since, 0).Format(time.DateTime),
+            time.Unix(e.Time, 0).Format(time.DateTime),
+        )
+        if e.Reason != "" {
+            text += "\n恢复原因: " + e.Reason
+        }
+        info := widget.NewLabel(text)
+        info.Wrapping = fyne.TextWrapWord
+        content := container.NewVBox(info)
+        if e.Diagnosis != "" {
+            diagnosis := widget.NewLabel(common.ALERT_DIAGNOSIS_TITLE + e.Diagnosis)
+            diagnosis.Wrapping = fyne.TextWrapWord
+            content.Add(diagnosis)
+        }
+
+        title := fmt.Sprintf("[%s] %s%s", time.Unix(e.Time, 0).Format("01-02 15:04:05"), state, e.Rule)
+        accordion.Append(widget.NewAccordionItem(title, content))
+    }
+
+    historyWindow.SetContent(container.NewVScroll(accordion))
+    historyWindow.Show()
+}
+
+// 显示进程操作的确认对话框并等待用户选择（在工具协程中调用）
+func confirmProcessAction(parent fyne.Window, req workers.ProcessActionRequest) bool {
+    action := common.PROCESS_ACTION_MAP[req.Action]
+    if req.Priority != "" {
+        action += ": " + req.Priority
+    }
+    valueLabel := func(s string) *widget.Label {
+        if s == "" {
+            s = "-"
+        }
+        l := widget.NewLabel(s)
+        l.Wrapping = fyne.TextWrapBreak
+        return l
+    }
+    t := req.Target
+    form := widget.NewForm(
+        widget.NewFormItem("操作", valueLabel(action)),
+        widget.NewFormItem("PID", valueLabel(fmt.Sprint(t.Pid))),
+        widget.NewFormItem("进程", valueLabel(t.Name)),
+        widget.NewFormItem("执行路径", valueLabel(t.Exe)),
+        widget.NewFormItem("命令行", valueLabel(t.Cmdline)),
+        widget.NewFormItem("用户", valueLabel(t.User)),
+        widget.NewFormItem("启动时间", valueLabel(time.UnixMilli(t.CreateTime).Format(time.DateTime))),
+        widget.NewFormItem("理由", valueLabel(req.Reason)),
+    )
+    content := container.NewVBox(widget.NewLabel(common.WIDGET_PROCESS_ACTION_HINT), form)
+
+    result := make(chan bool, 1)
+    confirm := dialog.NewCustomConfirm(common.WIDGET_PROCESS_ACTION, common.WIDGET_DIALOG_CONFIRM, common.WIDGET_DIALOG_CANCEL,
+        content, func(ok bool) { result <- ok }, parent)
+    confirm.Resize(fyne.NewSize(560, 420))
+    confirm.Show()
+    return <-result
 }
-    
\ No newline at end of file
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"winds-assistant/common"
+)
+
+// 告警历史保存在数据目录下的 alerts.jsonl，每行一个事件，只追加
+// 诊断结果作为单独的事件写入，读取时合并到对应的触发事件上
+const alertHistoryFile = "alerts.jsonl"
+
+var alertHistoryMu sync.Mutex
+
+func alertHistoryPath() string {
+	return filepath.Join(LoadMonitorCfg().DataDir, alertHistoryFile)
+}
+
+// 追加一个告警事件
+func AppendAlertEvent(event common.AlertEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	alertHistoryMu.Lock()
+	defer alertHistoryMu.Unlock()
+
+	path := alertHistoryPath()
+	if err := EnsureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// 读取最近 limit 个触发和恢复事件（从新到旧），诊断结果合并到对应的触发事件中
+func LoadAlertHistory(limit int) ([]common.AlertEvent, error) {
+	alertHistoryMu.Lock()
+	defer alertHistoryMu.Unlock()
+
+	file, err := os.Open(alertHistoryPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []common.AlertEvent
+	diagnoses := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var e common.AlertEvent
+		if json.Unmarshal(scanner.Bytes(), &e) != nil {
+			continue // 写了一半的行
+		}
+		if e.State == "diagnosis" {
+			diagnoses[e.ID] = e.Diagnosis
+			continue
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var result []common.AlertEvent
+	for i := len(events) - 1; i >= 0 && (limit <= 0 || len(result) < limit); i-- {
+		e := events[i]
+		if e.State == "firing" {
+			e.Diagnosis = diagnoses[e.ID]
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// 删除 before 之前的告警事件：保留的事件写入临时文件后替换原文件，无法解析的行一并丢弃
+func DeleteAlertEventsBefore(before time.Time) error {
+	alertHistoryMu.Lock()
+	defer alertHistoryMu.Unlock()
+	return deleteAlertEventsBefore(alertHistoryPath(), before)
+}
+
+func deleteAlertEventsBefore(path string, before time.Time) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var kept []byte
+	removed := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var e common.AlertEvent
+		if json.Unmarshal(scanner.Bytes(), &e) != nil || e.Time < before.Unix() {
+			removed++
+			continue
+		}
+		kept = append(append(kept, scanner.Bytes()...), '\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if removed == 0 {
+		return nil
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, kept, 0644); err != nil {
+		return err
+	}
+	file.Close() // Windows 上替换前需要先关闭
+	return os.Rename(tmp, path)
+}
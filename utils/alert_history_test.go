@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeleteAlertEventsBefore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), alertHistoryFile)
+	lines := []string{
+		`{"id":"a","time":1000,"state":"firing"}`,
+		`{"id":"a","time":1010,"state":"diagnosis","diagnosis":"x"}`,
+		`{"id":"a","time":2000,"state":"resolved"}`,
+		`{"id":"b","time":3000,"state":"firing"}`,
+		`{"id":"b","time":30`, // 写了一半的行
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := deleteAlertEventsBefore(path, time.Unix(2000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := lines[2] + "\n" + lines[3] + "\n"; string(data) != want {
+		t.Errorf("alerts.jsonl =\n%s\nwant\n%s", data, want)
+	}
+
+	// 没有过期事件时不改写文件
+	info, _ := os.Stat(path)
+	if err := deleteAlertEventsBefore(path, time.Unix(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := os.Stat(path); !after.ModTime().Equal(info.ModTime()) || after.Size() != info.Size() {
+		t.Error("file rewritten without expired events")
+	}
+
+	if err := deleteAlertEventsBefore(filepath.Join(t.TempDir(), "missing.jsonl"), time.Now()); err != nil {
+		t.Errorf("missing history: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"winds-assistant/common"
+)
+
+// ** 异常记录 **
+// 数据目录下的 anomalies/ 中每天一个 YYYYMMDD.jsonl 文件，每行一条异常，只追加
+const (
+	anomalyDirName = "anomalies"
+	anomalyExt     = ".jsonl"
+)
+
+type AnomalyStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func OpenAnomalyStore(dir string) (*AnomalyStore, error) {
+	if err := EnsureDir(dir); err != nil {
+		return nil, err
+	}
+	return &AnomalyStore{dir: dir}, nil
+}
+
+// 按日期追加写入异常
+func (s *AnomalyStore) Append(anomalies []common.Anomaly) error {
+	if len(anomalies) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lines := map[string][]byte{}
+	for _, a := range anomalies {
+		line, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		day := tsDay(a.Time)
+		lines[day] = append(append(lines[day], line...), '\n')
+	}
+	for day, data := range lines {
+		file, err := os.OpenFile(filepath.Join(s.dir, day+anomalyExt), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		_, err = file.Write(data)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 查询 [start, end] 内的异常，按时间排序；无法解析的行（如写了一半）跳过
+func (s *AnomalyStore) Query(start, end time.Time) ([]common.Anomaly, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []common.Anomaly
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local)
+	for day := startDay; !day.After(end); day = day.AddDate(0, 0, 1) {
+		file, err := os.Open(filepath.Join(s.dir, day.Format(tsDayFormat)+anomalyExt))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var a common.Anomaly
+			if json.Unmarshal(scanner.Bytes(), &a) != nil {
+				continue
+			}
+			if a.Time >= start.Unix() && a.Time <= end.Unix() {
+				result = append(result, a)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Time < result[j].Time })
+	return result, nil
+}
+
+// 删除 before 所在日期之前的异常记录
+func (s *AnomalyStore) DeleteBefore(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	cutoff := before.Local().Format(tsDayFormat)
+	for _, e := range entries {
+		day, ok := strings.CutSuffix(e.Name(), anomalyExt)
+		if !ok || len(day) != len(tsDayFormat) || day >= cutoff {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	anomalyStoresMu sync.Mutex
+	anomalyStores   = map[string]*AnomalyStore{} // 数据目录 -> 异常记录
+)
+
+// 当前配置的数据目录中的异常记录（监控守护进程写入，工具查询）
+func DefaultAnomalyStore() (*AnomalyStore, error) {
+	dir := LoadMonitorCfg().DataDir
+	anomalyStoresMu.Lock()
+	defer anomalyStoresMu.Unlock()
+	if s, ok := anomalyStores[dir]; ok {
+		return s, nil
+	}
+	s, err := OpenAnomalyStore(filepath.Join(dir, anomalyDirName))
+	if err != nil {
+		return nil, err
+	}
+	anomalyStores[dir] = s
+	return s, nil
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"log"
+	"time"
+	"winds-assistant/common"
+)
+
+const MONITOR_CONFIG_FILE = "config/monitor_settings.yaml"
+
+// 配置中没有指定时记录的文件系统类型，排除 squashfs、tmpfs、overlay 等虚拟文件系统
+var DefaultDiskFsTypes = []string{
+	"ntfs", "refs", "fat32", "vfat", "exfat",
+	"ext2", "ext3", "ext4", "xfs", "btrfs", "zfs", "f2fs", "apfs", "hfs",
+}
+
+// 默认的指标阈值，统计超过阈值的累计时长
+var DefaultMetricThresholds = map[string]float64{
+	"cpu_percent":          80,
+	"mem_used_percent":     90,
+	"disk_used_percent":    90,
+	"gpu_util":             90,
+	"gpu_mem_used_percent": 90,
+	"gpu_temp":             80,
+}
+
+var monitorCfgStore = NewYamlSectionStore(MONITOR_CONFIG_FILE)
+
+// 读取系统监控配置（修改后自动生效），缺省项使用默认值
+func LoadMonitorCfg() common.MonitorConfig {
+	var config common.MonitorConfig
+	if err := monitorCfgStore.Decode("monitor", &config); err != nil {
+		log.Printf("load monitor config failed: %v", err)
+	}
+	if config.DataDir == "" {
+		config.DataDir = TSDB_DIR
+	}
+	if config.StoreInterval <= 0 {
+		config.StoreInterval = 10 * time.Second
+	}
+	if len(config.Disk.FsTypes) == 0 {
+		config.Disk.FsTypes = DefaultDiskFsTypes
+	}
+	if config.Process.TopN <= 0 {
+		config.Process.TopN = 5
+	}
+	if config.Exporter.Listen == "" {
+		config.Exporter.Listen = "127.0.0.1:9464"
+	}
+	if config.Retention.Raw <= 0 {
+		config.Retention.Raw = 3 * 24 * time.Hour
+	}
+	if config.Retention.Minute <= 0 {
+		config.Retention.Minute = 30 * 24 * time.Hour
+	}
+	if config.Retention.Hour <= 0 {
+		config.Retention.Hour = 365 * 24 * time.Hour
+	}
+	if config.Retention.Anomalies <= 0 {
+		config.Retention.Anomalies = 30 * 24 * time.Hour
+	}
+	if config.Retention.Alerts <= 0 {
+		config.Retention.Alerts = 90 * 24 * time.Hour
+	}
+	return config
+}
+
+// 采集器是否启用
+func CollectorEnabled(config common.MonitorConfig, name string) bool {
+	c, ok := config.Collectors[name]
+	return !ok || c.Enabled == nil || *c.Enabled
+}
+
+// 采集器的周期：单独配置 > 默认配置 > 采集器内置周期
+func CollectorInterval(config common.MonitorConfig, name string, builtin time.Duration) time.Duration {
+	if c, ok := config.Collectors[name]; ok && c.Interval > 0 {
+		return c.Interval
+	}
+	if config.Interval > 0 {
+		return config.Interval
+	}
+	return builtin
+}
+
+// 指标是否需要记录
+func MetricEnabled(config common.MonitorConfig, name string) bool {
+	if len(config.Metrics) == 0 {
+		return true
+	}
+	for _, m := range config.Metrics {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// 指标的阈值：配置 > 默认值，没有阈值时返回 false
+func MetricThreshold(config common.MonitorConfig, name string) (float64, bool) {
+	if v, ok := config.Thresholds[name]; ok {
+		return v, true
+	}
+	v, ok := DefaultMetricThresholds[name]
+	return v, ok
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"winds-assistant/common"
+)
+
+// 进程操作的审计日志，每行一条记录，只追加
+const PROCESS_AUDIT_FILE = "data/process_audit.jsonl"
+
+var processAuditMu sync.Mutex
+
+// 追加一条进程操作记录
+func AppendProcessAudit(record common.ProcessAuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	processAuditMu.Lock()
+	defer processAuditMu.Unlock()
+
+	if err := EnsureDir(filepath.Dir(PROCESS_AUDIT_FILE)); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(PROCESS_AUDIT_FILE, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
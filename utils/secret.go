@@ -0,0 +1,370 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	SECRET_VAULT_FILE = "config/secrets.vault"
+	SECRET_INDEX_FILE = "config/secrets.index"
+	// 可通过环境变量提供凭据库口令，免去启动时手动解锁
+	SECRET_PASSPHRASE_ENV = "WINDS_VAULT_PASSPHRASE"
+
+	vaultKDFIter = 600000
+	vaultKeySize = 32
+)
+
+var (
+	ErrVaultLocked     = errors.New("credential vault is locked")
+	ErrSecretNotFound  = errors.New("secret not found")
+	ErrWrongPassphrase = errors.New("wrong vault passphrase")
+)
+
+// 凭据存储接口
+type SecretStore interface {
+	Name() string
+	Get(name string) (string, error)
+	Set(name string, value string) error
+	Delete(name string) error
+	List() ([]string, error)
+}
+
+// ** 加密凭据库 **
+// 文件中只保存盐、随机数和密文，密钥由口令通过 PBKDF2-SHA256 派生，数据使用 AES-256-GCM 加密
+type vaultFile struct {
+	Version int    `json:"version"`
+	Iter    int    `json:"iter"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+type FileVault struct {
+	path    string
+	salt    []byte
+	key     []byte
+	secrets map[string]string
+	mu      sync.Mutex
+}
+
+func NewFileVault(path string) *FileVault {
+	return &FileVault{path: path}
+}
+
+func (v *FileVault) Name() string {
+	return "vault"
+}
+
+// 凭据库是否尚未解锁
+func (v *FileVault) Locked() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.key == nil
+}
+
+// 使用口令解锁凭据库；凭据库不存在时以该口令创建新库
+func (v *FileVault) Unlock(passphrase string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	data, err := os.ReadFile(v.path)
+	if os.IsNotExist(err) {
+		v.salt = make([]byte, 16)
+		if _, err := rand.Read(v.salt); err != nil {
+			return err
+		}
+		if v.key, err = pbkdf2.Key(sha256.New, passphrase, v.salt, vaultKDFIter, vaultKeySize); err != nil {
+			return err
+		}
+		v.secrets = map[string]string{}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read vault failed: %w", err)
+	}
+
+	var vf vaultFile
+	if err := json.Unmarshal(data, &vf); err != nil {
+		return fmt.Errorf("extract vault failed: %w", err)
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, vf.Salt, vf.Iter, vaultKeySize)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	plain, err := gcm.Open(nil, vf.Nonce, vf.Data, nil)
+	if err != nil {
+		return ErrWrongPassphrase
+	}
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return fmt.Errorf("extract vault failed: %w", err)
+	}
+
+	v.salt, v.key, v.secrets = vf.Salt, key, secrets
+	return nil
+}
+
+// 加密并写回凭据库文件（调用方需持有锁）
+func (v *FileVault) save() error {
+	plain, err := json.Marshal(v.secrets)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(v.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data, err := json.Marshal(vaultFile{
+		Version: 1,
+		Iter:    vaultKDFIter,
+		Salt:    v.salt,
+		Nonce:   nonce,
+		Data:    gcm.Seal(nil, nonce, plain, nil),
+	})
+	if err != nil {
+		return err
+	}
+
+	// 先写临时文件再替换，避免中途退出损坏凭据库
+	tmp := v.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, v.path)
+}
+
+func (v *FileVault) Get(name string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return "", ErrVaultLocked
+	}
+	value, ok := v.secrets[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	return value, nil
+}
+
+func (v *FileVault) Set(name string, value string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return ErrVaultLocked
+	}
+	v.secrets[name] = value
+	return v.save()
+}
+
+func (v *FileVault) Delete(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return ErrVaultLocked
+	}
+	delete(v.secrets, name)
+	return v.save()
+}
+
+func (v *FileVault) List() ([]string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return nil, ErrVaultLocked
+	}
+	var names []string
+	for name := range v.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ** 系统钥匙串 **
+// 具体实现见 secret_keyring_*.go，名称列表单独保存在索引文件中（不含凭据内容）
+type KeyringStore struct {
+	index string
+	mu    sync.Mutex
+}
+
+func NewKeyringStore(index string) *KeyringStore {
+	return &KeyringStore{index: index}
+}
+
+func (k *KeyringStore) Name() string {
+	return "keyring"
+}
+
+func (k *KeyringStore) Get(name string) (string, error) {
+	return keyringGet(name)
+}
+
+func (k *KeyringStore) Set(name string, value string) error {
+	if err := keyringSet(name, value); err != nil {
+		return err
+	}
+	return k.updateIndex(name, true)
+}
+
+func (k *KeyringStore) Delete(name string) error {
+	if err := keyringDelete(name); err != nil {
+		return err
+	}
+	return k.updateIndex(name, false)
+}
+
+func (k *KeyringStore) List() ([]string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.readIndex(), nil
+}
+
+func (k *KeyringStore) readIndex() (names []string) {
+	data, err := os.ReadFile(k.index)
+	if err != nil {
+		return
+	}
+	for _, name := range strings.Split(string(data), "\n") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+func (k *KeyringStore) updateIndex(name string, add bool) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	set := map[string]bool{}
+	for _, n := range k.readIndex() {
+		set[n] = true
+	}
+	if add {
+		set[name] = true
+	} else {
+		delete(set, name)
+	}
+	var names []string
+	for n := range set {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return os.WriteFile(k.index, []byte(strings.Join(names, "\n")), 0600)
+}
+
+// ** 凭据存储选择 **
+var (
+	secretVault             = NewFileVault(SECRET_VAULT_FILE)
+	secretStore SecretStore = secretVault
+)
+
+// 根据配置选择凭据存储（"keyring" 或 "vault"），系统钥匙串不可用时回退到加密凭据库
+func UseSecretProvider(provider string) {
+	if provider == "keyring" && KeyringAvailable() {
+		secretStore = NewKeyringStore(SECRET_INDEX_FILE)
+		return
+	}
+	secretStore = secretVault
+	if secretVault.Locked() {
+		if passphrase := os.Getenv(SECRET_PASSPHRASE_ENV); passphrase != "" {
+			if err := secretVault.Unlock(passphrase); err != nil {
+				fmt.Printf("unlock vault failed: %v\n", err)
+			}
+		}
+	}
+}
+
+// 当前使用的凭据存储
+func Secrets() SecretStore {
+	return secretStore
+}
+
+// 当前凭据存储是否需要输入口令解锁
+func SecretsLocked() bool {
+	return secretStore == SecretStore(secretVault) && secretVault.Locked()
+}
+
+// 加密凭据库文件是否已存在（不存在时首次解锁即为创建）
+func SecretVaultExists() bool {
+	_, err := os.Stat(SECRET_VAULT_FILE)
+	return err == nil
+}
+
+// 使用口令解锁加密凭据库
+func UnlockSecrets(passphrase string) error {
+	return secretVault.Unlock(passphrase)
+}
+
+// ** 配置引用 **
+// 配置文件中的字符串可以写成 ${secret:name} 或 ${env:NAME}，读取时替换为真实值
+var secretRefPattern = regexp.MustCompile(`\$\{(secret|env):([^}]+)\}`)
+
+// 是否包含凭据引用
+func IsSecretRef(s string) bool {
+	return secretRefPattern.MatchString(s)
+}
+
+// 生成凭据引用
+func SecretRef(name string) string {
+	return fmt.Sprintf("${secret:%s}", name)
+}
+
+// 展开字符串中的所有引用
+func ResolveSecretRefs(s string) (string, error) {
+	var firstErr error
+	out := secretRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		m := secretRefPattern.FindStringSubmatch(ref)
+		kind, name := m[1], strings.TrimSpace(m[2])
+		if kind == "env" {
+			return os.Getenv(name)
+		}
+		value, err := secretStore.Get(name)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("resolve %s failed: %w", ref, err)
+		}
+		return value
+	})
+	return out, firstErr
+}
+
+// 遮盖凭据，只保留首尾少量字符用于辨认
+func MaskSecret(s string) string {
+	r := []rune(s)
+	switch {
+	case len(r) == 0:
+		return ""
+	case len(r) <= 8:
+		return strings.Repeat("*", len(r))
+	default:
+		return string(r[:3]) + strings.Repeat("*", 6) + string(r[len(r)-4:])
+	}
+}
@@ -0,0 +1,41 @@
+//go:build !windows
+
+package utils
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// 非 Windows 平台通过 libsecret 的 secret-tool 访问系统钥匙串
+const keyringService = "winds-assistant"
+
+func KeyringAvailable() bool {
+	_, err := exec.LookPath("secret-tool")
+	return err == nil
+}
+
+func keyringGet(name string) (string, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", keyringService, "name", name).Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s (%v)", ErrSecretNotFound, name, err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func keyringSet(name string, value string) error {
+	cmd := exec.Command("secret-tool", "store", "--label="+keyringService+"/"+name, "service", keyringService, "name", name)
+	cmd.Stdin = strings.NewReader(value)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("store secret failed: %v %s", err, out)
+	}
+	return nil
+}
+
+func keyringDelete(name string) error {
+	if out, err := exec.Command("secret-tool", "clear", "service", keyringService, "name", name).CombinedOutput(); err != nil {
+		return fmt.Errorf("clear secret failed: %v %s", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+//go:build windows
+
+package utils
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// Windows 凭据管理器（Credential Manager）
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	keyringPrefix           = "winds-assistant/"
+)
+
+type winCredential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+var (
+	advapi32        = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+func KeyringAvailable() bool {
+	return procCredReadW.Find() == nil
+}
+
+func keyringGet(name string) (string, error) {
+	target, err := syscall.UTF16PtrFromString(keyringPrefix + name)
+	if err != nil {
+		return "", err
+	}
+	var cred *winCredential
+	r, _, callErr := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if r == 0 {
+		return "", fmt.Errorf("%w: %s (%v)", ErrSecretNotFound, name, callErr)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+func keyringSet(name string, value string) error {
+	target, err := syscall.UTF16PtrFromString(keyringPrefix + name)
+	if err != nil {
+		return err
+	}
+	blob := []byte(value)
+	cred := winCredential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+	r, _, callErr := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if r == 0 {
+		return fmt.Errorf("write credential failed: %v", callErr)
+	}
+	return nil
+}
+
+func keyringDelete(name string) error {
+	target, err := syscall.UTF16PtrFromString(keyringPrefix + name)
+	if err != nil {
+		return err
+	}
+	r, _, callErr := procCredDeleteW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	if r == 0 {
+		return fmt.Errorf("delete credential failed: %v", callErr)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const TOOL_CONFIG_FILE = "config/agent_tools.yaml"
+
+// 按小节读取的 YAML 配置文件（每个小节对应一个工具）
+// 文件只在修改时间变化时重新解析，实现热加载
+type YamlSectionStore struct {
+	path     string
+	modTime  time.Time
+	sections map[string]yaml.Node
+	mu       sync.Mutex
+}
+
+// 创建一个 YamlSectionStore，文件在第一次读取时加载
+func NewYamlSectionStore(path string) *YamlSectionStore {
+	return &YamlSectionStore{path: path}
+}
+
+// 检查文件是否被修改，如有修改则重新解析全部小节
+func (s *YamlSectionStore) reload() error {
+	info, err := os.Stat(filepath.Clean(s.path))
+	if os.IsNotExist(err) {
+		s.sections = map[string]yaml.Node{}
+		s.modTime = time.Time{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.sections != nil && info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(filepath.Clean(s.path))
+	if err != nil {
+		return fmt.Errorf("read %s failed: %w", s.path, err)
+	}
+	sections := map[string]yaml.Node{}
+	if err := yaml.Unmarshal(data, &sections); err != nil {
+		return fmt.Errorf("extract %s failed: %w", s.path, err)
+	}
+	s.sections = sections
+	s.modTime = info.ModTime()
+	return nil
+}
+
+// 将名为 name 的小节解析到 out 中；小节不存在时 out 保持零值
+func (s *YamlSectionStore) Decode(name string, out interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return err
+	}
+	node, ok := s.sections[name]
+	if !ok {
+		return nil
+	}
+	resolved, err := resolveNode(&node)
+	if err != nil {
+		return fmt.Errorf("section <%s>: %w", name, err)
+	}
+	if err := resolved.Decode(out); err != nil {
+		return fmt.Errorf("extract section <%s> failed: %w", name, err)
+	}
+	return nil
+}
+
+// 复制节点并展开其中字符串的 ${secret:name} / ${env:NAME} 引用，原节点保持不变
+func resolveNode(n *yaml.Node) (*yaml.Node, error) {
+	out := *n
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" {
+		value, err := ResolveSecretRefs(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		out.Value = value
+		return &out, nil
+	}
+	out.Content = make([]*yaml.Node, len(n.Content))
+	for i, c := range n.Content {
+		rc, err := resolveNode(c)
+		if err != nil {
+			return nil, err
+		}
+		out.Content[i] = rc
+	}
+	return &out, nil
+}
@@ -0,0 +1,573 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"winds-assistant/common"
+)
+
+// ** 嵌入式时序存储 **
+// 目录结构：
+//   series.jsonl      序列表，每行一个序列（名称 + 标签），只追加
+//   20250301.seg      当天（及迟到）的采样，按写入顺序追加
+//   20250301.cseg     压缩后的采样，按时间排序并去重，可二分查找
+// 每条采样固定 24 字节：序列 ID(4) + 时间戳(8) + 值(8) + CRC32(4)，
+// 写入中途崩溃只会留下不完整或校验失败的尾部记录，打开和读取时会被丢弃
+const (
+	TSDB_DIR = "data/tsdb/"
+
+	tsRecordSize  = 24
+	tsDayFormat   = "20060102"
+	tsHeadExt     = ".seg"
+	tsCompactExt  = ".cseg"
+	tsSeriesFile  = "series.jsonl"
+	tsReadBufSize = 64 * 1024
+)
+
+// 序列元数据，名称 + 标签唯一确定一条序列
+type SeriesMeta struct {
+	ID     uint32            `json:"id"`
+	Name   string            `json:"name"`
+	Unit   string            `json:"unit"`
+	Source string            `json:"source"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type TSPoint struct {
+	Time  int64
+	Value float64
+}
+
+// 查询结果中的一条序列
+type TSSeries struct {
+	SeriesMeta
+	Points []TSPoint
+}
+
+type TSStore struct {
+	dir        string
+	byKey      map[string]*SeriesMeta
+	byID       map[uint32]*SeriesMeta
+	nextID     uint32
+	seriesFile *os.File
+	heads      map[string]*os.File // 日期 -> 打开的 .seg 文件
+	mu         sync.RWMutex
+}
+
+func seriesKey(name string, labels map[string]string) string {
+	return name + "{" + common.FormatLabels(labels) + "}"
+}
+
+// 打开（不存在时创建）时序存储目录
+func OpenTSStore(dir string) (*TSStore, error) {
+	if err := EnsureDir(dir); err != nil {
+		return nil, err
+	}
+	s := &TSStore{
+		dir:    dir,
+		byKey:  map[string]*SeriesMeta{},
+		byID:   map[uint32]*SeriesMeta{},
+		nextID: 1,
+		heads:  map[string]*os.File{},
+	}
+	if err := s.loadSeries(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// 读取序列表，截掉崩溃时写了一半的最后一行
+func (s *TSStore) loadSeries() error {
+	path := filepath.Join(s.dir, tsSeriesFile)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	valid := 0
+	for valid < len(data) {
+		end := bytes.IndexByte(data[valid:], '\n')
+		if end < 0 {
+			break
+		}
+		var meta SeriesMeta
+		if err := json.Unmarshal(data[valid:valid+end], &meta); err != nil {
+			break
+		}
+		s.addSeries(&meta)
+		valid += end + 1
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	if valid < len(data) {
+		if err := file.Truncate(int64(valid)); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		file.Close()
+		return err
+	}
+	s.seriesFile = file
+	return nil
+}
+
+func (s *TSStore) addSeries(meta *SeriesMeta) {
+	s.byKey[seriesKey(meta.Name, meta.Labels)] = meta
+	s.byID[meta.ID] = meta
+	if meta.ID >= s.nextID {
+		s.nextID = meta.ID + 1
+	}
+}
+
+// 获取或新建序列（调用方需持有写锁），新序列写入序列表
+func (s *TSStore) series(sample common.MetricSample) (*SeriesMeta, bool, error) {
+	key := seriesKey(sample.Name, sample.Labels)
+	if meta, ok := s.byKey[key]; ok {
+		return meta, false, nil
+	}
+	meta := &SeriesMeta{ID: s.nextID, Name: sample.Name, Unit: sample.Unit, Source: sample.Source, Labels: sample.Labels}
+	line, err := json.Marshal(meta)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err := s.seriesFile.Write(append(line, '\n')); err != nil {
+		return nil, false, err
+	}
+	s.addSeries(meta)
+	return meta, true, nil
+}
+
+func encodeTSRecord(buf []byte, id uint32, ts int64, value float64) {
+	binary.LittleEndian.PutUint32(buf[0:4], id)
+	binary.LittleEndian.PutUint64(buf[4:12], uint64(ts))
+	binary.LittleEndian.PutUint64(buf[12:20], math.Float64bits(value))
+	binary.LittleEndian.PutUint32(buf[20:24], crc32.ChecksumIEEE(buf[:20]))
+}
+
+func decodeTSRecord(buf []byte) (id uint32, ts int64, value float64, ok bool) {
+	if crc32.ChecksumIEEE(buf[:20]) != binary.LittleEndian.Uint32(buf[20:24]) {
+		return 0, 0, 0, false
+	}
+	id = binary.LittleEndian.Uint32(buf[0:4])
+	ts = int64(binary.LittleEndian.Uint64(buf[4:12]))
+	value = math.Float64frombits(binary.LittleEndian.Uint64(buf[12:20]))
+	return id, ts, value, true
+}
+
+func tsDay(ts int64) string {
+	return time.Unix(ts, 0).Local().Format(tsDayFormat)
+}
+
+// 打开某天的 .seg 文件用于追加，截掉不完整的尾部记录（调用方需持有写锁）
+func (s *TSStore) head(day string) (*os.File, error) {
+	if f, ok := s.heads[day]; ok {
+		return f, nil
+	}
+	f, err := os.OpenFile(filepath.Join(s.dir, day+tsHeadExt), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err == nil && info.Size()%tsRecordSize != 0 {
+		err = f.Truncate(info.Size() - info.Size()%tsRecordSize)
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekEnd)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.heads[day] = f
+	return f, nil
+}
+
+// 写入一批采样，返回前已落盘
+func (s *TSStore) Append(samples []common.MetricSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	newSeries := false
+	buffers := map[string][]byte{}
+	record := make([]byte, tsRecordSize)
+	for _, sample := range samples {
+		meta, created, err := s.series(sample)
+		if err != nil {
+			return fmt.Errorf("write series failed: %w", err)
+		}
+		newSeries = newSeries || created
+		encodeTSRecord(record, meta.ID, sample.Time, sample.Value)
+		day := tsDay(sample.Time)
+		buffers[day] = append(buffers[day], record...)
+	}
+	if newSeries {
+		if err := s.seriesFile.Sync(); err != nil {
+			return err
+		}
+	}
+
+	for day, buf := range buffers {
+		f, err := s.head(day)
+		if err != nil {
+			return fmt.Errorf("open segment %s failed: %w", day, err)
+		}
+		if _, err := f.Write(buf); err != nil {
+			return fmt.Errorf("write segment %s failed: %w", day, err)
+		}
+		if err := f.Sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 所有指标名称（去重并排序）
+func (s *TSStore) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	set := map[string]bool{}
+	for _, meta := range s.byID {
+		set[meta.Name] = true
+	}
+	var names []string
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 名称相同且包含 match 中全部标签的序列
+func (s *TSStore) matchSeries(name string, match map[string]string) map[uint32]*SeriesMeta {
+	ids := map[uint32]*SeriesMeta{}
+	for id, meta := range s.byID {
+		if meta.Name != name {
+			continue
+		}
+		ok := true
+		for k, v := range match {
+			if meta.Labels[k] != v {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			ids[id] = meta
+		}
+	}
+	return ids
+}
+
+// 查询 [start, end] 内指定指标的采样，可跨越多天
+// match 为需要匹配的标签，为空表示该指标的全部序列
+func (s *TSStore) Query(name string, match map[string]string, start, end time.Time) ([]TSSeries, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.query(s.matchSeries(name, match), start, end)
+}
+
+// 一次扫描查询 [start, end] 内全部序列的采样
+func (s *TSStore) QueryAll(start, end time.Time) ([]TSSeries, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.query(s.byID, start, end)
+}
+
+func (s *TSStore) query(ids map[uint32]*SeriesMeta, start, end time.Time) ([]TSSeries, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	points := map[uint32][]TSPoint{}
+	collect := func(id uint32, ts int64, value float64) {
+		if _, ok := ids[id]; ok && ts >= start.Unix() && ts <= end.Unix() {
+			points[id] = append(points[id], TSPoint{ts, value})
+		}
+	}
+
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local)
+	for day := startDay; !day.After(end); day = day.AddDate(0, 0, 1) {
+		name := day.Format(tsDayFormat)
+		if err := scanCompactSegment(filepath.Join(s.dir, name+tsCompactExt), start.Unix(), end.Unix(), collect); err != nil {
+			return nil, err
+		}
+		if err := scanSegment(filepath.Join(s.dir, name+tsHeadExt), collect); err != nil {
+			return nil, err
+		}
+	}
+
+	var result []TSSeries
+	for id, pts := range points {
+		sort.SliceStable(pts, func(i, j int) bool { return pts[i].Time < pts[j].Time })
+		result = append(result, TSSeries{SeriesMeta: *ids[id], Points: dedupePoints(pts)})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return seriesKey(result[i].Name, result[i].Labels) < seriesKey(result[j].Name, result[j].Labels)
+	})
+	return result, nil
+}
+
+// 同一时间戳保留最后写入的值
+func dedupePoints(pts []TSPoint) []TSPoint {
+	out := pts[:0]
+	for _, p := range pts {
+		if len(out) > 0 && out[len(out)-1].Time == p.Time {
+			out[len(out)-1] = p
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// 顺序扫描整个段文件
+func scanSegment(path string, fn func(id uint32, ts int64, value float64)) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return scanRecords(bufio.NewReaderSize(file, tsReadBufSize), fn, nil)
+}
+
+// 在按时间排序的段文件中二分查找 start 的位置，再顺序读到 end 为止
+func scanCompactSegment(path string, start, end int64, fn func(id uint32, ts int64, value float64)) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	n := int(info.Size() / tsRecordSize)
+	record := make([]byte, tsRecordSize)
+	var readErr error
+	first := sort.Search(n, func(i int) bool {
+		if _, err := file.ReadAt(record, int64(i)*tsRecordSize); err != nil {
+			readErr = err
+			return true
+		}
+		return int64(binary.LittleEndian.Uint64(record[4:12])) >= start
+	})
+	if readErr != nil {
+		return readErr
+	}
+
+	section := io.NewSectionReader(file, int64(first)*tsRecordSize, int64(n-first)*tsRecordSize)
+	return scanRecords(bufio.NewReaderSize(section, tsReadBufSize), fn, func(ts int64) bool { return ts > end })
+}
+
+// 逐条读取记录，跳过校验失败的记录；stop 返回 true 时停止
+func scanRecords(r io.Reader, fn func(id uint32, ts int64, value float64), stop func(ts int64) bool) error {
+	record := make([]byte, tsRecordSize)
+	for {
+		if _, err := io.ReadFull(r, record); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+		id, ts, value, ok := decodeTSRecord(record)
+		if !ok {
+			continue
+		}
+		if stop != nil && stop(ts) {
+			return nil
+		}
+		fn(id, ts, value)
+	}
+}
+
+// 段文件对应的日期，不是段文件时返回空
+func segmentDay(name string) string {
+	for _, ext := range []string{tsHeadExt, tsCompactExt} {
+		if day, ok := strings.CutSuffix(name, ext); ok && len(day) == len(tsDayFormat) {
+			return day
+		}
+	}
+	return ""
+}
+
+// 将 before 之前各天的 .seg 合并进 .cseg：按时间排序、去重，先写临时文件再替换
+func (s *TSStore) Compact(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	cutoff := before.Local().Format(tsDayFormat)
+	for _, e := range entries {
+		day, ok := strings.CutSuffix(e.Name(), tsHeadExt)
+		if !ok || segmentDay(e.Name()) == "" || day >= cutoff {
+			continue
+		}
+		if err := s.compactDay(day); err != nil {
+			return fmt.Errorf("compact %s failed: %w", day, err)
+		}
+	}
+	return nil
+}
+
+func (s *TSStore) compactDay(day string) error {
+	if f, ok := s.heads[day]; ok {
+		f.Close()
+		delete(s.heads, day)
+	}
+	headPath := filepath.Join(s.dir, day+tsHeadExt)
+	compactPath := filepath.Join(s.dir, day+tsCompactExt)
+
+	type rec struct {
+		id    uint32
+		ts    int64
+		value float64
+	}
+	var recs []rec
+	collect := func(id uint32, ts int64, value float64) { recs = append(recs, rec{id, ts, value}) }
+	if err := scanSegment(compactPath, collect); err != nil {
+		return err
+	}
+	if err := scanSegment(headPath, collect); err != nil {
+		return err
+	}
+	// 稳定排序保证同一序列同一时间戳时后写入的值在后面
+	sort.SliceStable(recs, func(i, j int) bool {
+		if recs[i].ts != recs[j].ts {
+			return recs[i].ts < recs[j].ts
+		}
+		return recs[i].id < recs[j].id
+	})
+
+	buf := make([]byte, 0, len(recs)*tsRecordSize)
+	record := make([]byte, tsRecordSize)
+	for i, r := range recs {
+		if i+1 < len(recs) && recs[i+1].ts == r.ts && recs[i+1].id == r.id {
+			continue
+		}
+		encodeTSRecord(record, r.id, r.ts, r.value)
+		buf = append(buf, record...)
+	}
+
+	tmp := compactPath + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(buf); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	file.Close()
+	if err := os.Rename(tmp, compactPath); err != nil {
+		return err
+	}
+	return os.Remove(headPath)
+}
+
+// 删除 before 所在日期之前的全部段文件
+func (s *TSStore) DeleteBefore(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	cutoff := before.Local().Format(tsDayFormat)
+	for _, e := range entries {
+		day := segmentDay(e.Name())
+		if day == "" || day >= cutoff {
+			continue
+		}
+		if f, ok := s.heads[day]; ok {
+			f.Close()
+			delete(s.heads, day)
+		}
+		if err := os.Remove(filepath.Join(s.dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TSStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for day, f := range s.heads {
+		f.Close()
+		delete(s.heads, day)
+	}
+	return s.seriesFile.Close()
+}
+
+var (
+	defaultStoresMu sync.Mutex
+	defaultStores   = map[string]*TSStore{}   // 数据目录 -> 存储
+	defaultRollups  = map[string][]*Rollup{} // 数据目录 -> 各精度汇总
+)
+
+// 当前配置的数据目录中的指标存储（监控守护进程写入，工具查询）
+// 进程内共享，配置中的目录修改后返回新目录的存储
+func DefaultTSStore() (*TSStore, error) {
+	dir := LoadMonitorCfg().DataDir
+	defaultStoresMu.Lock()
+	defer defaultStoresMu.Unlock()
+	if s, ok := defaultStores[dir]; ok {
+		return s, nil
+	}
+	s, err := OpenTSStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	defaultStores[dir] = s
+	return s, nil
+}
+
+// 关闭所有共享的存储和汇总，之后再获取时重新打开
+func CloseDefaultStores() {
+	defaultStoresMu.Lock()
+	defer defaultStoresMu.Unlock()
+	for dir, s := range defaultStores {
+		s.Close()
+		delete(defaultStores, dir)
+	}
+	for dir, rs := range defaultRollups {
+		for _, r := range rs {
+			r.Close()
+		}
+		delete(defaultRollups, dir)
+	}
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"winds-assistant/common"
+)
+
+// 导入完成的旧 CSV 文件移动到该子目录，避免重复导入
+const csvImportedDir = "csv_imported"
+
+const csvImportBatch = 4096
+
+// 旧版 CSV 只记录 C 盘和第一块显卡（NVIDIA），没有标签列
+// 导入时补上当前采集器对应的标签，使旧数据与新采集的数据属于同一序列
+var legacyCSVLabels = map[string]map[string]string{
+	"disk_used":      {"mount": "C:", "fstype": "NTFS"},
+	"gpu_util":       {"gpu": "0", "vendor": "nvidia"},
+	"gpu_mem_used":   {"gpu": "0", "vendor": "nvidia"},
+	"gpu_mem_clock":  {"gpu": "0", "vendor": "nvidia"},
+	"gpu_core_clock": {"gpu": "0", "vendor": "nvidia"},
+	"gpu_temp":       {"gpu": "0", "vendor": "nvidia"},
+	"gpu_power":      {"gpu": "0", "vendor": "nvidia"},
+}
+
+// 将旧版监控数据（dir 下的 <指标>_<日期>.csv）导入时序存储
+// 每个文件导入成功后移动到 dir/csv_imported/，返回导入的采样数
+func ImportMetricCSV(store *TSStore, dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.csv"))
+	if err != nil || len(files) == 0 {
+		return 0, err
+	}
+	if err := EnsureDir(filepath.Join(dir, csvImportedDir)); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, path := range files {
+		n, err := importMetricCSVFile(store, path)
+		if err != nil {
+			return total, fmt.Errorf("import %s failed: %w", path, err)
+		}
+		total += n
+		if err := os.Rename(path, filepath.Join(dir, csvImportedDir, filepath.Base(path))); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// 列：timestamp, time, name, value, unit, source[, labels]
+func importMetricCSVFile(store *TSStore, path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	var batch []common.MetricSample
+	total := 0
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return total, err
+		}
+		if len(row) < 6 {
+			continue
+		}
+		ts, err := strconv.ParseInt(row[0], 10, 64)
+		if err != nil { // 表头
+			continue
+		}
+		value, err := strconv.ParseFloat(row[3], 64)
+		if err != nil {
+			continue
+		}
+		sample := common.MetricSample{Time: ts, Name: row[2], Value: value, Unit: row[4], Source: row[5]}
+		if len(row) > 6 && row[6] != "" {
+			sample.Labels = common.ParseLabels(row[6])
+		} else if labels, ok := legacyCSVLabels[sample.Name]; ok {
+			sample.Labels = make(map[string]string, len(labels))
+			for k, v := range labels {
+				sample.Labels[k] = v
+			}
+		}
+		batch = append(batch, sample)
+		if len(batch) >= csvImportBatch {
+			if err := store.Append(batch); err != nil {
+				return total, err
+			}
+			total += len(batch)
+			batch = batch[:0]
+		}
+	}
+	if err := store.Append(batch); err != nil {
+		return total, err
+	}
+	return total + len(batch), nil
+}
@@ -0,0 +1,350 @@
+package utils
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"winds-assistant/common"
+)
+
+// ** 降采样汇总 **
+// 原始采样按固定步长（1 分钟、1 小时）分桶，每个桶写入 min/max/avg/p95 四条汇总序列，
+// 序列名称与原始指标相同，额外带 agg 标签，存放在数据目录下以步长命名的子目录中。
+// watermark 文件记录已写入的最后一个桶的结束时间，重启后从原始数据补算之后的桶
+const (
+	rollupAggLabel      = "agg"
+	rollupWatermarkFile = "watermark"
+)
+
+var rollupAggs = []string{"min", "max", "avg", "p95"}
+
+// 支持的汇总精度，按步长从小到大排列
+var RollupLevels = []struct {
+	Name string
+	Step time.Duration
+}{
+	{"1m", time.Minute},
+	{"1h", time.Hour},
+}
+
+type RollupPoint struct {
+	Time int64 // 桶的开始时间
+	Min  float64
+	Max  float64
+	Avg  float64
+	P95  float64
+}
+
+// 汇总查询结果中的一条序列（标签中不含 agg）
+type RollupSeries struct {
+	SeriesMeta
+	Points []RollupPoint
+}
+
+type rollupBucket struct {
+	sample common.MetricSample // 序列信息，Time 为桶的开始时间
+	values []float64
+}
+
+type Rollup struct {
+	Name      string
+	Step      time.Duration
+	store     *TSStore
+	watermark int64                    // 之前的桶已写入，迟到的采样直接丢弃
+	buckets   map[string]*rollupBucket // 序列 + 桶开始时间 -> 桶
+	mu        sync.Mutex
+}
+
+// 打开（不存在时创建）某个精度的汇总存储
+func OpenRollup(dir, name string, step time.Duration) (*Rollup, error) {
+	store, err := OpenTSStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &Rollup{Name: name, Step: step, store: store, buckets: map[string]*rollupBucket{}}
+	if data, err := os.ReadFile(filepath.Join(dir, rollupWatermarkFile)); err == nil {
+		r.watermark, _ = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	}
+	return r, nil
+}
+
+func (r *Rollup) Store() *TSStore {
+	return r.store
+}
+
+func (r *Rollup) bucketStart(ts int64) int64 {
+	step := int64(r.Step / time.Second)
+	return ts - ((ts%step)+step)%step
+}
+
+// 将原始采样累加到对应的桶中
+func (r *Rollup) Add(samples []common.MetricSample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, sample := range samples {
+		start := r.bucketStart(sample.Time)
+		if start < r.watermark {
+			continue
+		}
+		key := seriesKey(sample.Name, sample.Labels) + "@" + strconv.FormatInt(start, 10)
+		b, ok := r.buckets[key]
+		if !ok {
+			meta := sample
+			meta.Time = start
+			b = &rollupBucket{sample: meta}
+			r.buckets[key] = b
+		}
+		b.values = append(b.values, sample.Value)
+	}
+}
+
+// 写入结束时间不晚于 upTo 的全部桶，并推进 watermark
+func (r *Rollup) Flush(upTo time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	limit := r.bucketStart(upTo.Unix())
+	var samples []common.MetricSample
+	var done []string
+	for key, b := range r.buckets {
+		if b.sample.Time >= limit {
+			continue
+		}
+		samples = append(samples, aggregateBucket(b)...)
+		done = append(done, key)
+	}
+	if err := r.store.Append(samples); err != nil {
+		return err
+	}
+	for _, key := range done {
+		delete(r.buckets, key)
+	}
+	if limit > r.watermark {
+		r.watermark = limit
+		return os.WriteFile(filepath.Join(r.store.dir, rollupWatermarkFile), []byte(strconv.FormatInt(limit, 10)), 0644)
+	}
+	return nil
+}
+
+// 计算一个桶内采样的 min/max/avg/p95（会对 values 排序）
+func aggregateValues(start int64, values []float64) RollupPoint {
+	sort.Float64s(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	// p95 取最近秩：不小于 95% 采样的最小值
+	rank := int(math.Ceil(0.95*float64(len(values)))) - 1
+	return RollupPoint{Time: start, Min: values[0], Max: values[len(values)-1], Avg: sum / float64(len(values)), P95: values[rank]}
+}
+
+func aggregateBucket(b *rollupBucket) []common.MetricSample {
+	p := aggregateValues(b.sample.Time, b.values)
+	aggs := map[string]float64{"min": p.Min, "max": p.Max, "avg": p.Avg, "p95": p.P95}
+
+	samples := make([]common.MetricSample, 0, len(rollupAggs))
+	for _, agg := range rollupAggs {
+		s := b.sample
+		s.Value = aggs[agg]
+		s.Labels = map[string]string{rollupAggLabel: agg}
+		for k, v := range b.sample.Labels {
+			s.Labels[k] = v
+		}
+		samples = append(samples, s)
+	}
+	return samples
+}
+
+// 从原始数据补算 watermark（没有时为 since）到 until 之间的桶
+// 按天分段读取，避免一次性载入全部原始数据
+func (r *Rollup) Backfill(raw *TSStore, since, until time.Time) error {
+	r.mu.Lock()
+	from := time.Unix(r.watermark, 0)
+	r.mu.Unlock()
+	if from.Before(since) {
+		from = since
+	}
+	for from.Before(until) {
+		to := from.Add(24 * time.Hour)
+		if to.After(until) {
+			to = until
+		}
+		series, err := raw.QueryAll(from, to.Add(-time.Second))
+		if err != nil {
+			return err
+		}
+		for _, s := range series {
+			samples := make([]common.MetricSample, len(s.Points))
+			for i, p := range s.Points {
+				samples[i] = common.MetricSample{Time: p.Time, Name: s.Name, Value: p.Value, Unit: s.Unit, Source: s.Source, Labels: s.Labels}
+			}
+			r.Add(samples)
+		}
+		if err := r.Flush(to); err != nil {
+			return err
+		}
+		from = to
+	}
+	return nil
+}
+
+// 查询 [start, end] 内指定指标的汇总数据，参数含义同 TSStore.Query
+func (r *Rollup) Query(name string, match map[string]string, start, end time.Time) ([]RollupSeries, error) {
+	series, err := r.store.Query(name, match, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return groupRollupSeries(series), nil
+}
+
+// 同 Query，并用原始采样临时汇总 watermark 之后尚未写入的桶（包括当前未结束的桶），
+// 否则 1 小时汇总会缺少最近约一小时的数据，正在发生的问题在长时间范围的查询中看不到
+func (r *Rollup) QueryWithRecent(raw *TSStore, name string, match map[string]string, start, end time.Time) ([]RollupSeries, error) {
+	result, err := r.Query(name, match, start, end)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	from := time.Unix(r.watermark, 0)
+	r.mu.Unlock()
+	if from.Before(start) {
+		from = start
+	}
+	if from.After(end) {
+		return result, nil
+	}
+	recent, err := raw.Query(name, match, from, end)
+	if err != nil {
+		return nil, err
+	}
+
+	index := map[string]int{}
+	for i, rs := range result {
+		index[seriesKey(rs.Name, rs.Labels)] = i
+	}
+	for _, ts := range recent {
+		buckets := map[int64][]float64{}
+		for _, p := range ts.Points {
+			b := r.bucketStart(p.Time)
+			buckets[b] = append(buckets[b], p.Value)
+		}
+		if len(buckets) == 0 {
+			continue
+		}
+		key := seriesKey(ts.Name, ts.Labels)
+		i, ok := index[key]
+		if !ok {
+			i = len(result)
+			index[key] = i
+			result = append(result, RollupSeries{SeriesMeta: ts.SeriesMeta})
+		}
+		for b, values := range buckets {
+			result[i].Points = append(result[i].Points, aggregateValues(b, values))
+		}
+		sort.Slice(result[i].Points, func(a, b int) bool { return result[i].Points[a].Time < result[i].Points[b].Time })
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return seriesKey(result[i].Name, result[i].Labels) < seriesKey(result[j].Name, result[j].Labels)
+	})
+	return result, nil
+}
+
+// 一次扫描查询 [start, end] 内全部指标的汇总数据
+func (r *Rollup) QueryAll(start, end time.Time) ([]RollupSeries, error) {
+	series, err := r.store.QueryAll(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return groupRollupSeries(series), nil
+}
+
+// 将同一序列的 min/max/avg/p95 四条汇总序列合并为一条
+func groupRollupSeries(series []TSSeries) []RollupSeries {
+	byKey := map[string]*RollupSeries{}
+	var keys []string
+	for _, s := range series {
+		agg := s.Labels[rollupAggLabel]
+		labels := map[string]string{}
+		for k, v := range s.Labels {
+			if k != rollupAggLabel {
+				labels[k] = v
+			}
+		}
+		key := seriesKey(s.Name, labels)
+		rs, ok := byKey[key]
+		if !ok {
+			meta := s.SeriesMeta
+			meta.Labels = labels
+			rs = &RollupSeries{SeriesMeta: meta}
+			byKey[key] = rs
+			keys = append(keys, key)
+		}
+		rs.merge(agg, s.Points)
+	}
+
+	sort.Strings(keys)
+	result := make([]RollupSeries, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *byKey[key])
+	}
+	return result
+}
+
+// 合并某一种汇总值，各汇总序列的时间点相同
+func (rs *RollupSeries) merge(agg string, points []TSPoint) {
+	index := map[int64]int{}
+	for i, p := range rs.Points {
+		index[p.Time] = i
+	}
+	for _, p := range points {
+		i, ok := index[p.Time]
+		if !ok {
+			i = len(rs.Points)
+			index[p.Time] = i
+			rs.Points = append(rs.Points, RollupPoint{Time: p.Time})
+		}
+		switch agg {
+		case "min":
+			rs.Points[i].Min = p.Value
+		case "max":
+			rs.Points[i].Max = p.Value
+		case "avg":
+			rs.Points[i].Avg = p.Value
+		case "p95":
+			rs.Points[i].P95 = p.Value
+		}
+	}
+	sort.Slice(rs.Points, func(i, j int) bool { return rs.Points[i].Time < rs.Points[j].Time })
+}
+
+func (r *Rollup) Close() error {
+	return r.store.Close()
+}
+
+// 当前配置的数据目录中的各精度汇总存储，顺序同 RollupLevels
+func DefaultRollups() ([]*Rollup, error) {
+	dir := LoadMonitorCfg().DataDir
+	defaultStoresMu.Lock()
+	defer defaultStoresMu.Unlock()
+	if rs, ok := defaultRollups[dir]; ok {
+		return rs, nil
+	}
+	var rs []*Rollup
+	for _, level := range RollupLevels {
+		r, err := OpenRollup(filepath.Join(dir, level.Name), level.Name, level.Step)
+		if err != nil {
+			for _, opened := range rs {
+				opened.Close()
+			}
+			return nil, err
+		}
+		rs = append(rs, r)
+	}
+	defaultRollups[dir] = rs
+	return rs, nil
+}
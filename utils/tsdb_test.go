@@ -0,0 +1,258 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"winds-assistant/common"
+)
+
+var tsBase = time.Date(2025, 3, 1, 22, 0, 0, 0, time.Local)
+
+func openTestStore(t *testing.T, dir string) *TSStore {
+	t.Helper()
+	s, err := OpenTSStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// 相对 tsBase 偏移 offset 的采样
+func tsSample(name string, labels map[string]string, offset time.Duration, value float64) common.MetricSample {
+	return common.MetricSample{Time: tsBase.Add(offset).Unix(), Name: name, Value: value, Unit: "%", Source: "test", Labels: labels}
+}
+
+func appendSamples(t *testing.T, s *TSStore, samples ...common.MetricSample) {
+	t.Helper()
+	if err := s.Append(samples); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 查询单条序列，返回 偏移秒数 -> 值
+func queryPoints(t *testing.T, s *TSStore, name string, match map[string]string, start, end time.Time) map[int64]float64 {
+	t.Helper()
+	series, err := s.Query(name, match, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 1 {
+		t.Fatalf("Query(%s, %v) returned %d series, want 1", name, match, len(series))
+	}
+	got := map[int64]float64{}
+	for i, p := range series[0].Points {
+		if i > 0 && p.Time <= series[0].Points[i-1].Time {
+			t.Errorf("points not strictly ordered: %+v", series[0].Points)
+		}
+		got[p.Time-tsBase.Unix()] = p.Value
+	}
+	return got
+}
+
+func checkPoints(t *testing.T, got, want map[int64]float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("got %d points %v, want %v", len(got), got, want)
+		return
+	}
+	for ts, v := range want {
+		if g, ok := got[ts]; !ok || g != v {
+			t.Errorf("point at +%ds = %v (present %v), want %v", ts, g, ok, v)
+		}
+	}
+}
+
+func TestTSStoreTornTail(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir)
+	appendSamples(t, s, tsSample("cpu_percent", nil, 0, 1), tsSample("cpu_percent", nil, 10*time.Second, 2))
+	s.Close()
+
+	// 模拟写入中途崩溃：段文件尾部半条记录，序列表尾部半行
+	seg := filepath.Join(dir, tsDay(tsBase.Unix())+tsHeadExt)
+	appendFile(t, seg, make([]byte, tsRecordSize/2))
+	appendFile(t, filepath.Join(dir, tsSeriesFile), []byte(`{"id":2,"name":"mem_u`))
+
+	s = openTestStore(t, dir)
+	appendSamples(t, s, tsSample("cpu_percent", nil, 20*time.Second, 3), tsSample("mem_used", nil, 20*time.Second, 4))
+	if info, err := os.Stat(seg); err != nil || info.Size() != 4*tsRecordSize {
+		t.Fatalf("segment size = %v, %v, want %d", info.Size(), err, 4*tsRecordSize)
+	}
+	end := tsBase.Add(time.Minute)
+	checkPoints(t, queryPoints(t, s, "cpu_percent", nil, tsBase, end), map[int64]float64{0: 1, 10: 2, 20: 3})
+	checkPoints(t, queryPoints(t, s, "mem_used", nil, tsBase, end), map[int64]float64{20: 4})
+
+	// 重新打开后序列表完整
+	s.Close()
+	s = openTestStore(t, dir)
+	if names := s.Names(); len(names) != 2 || names[0] != "cpu_percent" || names[1] != "mem_used" {
+		t.Errorf("Names() = %v after reopen", names)
+	}
+}
+
+func TestTSStoreCorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir)
+	appendSamples(t, s,
+		tsSample("cpu_percent", nil, 0, 1),
+		tsSample("cpu_percent", nil, 10*time.Second, 2),
+		tsSample("cpu_percent", nil, 20*time.Second, 3),
+	)
+
+	// 改动第二条记录的值，CRC 校验失败后应被跳过
+	seg := filepath.Join(dir, tsDay(tsBase.Unix())+tsHeadExt)
+	data, err := os.ReadFile(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[tsRecordSize+15] ^= 0xff
+	if err := os.WriteFile(seg, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want := map[int64]float64{0: 1, 20: 3}
+	checkPoints(t, queryPoints(t, s, "cpu_percent", nil, tsBase, tsBase.Add(time.Minute)), want)
+
+	// 压缩时同样丢弃损坏的记录
+	if err := s.Compact(tsBase.AddDate(0, 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	checkPoints(t, queryPoints(t, s, "cpu_percent", nil, tsBase, tsBase.Add(time.Minute)), want)
+}
+
+func TestTSStoreCompact(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir)
+	gpu0, gpu1 := map[string]string{"gpu": "0"}, map[string]string{"gpu": "1"}
+	appendSamples(t, s,
+		tsSample("gpu_temp", gpu0, 30*time.Second, 3),
+		tsSample("gpu_temp", gpu0, 0, 1),
+		tsSample("gpu_temp", gpu1, 0, 10),
+		tsSample("gpu_temp", gpu0, 10*time.Second, 2),
+	)
+	if err := s.Compact(tsBase.AddDate(0, 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 压缩后同一天又写入了迟到和重复的采样，留下新的 .seg
+	appendSamples(t, s,
+		tsSample("gpu_temp", gpu0, 10*time.Second, 20), // 覆盖已压缩的值
+		tsSample("gpu_temp", gpu0, 20*time.Second, 2.5),
+		tsSample("gpu_temp", gpu1, 0, 11),
+		tsSample("gpu_temp", gpu1, 0, 12), // 同一批中后写入的值为准
+	)
+	end := tsBase.Add(time.Minute)
+	want0 := map[int64]float64{0: 1, 10: 20, 20: 2.5, 30: 3}
+	want1 := map[int64]float64{0: 12}
+	checkPoints(t, queryPoints(t, s, "gpu_temp", gpu0, tsBase, end), want0)
+	checkPoints(t, queryPoints(t, s, "gpu_temp", gpu1, tsBase, end), want1)
+
+	if err := s.Compact(tsBase.AddDate(0, 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	day := tsDay(tsBase.Unix())
+	if _, err := os.Stat(filepath.Join(dir, day+tsHeadExt)); !os.IsNotExist(err) {
+		t.Errorf(".seg still exists after compaction: %v", err)
+	}
+	// .cseg 按时间排序且没有重复记录
+	var prev int64
+	count := 0
+	if err := scanSegment(filepath.Join(dir, day+tsCompactExt), func(id uint32, ts int64, value float64) {
+		if ts < prev {
+			t.Errorf("record at %d after %d", ts, prev)
+		}
+		prev = ts
+		count++
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if count != len(want0)+len(want1) {
+		t.Errorf(".cseg has %d records, want %d", count, len(want0)+len(want1))
+	}
+	checkPoints(t, queryPoints(t, s, "gpu_temp", gpu0, tsBase, end), want0)
+	checkPoints(t, queryPoints(t, s, "gpu_temp", gpu1, tsBase, end), want1)
+	// 二分查找的范围查询
+	checkPoints(t, queryPoints(t, s, "gpu_temp", gpu0, tsBase.Add(10*time.Second), tsBase.Add(20*time.Second)), map[int64]float64{10: 20, 20: 2.5})
+
+	// 当天的 .seg 不压缩
+	today := time.Now()
+	appendSamples(t, s, common.MetricSample{Time: today.Unix(), Name: "gpu_temp", Labels: gpu0, Value: 1})
+	if err := s.Compact(today); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, tsDay(today.Unix())+tsHeadExt)); err != nil {
+		t.Errorf("today's segment compacted: %v", err)
+	}
+}
+
+func TestTSStoreQueryAcrossDays(t *testing.T) {
+	s := openTestStore(t, t.TempDir())
+	// 3 月 1 日 22:00 起每 12 小时一个采样，跨越 4 天
+	var samples []common.MetricSample
+	for i := 0; i < 6; i++ {
+		samples = append(samples, tsSample("cpu_percent", nil, time.Duration(i)*12*time.Hour, float64(i)))
+	}
+	appendSamples(t, s, samples...)
+	// 第一天压缩，其余留在 .seg
+	if err := s.Compact(tsBase.AddDate(0, 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	h := int64(3600)
+	checkPoints(t, queryPoints(t, s, "cpu_percent", nil, tsBase, tsBase.Add(72*time.Hour)),
+		map[int64]float64{0: 0, 12 * h: 1, 24 * h: 2, 36 * h: 3, 48 * h: 4, 60 * h: 5})
+	// 起止时间落在一天中间
+	checkPoints(t, queryPoints(t, s, "cpu_percent", nil, tsBase.Add(time.Hour), tsBase.Add(36*time.Hour)),
+		map[int64]float64{12 * h: 1, 24 * h: 2, 36 * h: 3})
+
+	if series, err := s.Query("cpu_percent", nil, tsBase.AddDate(0, 0, 10), tsBase.AddDate(0, 0, 11)); err != nil || len(series) != 0 {
+		t.Errorf("query without data = %+v, %v", series, err)
+	}
+}
+
+func TestTSStoreDeleteBefore(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestStore(t, dir)
+	for i := 0; i < 3; i++ {
+		appendSamples(t, s, tsSample("cpu_percent", nil, time.Duration(i)*24*time.Hour, float64(i)))
+	}
+	if err := s.Compact(tsBase.AddDate(0, 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	// 第一天是 .cseg，第二天是仍处于打开状态的 .seg
+	if err := s.DeleteBefore(tsBase.AddDate(0, 0, 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := []string{tsDay(tsBase.AddDate(0, 0, 2).Unix()) + tsHeadExt, tsSeriesFile}
+	if len(names) != len(want) || names[0] != want[0] || names[1] != want[1] {
+		t.Errorf("files after DeleteBefore = %v, want %v", names, want)
+	}
+	checkPoints(t, queryPoints(t, s, "cpu_percent", nil, tsBase, tsBase.AddDate(0, 0, 3)), map[int64]float64{48 * 3600: 2})
+
+	// 删除后同一天可以重新写入
+	appendSamples(t, s, tsSample("cpu_percent", nil, 24*time.Hour, 7))
+	checkPoints(t, queryPoints(t, s, "cpu_percent", nil, tsBase, tsBase.AddDate(0, 0, 3)), map[int64]float64{24 * 3600: 7, 48 * 3600: 2})
+}
+
+func appendFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
+	}
+}
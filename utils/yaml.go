@@ -10,7 +10,11 @@ import (
 
 const CONFIG_FILE = "config/llm_settings.yaml"
 
+// 配置文件中原始（未展开引用）的后端配置，保存时用于还原引用
+var llmCfgRaw = map[string]common.BackendConfig{}
+
 // 从指定路径加载配置文件并解析为 LLMConfig 结构体。
+// 其中的 ${secret:name} 与 ${env:NAME} 引用会被展开，凭据库未解锁时对应字段为空
 func LoadLLMCfg() (config *common.LLMConfig, err error) {
     // 读取文件内容
     data, err := os.ReadFile(filepath.Clean(CONFIG_FILE))
@@ -24,21 +28,76 @@ func LoadLLMCfg() (config *common.LLMConfig, err error) {
         fmt.Printf("error extract yaml: %v", err)
         return nil, err
     }
+
+    // 展开引用
+    UseSecretProvider(config.SecretProvider)
+    llmCfgRaw = map[string]common.BackendConfig{}
+    for name, b := range config.Backend {
+        llmCfgRaw[name] = b
+        b.BaseURL = resolveField(b.BaseURL)
+        b.APIKey = resolveField(b.APIKey)
+        b.Model = resolveField(b.Model)
+        config.Backend[name] = b
+    }
     return
 }
 
+// 展开单个字段，失败时打印错误并返回空
+func resolveField(raw string) string {
+    value, err := ResolveSecretRefs(raw)
+    if err != nil {
+        fmt.Printf("error resolve config: %v\n", err)
+        return ""
+    }
+    return value
+}
+
+// 字段未被修改时写回原始引用，否则写入新值
+func unresolveField(raw string, value string) string {
+    if IsSecretRef(raw) {
+        if resolved, err := ResolveSecretRefs(raw); err != nil || resolved == value {
+            return raw
+        }
+    }
+    return value
+}
+
 // 将给定的 LLMConfig 配置序列化为 YAML 格式并保存到指定的配置文件中
-func SaveLLMCfg(config *common.LLMConfig) {
-    yamlData, err := yaml.Marshal(&config)
+// API Key 不会以明文写入文件：新填写的密钥存入凭据存储，文件中只保留 ${secret:<后端>_api_key} 引用
+func SaveLLMCfg(config *common.LLMConfig) error {
+    out := *config
+    out.Backend = map[string]common.BackendConfig{}
+    for name, b := range config.Backend {
+        raw := llmCfgRaw[name]
+        b.BaseURL = unresolveField(raw.BaseURL, b.BaseURL)
+        b.Model = unresolveField(raw.Model, b.Model)
+
+        apiKey := unresolveField(raw.APIKey, b.APIKey)
+        if apiKey != "" && !IsSecretRef(apiKey) {
+            secretName := name + "_api_key"
+            if err := Secrets().Set(secretName, apiKey); err != nil {
+                return fmt.Errorf("save api key of <%s> failed: %w", name, err)
+            }
+            apiKey = SecretRef(secretName)
+        }
+        b.APIKey = apiKey
+        out.Backend[name] = b
+    }
+
+    yamlData, err := yaml.Marshal(&out)
 	if err != nil {
-		fmt.Printf("error marshaling yaml: %v", err)
-		return
+		return fmt.Errorf("error marshaling yaml: %w", err)
 	}
 
 	// 写入文件
 	err = os.WriteFile(CONFIG_FILE, yamlData, 0644)
 	if err != nil {
-		fmt.Printf("error writing file: %v", err)
-		return
+		return fmt.Errorf("error writing file: %w", err)
 	}
-}
\ No newline at end of file
+
+    // 更新原始配置，便于下次保存时识别引用
+    for name, b := range out.Backend {
+        llmCfgRaw[name] = b
+    }
+    return nil
+}
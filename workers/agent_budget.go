@@ -0,0 +1,30 @@
+package workers
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// 单个工具返回结果的默认长度上限（字符数），避免一次工具调用占满模型上下文
+const TOOL_OUTPUT_BUDGET = 16000
+
+// ** 注册单个工具的长度上限，未注册的工具使用 TOOL_OUTPUT_BUDGET
+var ToolsOutputBudget = map[string]int{
+	"get_file_tree": 32000,
+	"get_log_file":  24000,
+}
+
+// 按工具的长度上限截断结果（保留开头、丢弃末尾），并提示模型缩小查询范围
+// 需要保留最新内容的工具（如 get_log_file）应在工具内部按上限丢弃较早的内容
+func truncateToolOutput(name string, output string) string {
+	budget, ok := ToolsOutputBudget[name]
+	if !ok {
+		budget = TOOL_OUTPUT_BUDGET
+	}
+	total := utf8.RuneCountInString(output)
+	if total <= budget {
+		return output
+	}
+	return string([]rune(output)[:budget]) +
+		fmt.Sprintf("\n<%s> [结果已截断] 共 %d 字符, 仅保留前 %d 字符, 末尾的内容已丢弃。如需其余内容, 请缩小查询范围或减少返回条数。\n", name, total, budget)
+}
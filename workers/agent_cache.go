@@ -0,0 +1,118 @@
+package workers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 模型可在任意工具参数中设置该字段以跳过缓存
+const FORCE_REFRESH_KEY = "force_refresh"
+
+// ** 注册工具结果的缓存时间，未注册的工具每次都重新执行
+var ToolsCacheTTL = map[string]time.Duration{
+	"get_win_event":       1 * time.Minute,
+	"get_file_tree":       1 * time.Hour,
+	"get_sys_health":      10 * time.Second,
+	"get_sys_process":     5 * time.Second,
+	"get_sys_driver":      10 * time.Minute,
+	"get_evtx_file":       1 * time.Hour,
+	"get_anomalies":       10 * time.Second,
+	"get_process_history": 10 * time.Second,
+}
+
+type toolCacheEntry struct {
+	output    string
+	createdAt time.Time
+	ttl       time.Duration
+}
+
+// 缓存的最大条目数，清理过期条目后仍超出时丢弃最早的条目
+const maxToolCacheEntries = 128
+
+// 工具结果缓存，键为工具名 + 归一化后的参数
+type ToolCache struct {
+	entries map[string]toolCacheEntry
+	mu      sync.Mutex
+}
+
+var toolCache = &ToolCache{entries: make(map[string]toolCacheEntry)}
+
+// 生成缓存键：去掉 force_refresh 后按键名排序序列化参数
+func toolCacheKey(name string, q map[string]interface{}) string {
+	args := make(map[string]interface{}, len(q))
+	for k, v := range q {
+		if k != FORCE_REFRESH_KEY {
+			args[k] = v
+		}
+	}
+	b, _ := json.Marshal(args) // map 序列化时键名有序
+	return name + ":" + string(b)
+}
+
+// 查询未过期的缓存
+func (c *ToolCache) Get(key string, ttl time.Duration) (toolCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return e, false
+	}
+	if time.Since(e.createdAt) > ttl {
+		delete(c.entries, key)
+		return e, false
+	}
+	return e, true
+}
+
+// 写入缓存，同时清理已过期的条目（过期条目可能再也不会被查询到）
+func (c *ToolCache) Put(key string, output string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var oldestKey string
+	var oldest time.Time
+	for k, e := range c.entries {
+		if time.Since(e.createdAt) > e.ttl {
+			delete(c.entries, k)
+		} else if oldestKey == "" || e.createdAt.Before(oldest) {
+			oldestKey, oldest = k, e.createdAt
+		}
+	}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxToolCacheEntries {
+		delete(c.entries, oldestKey)
+	}
+	c.entries[key] = toolCacheEntry{output: output, createdAt: time.Now(), ttl: ttl}
+}
+
+// 执行工具函数，命中缓存时直接返回缓存结果并标注数据获取时间，只缓存成功的结果
+// status 为 ok、cached 或 error（工具返回了错误标记，见 TOOL_ERROR_MARK）
+func runToolCached(name string, f func(map[string]interface{}, chan<- string), q map[string]interface{}) (result string, status string) {
+	ttl, cacheable := ToolsCacheTTL[name]
+	forceRefresh, _ := q[FORCE_REFRESH_KEY].(bool)
+	key := toolCacheKey(name, q)
+
+	if cacheable && !forceRefresh {
+		if e, ok := toolCache.Get(key, ttl); ok {
+			age := time.Since(e.createdAt).Round(time.Second)
+			return fmt.Sprintf("<%s> [缓存结果] 以下数据获取于 %s (%v 前), 如需最新数据请设置 \"%s\": true\n%s",
+				name, e.createdAt.Format(time.DateTime), age, FORCE_REFRESH_KEY, e.output), "cached"
+		}
+	}
+
+	ch := make(chan string, 4096)
+	f(q, ch)  // 执行工具函数，结果写入ch
+	close(ch) // 确保通道关闭
+
+	// 读取通道结果（假设只发送一次结果），缓存完整结果，交给模型时再按长度上限截断
+	result = <-ch
+	if toolFailed(name, result) { // 失败可能是暂时的，不缓存
+		return result, "error"
+	}
+	if cacheable {
+		toolCache.Put(key, result, ttl)
+	}
+	return result, "ok"
+}
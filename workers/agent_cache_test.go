@@ -0,0 +1,54 @@
+package workers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestToolCachePrunesOnPut(t *testing.T) {
+	c := &ToolCache{entries: map[string]toolCacheEntry{}}
+	c.Put("expired", "a", time.Minute)
+	c.Put("fresh", "b", time.Hour)
+	// 模拟 10 分钟前写入
+	for k, e := range c.entries {
+		e.createdAt = e.createdAt.Add(-10 * time.Minute)
+		c.entries[k] = e
+	}
+
+	c.Put("new", "c", time.Minute)
+	if _, ok := c.entries["expired"]; ok {
+		t.Error("expired entry not pruned on Put")
+	}
+	for _, key := range []string{"fresh", "new"} {
+		if _, ok := c.Get(key, time.Hour); !ok {
+			t.Errorf("entry %s missing", key)
+		}
+	}
+}
+
+func TestToolCacheSizeLimit(t *testing.T) {
+	c := &ToolCache{entries: map[string]toolCacheEntry{}}
+	for i := 0; i < maxToolCacheEntries; i++ {
+		c.Put(fmt.Sprint(i), "x", time.Hour)
+		e := c.entries[fmt.Sprint(i)]
+		e.createdAt = e.createdAt.Add(time.Duration(i-maxToolCacheEntries) * time.Second) // 0 最早
+		c.entries[fmt.Sprint(i)] = e
+	}
+
+	// 覆盖已有条目不淘汰其他条目
+	c.Put("5", "y", time.Hour)
+	if len(c.entries) != maxToolCacheEntries {
+		t.Fatalf("%d entries after overwrite, want %d", len(c.entries), maxToolCacheEntries)
+	}
+	c.Put("extra", "z", time.Hour)
+	if len(c.entries) != maxToolCacheEntries {
+		t.Errorf("%d entries, want %d", len(c.entries), maxToolCacheEntries)
+	}
+	if _, ok := c.entries["0"]; ok {
+		t.Error("oldest entry not evicted")
+	}
+	if e, ok := c.Get("5", time.Hour); !ok || e.output != "y" {
+		t.Errorf("overwritten entry = %+v, %v", e, ok)
+	}
+}
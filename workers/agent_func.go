@@ -1,6 +1,11 @@
 package workers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 	"winds-assistant/tools"
 )
 
@@ -13,6 +18,11 @@ var ToolsFuncRegister = map[string]func(map[string]interface{}, chan<- string) {
 	"get_sys_driver": getSysDriver,
 	"get_bili_rcmd": getBiliRcmd,
 	"get_zhihu_rcmd": getZhihuRcmd,
+	"get_evtx_file": getEvtxFile,
+	"get_log_file": getLogFile,
+	"get_anomalies": getAnomalies,
+	"get_process_history": getProcessHistory,
+	"manage_process": manageProcess,
 }
 
 // ** 注册 Agent 工具 Prompt，后面的布尔值是设定其是否启用
@@ -24,6 +34,11 @@ var ToolsPromptRegister = map[string]interface{}{
 	"GET_SYS_DRIVER": map[string]interface{}{"prompt": GET_SYS_DRIVER_PROMPT, "enable": true},
 	"GET_BILI_RCMD": map[string]interface{}{"prompt": GET_BILI_RCMD_PROMPT, "enable": true},
 	"GET_ZHIHU_RCMD": map[string]interface{}{"prompt": GET_ZHIHU_RCMD_PROMPT, "enable": true},
+	"GET_EVTX_FILE": map[string]interface{}{"prompt": GET_EVTX_FILE_PROMPT, "enable": true},
+	"GET_LOG_FILE": map[string]interface{}{"prompt": GET_LOG_FILE_PROMPT, "enable": true},
+	"GET_ANOMALIES": map[string]interface{}{"prompt": GET_ANOMALIES_PROMPT, "enable": true},
+	"GET_PROCESS_HISTORY": map[string]interface{}{"prompt": GET_PROCESS_HISTORY_PROMPT, "enable": true},
+	"MANAGE_PROCESS": map[string]interface{}{"prompt": MANAGE_PROCESS_PROMPT, "enable": true},
 }
 
 // 在这里写 Agent Tools 的函数入口
@@ -34,13 +49,16 @@ const GET_WIN_EVENT_PROMPT = `
 工具 <get_win_event> 使用规则：
 1. 如果用户提到了 <分析日志> 等类似的需求，你可以使用 <get_win_event> 工具来获取日志。
 2. 此外，你还要解析用户需要分析的日志类型(Application, Security, System), 分析天数(StartTime, 为正数(默认1), 表示往前分析多少天)和最大事件数(MaxEvents, 默认50)。
-3. 最后, 只返回如下类似的json内容, 除此之外不要说任何其他内容, 不要有多余的符号如 Markdown 代码块标识符, 无效换行和空白等:
+3. 可选的过滤条件: levels(等级列表, 可选 Critical, Error, Warning, Information, Verbose), providers(事件来源列表), eventIDs(事件ID列表), keywords(消息关键字列表, 包含任一即可)。用户没有提到的过滤条件不要填写。
+4. 在 Linux 上同样使用这三种日志类型(分别对应全部日志、系统日志、认证日志), 也可以直接填写 systemd 服务名(如 sshd)。每条事件格式为: 时间 | 等级 | 来源 | 事件ID | 消息。事件较多时, 重复出现的事件会归纳为模式(<*> 表示变化的部分)并给出次数, 只出现一次的罕见事件原样列出。
+5. 最后, 只返回如下类似的json内容, 除此之外不要说任何其他内容, 不要有多余的符号如 Markdown 代码块标识符, 无效换行和空白等:
 {
 	"tools": {
 		"get_win_event": {
 			"logName": "Application",
 			"startTime": 1,
-			"maxEvents": 50
+			"maxEvents": 50,
+			"levels": ["Error", "Critical"]
 		}
 	}            
 }`
@@ -48,10 +66,25 @@ func getWinEvent(q map[string]interface{}, ch chan<- string) {
 	logName, _ := q["logName"].(string)
     _s, _ := q["startTime"].(float64)
     _m, _ := q["maxEvents"].(float64)
-    startTime := int(_s)
-    maxEvents := int(_m)
+    if _s <= 0 { // 与 Prompt 中的默认值一致
+        _s = 1
+    }
+    if _m <= 0 {
+        _m = 50
+    }
+    filter := tools.EventFilter{
+        Levels:    toStringList(q["levels"]),
+        Providers: toStringList(q["providers"]),
+        EventIDs:  toStringList(q["eventIDs"]),
+        Keywords:  toStringList(q["keywords"]),
+        StartTime: time.Now().AddDate(0, 0, -int(_s)),
+        MaxEvents: int(_m),
+    }
 
-	_o, _ := tools.QueryEvents(logName, startTime, maxEvents)
+	_o, err := tools.QueryEvents(logName, filter)
+	if err != nil {
+		_o = toolError(err)
+	}
 	output := "<get_win_event> 返回结果：" + _o
 	ch <- output
 }
@@ -72,21 +105,30 @@ const GET_FILE_TREE_PROMPT = `
 func getFileTree(q map[string]interface{}, ch chan<- string) {
 	diskList, _ := q["disk"].([]interface{})
 
-	output := "<get_file_tree> 返回结果："
+	var trees string
+	var errs []error
 	for _, d := range diskList {
 		disk, _ := d.(string)
-		_o, _ := tools.GetFileTree(disk, 3, 10, false)
-		output += _o
+		_o, err := tools.GetFileTree(disk, 3, 10, false)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", disk, err))
+		}
+		trees += _o
 	}
-	ch <- output
+	output := "<get_file_tree> 返回结果："
+	if len(errs) > 0 {
+		output += toolError(errors.Join(errs...)) + "\n"
+	}
+	ch <- output + trees
 }
 
 const GET_SYS_HEALTH_PROMPT = `
 工具 <get_sys_health> 使用规则：
-1. 如果用户提到了 <分析系统、硬件监控、CPU、GPU、内存、硬盘> 等类似的需求，你可以使用 <get_sys_health> 工具来获取系统信息。
-2. 此外，你还要解析用户想分析的时间范围(Minutes, 为正数, 表示往前分析多少分钟)。默认是1, 表示分析最近一分钟。
-3. 用户对每个指标返回一个列表, 分别代表每个指标在每个时间的值。默认每10秒记录一次数据。如果程序中断, 记录情况会断开。
-4. 你需要结合多个指标对系统状态进行分析。此外，因为这些是时间序列，你需要额外地进行一些数学上的分析。
+1. 如果用户提到了 <分析系统、硬件监控、CPU、GPU、内存、硬盘、网络> 等类似的需求，你可以使用 <get_sys_health> 工具来获取系统信息。
+2. 此外，你还要解析用户想分析的时间范围: 最近一段时间用 minutes(为正数, 表示往前分析多少分钟, 默认1); 用户提到具体的过去时段(如"昨天 14:00 到 15:00")时改用 start 和 end(格式为 "2006-01-02 15:04:05", 根据当前本地时间换算), 不要再填写 minutes。
+   可选参数: metrics(指标名称列表, 只分析用户关心的指标, 可选 cpu_percent, mem_used, mem_used_percent, disk_used, disk_used_percent, disk_read_bytes_rate, disk_write_bytes_rate, net_recv_bytes_rate, net_sent_bytes_rate, net_recv_packets_rate, net_sent_packets_rate, net_errors_rate, net_drops_rate, net_connections, gpu_util, gpu_mem_used, gpu_mem_used_percent, gpu_mem_clock, gpu_core_clock, gpu_temp, gpu_power); labels(标签过滤, 如 {"gpu": "0"} 表示第一块 GPU, {"mount": "C:"} 表示 C 盘, {"iface": "以太网"} 表示网卡); resolution(数据精度, 可选 raw, 1m, 1h, 默认按时间跨度自动选择)。用户没有提到的参数不要填写。
+3. 工具会返回各指标(按标签区分多块 GPU、磁盘、网卡等)在时间范围内的统计表: 样本数、最小、最大、均值、P50、P95、标准差、斜率(每小时变化量)、超过阈值的累计时长和变化点(均值明显跳变的时间及前后均值)。统计已经算好, 直接引用即可, 不需要自己计算。时间范围超过1小时时基于每分钟的汇总, 超过6小时时基于每小时的汇总, 最长可分析一年。如果程序中断, 记录情况会断开。
+4. 只有用户明确要求查看原始数据或逐条数据时, 才将 includeRaw 设为 true, 此时会额外返回每个指标的逐条数据; 否则不要填写。你需要结合多个指标对系统状态进行分析。
 5. 最后, 只返回如下类似的json内容, 除此之外不要说任何其他内容, 不要有多余的符号如 Markdown 代码块标识符, 无效换行和空白等:
 {
 	"tools": {
@@ -97,34 +139,71 @@ const GET_SYS_HEALTH_PROMPT = `
 }`
 // 根据提供的参数获取系统健康数据
 func getSysHealth(q map[string]interface{}, ch chan<- string) {
+	start, end, err := parseTimeRange(q)
+	if err != nil {
+		ch <- "<get_sys_health> 返回结果：" + toolError(err)
+		return
+	}
 	_m, _ := q["minutes"].(float64)
-	minutes := int(_m)
-	_o, _ := tools.GetSysHealthData(minutes)
+	includeRaw, _ := q["includeRaw"].(bool)
+	resolution, _ := q["resolution"].(string)
+	var labels map[string]string
+	if l, ok := q["labels"].(map[string]interface{}); ok {
+		labels = map[string]string{}
+		for k, v := range l {
+			labels[k] = fmt.Sprint(v)
+		}
+	}
+	_o, err := tools.GetSysHealthData(tools.SysHealthQuery{
+		Minutes:    int(_m),
+		Start:      start,
+		End:        end,
+		Metrics:    toStringList(q["metrics"]),
+		Labels:     labels,
+		Resolution: strings.ToLower(resolution),
+		IncludeRaw: includeRaw,
+	})
+	if err != nil {
+		_o = toolError(err)
+	}
 	output := "<get_sys_health> 返回结果：" + _o
 	ch <- output
 }
 
 const GET_SYS_PROCESS_PROMPT = `
 工具 <get_sys_process> 使用规则：
-1. 如果用户提到了 <分析系统进程、查看运行情况> 等类似的需求，你可以使用 <get_sys_process> 工具来获取系统进程信息。
-2. 你需要结合多个指标对系统状态进行分析, 如进程的CPU, 内存 ,执行路径 ,启动参数 ,运行状态 ,线程/句柄数 ,IO 统计等详细信息, 查找潜在问题。
-3. 最后, 只返回如下类似的json内容, 除此之外不要说任何其他内容, 不要有多余的符号如 Markdown 代码块标识符, 无效换行和空白等:
+1. 如果用户提到了 <分析系统进程、查看运行情况> 等类似的需求，你可以使用 <get_sys_process> 工具来获取系统当前的进程信息。
+2. 可选参数: sortBy(排序依据, 可选 cpu, mem, io, threads, 默认 cpu); topN(最多列出的进程数, 默认20); name(进程名的正则表达式, Go 语法, 忽略大小写请加 (?i), 如 "(?i)^chrome"); user(运行用户); ppid(父进程 ID, 只看其子进程); tree(为 true 时按父子关系以树状列出, 与 ppid 一起使用时包含全部子孙进程)。用户没有提到的参数不要填写。
+3. 工具返回紧凑的表格: PID、父进程 ID、进程名、状态、CPU 利用率(1 秒内按全部核心归一化)、内存、线程数、读写速率、启动时间和执行路径。你需要结合多个指标对系统状态进行分析, 查找潜在问题。
+4. 最后, 只返回如下类似的json内容, 除此之外不要说任何其他内容, 不要有多余的符号如 Markdown 代码块标识符, 无效换行和空白等:
 {
 	"tools": {
-		"get_sys_process": {                          
-			"enable": true
+		"get_sys_process": {
+			"sortBy": "cpu",
+			"topN": 20
 		}
 	}
 }`
-// 根据传入的参数判断是否启用获取系统进程信息
+// 根据提供的参数获取过滤、排序后的系统进程信息
 func getSysProcess(q map[string]interface{}, ch chan<- string) {
-	enable, _ := q["enable"].(bool)
-	output := "<get_sys_process> 返回结果："
-	if enable {
-		// 获取系统进程信息
-		_o := tools.GetSysProcessStr()
-		output +=  _o
+	sortBy, _ := q["sortBy"].(string)
+	topN, _ := q["topN"].(float64)
+	name, _ := q["name"].(string)
+	user, _ := q["user"].(string)
+	ppid, _ := q["ppid"].(float64)
+	tree, _ := q["tree"].(bool)
+	_o, err := tools.GetSysProcessStr(context.Background(), tools.ProcessQuery{
+		SortBy: sortBy,
+		TopN:   int(topN),
+		Name:   name,
+		User:   user,
+		Ppid:   int32(ppid),
+		Tree:   tree,
+	})
+	if err != nil {
+		_o = toolError(err)
 	}
+	output := "<get_sys_process> 返回结果：" + _o
 	ch <- output
 }
 
@@ -170,10 +249,18 @@ const GET_BILI_RCMD_PROMPT = `
 }`
 
 func getBiliRcmd(q map[string]interface{}, ch chan<- string) {
-	enable_cookie, _ := q["cookie"].(bool)
+	enable_cookie, _ := q["enable_cookie"].(bool)
 	rounds, _ := q["rounds"].(float64)
 
-	_o := tools.GetBiliRcmdStr(enable_cookie, int(rounds))
+	var cfg tools.BiliToolCfg
+	if err := tools.LoadToolCfg("get_bili_rcmd", &cfg); err != nil {
+		ch <- "<get_bili_rcmd> 返回结果：" + toolError(err)
+		return
+	}
+	_o, err := tools.GetBiliRcmdStr(cfg, enable_cookie, int(rounds))
+	if err != nil { // 已获取的部分结果附在错误之后
+		_o = toolError(err) + "\n" + _o
+	}
 	output := "<get_bili_rcmd> 返回结果：" + _o
 	ch <- output
 }
@@ -195,7 +282,294 @@ const GET_ZHIHU_RCMD_PROMPT = `
 
 func getZhihuRcmd(q map[string]interface{}, ch chan<- string) {
 	rounds, _ := q["rounds"].(float64)
-	_o := tools.GetZhihuRcmdStr(int(rounds))
+
+	var cfg tools.ZhihuToolCfg
+	if err := tools.LoadToolCfg("get_zhihu_rcmd", &cfg); err != nil {
+		ch <- "<get_zhihu_rcmd> 返回结果：" + toolError(err)
+		return
+	}
+	_o, err := tools.GetZhihuRcmdStr(cfg, int(rounds))
+	if err != nil { // 已获取的部分结果附在错误之后
+		_o = toolError(err) + "\n" + _o
+	}
 	output := "<get_zhihu_rcmd> 返回结果：" + _o
 	ch <- output
-}
\ No newline at end of file
+}
+
+// 返回工具缺失的必填配置项，name 可以是 ToolsPromptRegister 中的大写名称
+func ToolCfgMissing(name string) ([]string, error) {
+	return tools.CheckToolCfg(strings.ToLower(name))
+}
+const GET_EVTX_FILE_PROMPT = `
+工具 <get_evtx_file> 使用规则：
+1. 如果用户提供了导出的 Windows 事件日志文件(.evtx)并希望分析, 你可以使用 <get_evtx_file> 工具读取该文件, 这在任何系统上都可用。
+2. path 为用户给出的文件路径(如用户消息中的 [附件] 路径)。只能读取配置中允许的目录, 相对路径基于第一个允许的目录。
+3. 可选的过滤条件: levels(等级列表, 可选 Critical, Error, Warning, Information, Verbose), providers(事件来源列表), eventIDs(事件ID列表), keywords(消息关键字列表, 包含任一即可), start 和 end(时间范围, 格式为 "2006-01-02 15:04:05"), maxEvents(最大事件数, 默认50)。不需要的过滤条件不要填写。
+4. 每条事件格式为: 时间 | 等级 | 来源 | 事件ID | 事件数据。事件较多时同样会归纳为重复模式和罕见事件。
+5. 最后, 只返回如下类似的json内容, 除此之外不要说任何其他内容, 不要有多余的符号如 Markdown 代码块标识符, 无效换行和空白等:
+{
+	"tools": {
+		"get_evtx_file": {
+			"path": "D:/logs/System.evtx",
+			"levels": ["Error", "Warning"],
+			"start": "2025-03-01 00:00:00",
+			"maxEvents": 50
+		}
+	}
+}`
+
+func getEvtxFile(q map[string]interface{}, ch chan<- string) {
+	var cfg tools.EvtxFileToolCfg
+	if err := tools.LoadToolCfg("get_evtx_file", &cfg); err != nil {
+		ch <- "<get_evtx_file> 返回结果：" + toolError(err)
+		return
+	}
+
+	start, end, err := parseTimeRange(q)
+	if err != nil {
+		ch <- "<get_evtx_file> 返回结果：" + toolError(err)
+		return
+	}
+	path, _ := q["path"].(string)
+	_m, _ := q["maxEvents"].(float64)
+	filter := tools.EventFilter{
+		Levels:    toStringList(q["levels"]),
+		Providers: toStringList(q["providers"]),
+		EventIDs:  toStringList(q["eventIDs"]),
+		Keywords:  toStringList(q["keywords"]),
+		StartTime: start,
+		EndTime:   end,
+		MaxEvents: int(_m),
+	}
+	if filter.MaxEvents <= 0 {
+		filter.MaxEvents = 50
+	}
+
+	_o, err := tools.GetEvtxFileStr(cfg, path, filter)
+	if err != nil {
+		_o = toolError(err)
+	}
+	output := "<get_evtx_file> 返回结果：" + _o
+	ch <- output
+}
+
+const GET_LOG_FILE_PROMPT = `
+工具 <get_log_file> 使用规则：
+1. 如果用户希望分析某个应用程序的日志文件(如 .log, .txt), 你可以使用 <get_log_file> 工具读取该文件。只能读取配置中允许的目录, 相对路径基于第一个允许的目录。
+2. path 为日志文件路径; tail 为返回匹配结果的最后多少行(默认200); grep 为过滤行的正则表达式(Go 语法, 忽略大小写请加 (?i)); start 和 end 为时间范围(格式为 "2006-01-02 15:04:05")。不需要的参数不要填写。
+3. 如果用户关心更早的记录, 设置 rotated 为 true 以同时读取轮转文件(如 app.log.1, app.log.2.gz)。如果匹配行数很多且用户想了解整体情况, 设置 summarize 为 true, 重复的行会归纳为模式并给出次数。
+4. 最后, 只返回如下类似的json内容, 除此之外不要说任何其他内容, 不要有多余的符号如 Markdown 代码块标识符, 无效换行和空白等:
+{
+	"tools": {
+		"get_log_file": {
+			"path": "myapp/app.log",
+			"tail": 200,
+			"grep": "(?i)error|exception",
+			"start": "2025-03-01 00:00:00",
+			"rotated": false,
+			"summarize": false
+		}
+	}
+}`
+
+func getLogFile(q map[string]interface{}, ch chan<- string) {
+	var cfg tools.LogFileToolCfg
+	if err := tools.LoadToolCfg("get_log_file", &cfg); err != nil {
+		ch <- "<get_log_file> 返回结果：" + toolError(err)
+		return
+	}
+
+	start, end, err := parseTimeRange(q)
+	if err != nil {
+		ch <- "<get_log_file> 返回结果：" + toolError(err)
+		return
+	}
+
+	path, _ := q["path"].(string)
+	grep, _ := q["grep"].(string)
+	tail, _ := q["tail"].(float64)
+	rotated, _ := q["rotated"].(bool)
+	summarize, _ := q["summarize"].(bool)
+	_o, err := tools.GetLogFileStr(cfg, tools.LogFileQuery{
+		Path:      path,
+		Tail:      int(tail),
+		Grep:      grep,
+		StartTime: start,
+		EndTime:   end,
+		Rotated:   rotated,
+		Summarize: summarize,
+		MaxChars:  ToolsOutputBudget["get_log_file"], // 在工具内截断，保留最新的行
+	})
+	if err != nil {
+		_o = toolError(err)
+	}
+	output := "<get_log_file> 返回结果：" + _o
+	ch <- output
+}
+
+// 查询监控守护进程检测到的指标异常
+const GET_ANOMALIES_PROMPT = `
+工具 <get_anomalies> 使用规则：
+1. 如果用户询问 <有没有什么异常、今天电脑有没有不对劲、某段时间是否出现异常> 等类似的问题，你可以使用 <get_anomalies> 工具获取监控守护进程自动检测到的指标异常, 不需要扫描原始数据。
+2. 异常由三种检测器产生: zscore(与最近一段采样相比突变), ewma(偏离指数加权的正常区间), seasonal(与过去两周同一小时的水平相比异常)。严重程度分为 warning 和 critical, 偏离(σ)越大越异常。
+3. 可选参数: start 和 end(时间范围, 格式为 "2006-01-02 15:04:05", 默认从今天 0 点到现在); metrics(指标名称列表, 同 <get_sys_health>); severity(最低严重程度, 只关心严重异常时填 critical); maxResults(最多列出的明细条数, 默认50)。用户没有提到的参数不要填写。
+4. 工具会先按指标汇总异常次数, 再按偏离程度列出明细。需要进一步分析某个异常时段, 可以再使用 <get_sys_health> 查询该时段的统计。
+5. 最后, 只返回如下类似的json内容, 除此之外不要说任何其他内容, 不要有多余的符号如 Markdown 代码块标识符, 无效换行和空白等:
+{
+	"tools": {
+		"get_anomalies": {
+			"start": "2025-03-01 00:00:00"
+		}
+	}
+}`
+// 根据提供的参数查询检测到的异常
+func getAnomalies(q map[string]interface{}, ch chan<- string) {
+	start, end, err := parseTimeRange(q)
+	if err != nil {
+		ch <- "<get_anomalies> 返回结果：" + toolError(err)
+		return
+	}
+	severity, _ := q["severity"].(string)
+	maxResults, _ := q["maxResults"].(float64)
+	_o, err := tools.GetAnomaliesStr(tools.AnomalyQuery{
+		Start:      start,
+		End:        end,
+		Metrics:    toStringList(q["metrics"]),
+		Severity:   severity,
+		MaxResults: int(maxResults),
+	})
+	if err != nil {
+		_o = toolError(err)
+	}
+	output := "<get_anomalies> 返回结果：" + _o
+	ch <- output
+}
+
+// 查询过去某段时间资源占用最高的进程
+const GET_PROCESS_HISTORY_PROMPT = `
+工具 <get_process_history> 使用规则：
+1. 如果用户询问 <过去某个时间是什么进程占用 CPU、内存或磁盘 I/O, 比如"下午三点电脑很卡是谁在占用 CPU"> 等类似的问题，你可以使用 <get_process_history> 工具。<get_sys_process> 只能查看当前进程, 过去的时段请使用本工具。
+2. 时间范围: 最近一段时间用 minutes(为正数, 默认10); 用户提到具体的过去时段时改用 start 和 end(格式为 "2006-01-02 15:04:05", 根据当前本地时间换算), 不要再填写 minutes。
+3. 可选参数: by(排序依据, 可选 cpu, mem, io, 默认 cpu); topN(列出的进程数, 默认10); name(只看进程名包含该字符串的进程, 如 chrome); resolution(数据精度, 可选 raw, 1m, 1h, 默认按时间跨度自动选择)。用户没有提到的参数不要填写。
+4. 监控守护进程每次采集只记录 CPU、内存、I/O 占用前几名的进程, 工具返回每个进程(按进程名和执行路径合并同名的多个实例, 附带实例数和最近的 PID, 使用汇总数据时 PID 显示为 -)在时间范围内的 CPU 利用率(按全部核心归一化)、内存、读写速率的均值和峰值。
+5. 最后, 只返回如下类似的json内容, 除此之外不要说任何其他内容, 不要有多余的符号如 Markdown 代码块标识符, 无效换行和空白等:
+{
+	"tools": {
+		"get_process_history": {
+			"start": "2025-03-01 15:00:00",
+			"end": "2025-03-01 15:30:00",
+			"by": "cpu"
+		}
+	}
+}`
+// 根据提供的参数查询进程的历史资源占用
+func getProcessHistory(q map[string]interface{}, ch chan<- string) {
+	start, end, err := parseTimeRange(q)
+	if err != nil {
+		ch <- "<get_process_history> 返回结果：" + toolError(err)
+		return
+	}
+	_m, _ := q["minutes"].(float64)
+	topN, _ := q["topN"].(float64)
+	by, _ := q["by"].(string)
+	name, _ := q["name"].(string)
+	resolution, _ := q["resolution"].(string)
+	_o, err := tools.GetProcessHistoryStr(tools.ProcessHistoryQuery{
+		Minutes:    int(_m),
+		Start:      start,
+		End:        end,
+		By:         by,
+		TopN:       int(topN),
+		Name:       name,
+		Resolution: strings.ToLower(resolution),
+	})
+	if err != nil {
+		_o = toolError(err)
+	}
+	output := "<get_process_history> 返回结果：" + _o
+	ch <- output
+}
+
+// 结束、挂起、恢复进程或修改优先级，每次执行前都需要用户在对话框中确认
+const MANAGE_PROCESS_PROMPT = `
+工具 <manage_process> 使用规则：
+1. 只有用户明确要求 <结束、强制结束、暂停/挂起、恢复某个进程, 或调整进程优先级> 时, 才可以使用 <manage_process> 工具。不要主动处理用户没有要求的进程。
+2. pid 为目标进程 ID(不知道时先用 <get_sys_process> 按进程名查询); action 可选 terminate(正常结束), kill(强制结束, 仅在 terminate 无效或用户要求时使用), suspend(挂起), resume(恢复), set_priority(修改优先级); priority 只用于 set_priority, 可选 idle, below_normal, normal, above_normal, high; reason 为简要的操作理由, 会展示给用户。
+3. 每次操作都会弹出对话框请用户确认, 用户拒绝时不要再次请求同一操作。工具返回执行结果。
+4. 最后, 只返回如下类似的json内容, 除此之外不要说任何其他内容, 不要有多余的符号如 Markdown 代码块标识符, 无效换行和空白等:
+{
+	"tools": {
+		"manage_process": {
+			"pid": 4120,
+			"action": "set_priority",
+			"priority": "below_normal",
+			"reason": "该进程持续占用大量 CPU"
+		}
+	}
+}`
+// 根据提供的参数在用户确认后操作进程
+func manageProcess(q map[string]interface{}, ch chan<- string) {
+	pid, _ := q["pid"].(float64)
+	action, _ := q["action"].(string)
+	priority, _ := q["priority"].(string)
+	reason, _ := q["reason"].(string)
+	_o := runProcessAction(context.Background(), int32(pid), strings.ToLower(action), strings.ToLower(priority), reason)
+	output := "<manage_process> 返回结果：" + _o
+	ch <- output
+}
+
+// 工具执行失败时结果以该标记开头（紧跟在 "<工具名> 返回结果：" 之后），工具调用记录据此标注为 error
+const TOOL_ERROR_MARK = "[错误] "
+
+func toolError(err error) string {
+	return TOOL_ERROR_MARK + err.Error()
+}
+
+// 工具结果是否表示执行失败
+func toolFailed(name, result string) bool {
+	return strings.HasPrefix(strings.TrimPrefix(result, "<"+name+"> 返回结果："), TOOL_ERROR_MARK)
+}
+
+// 将模型给出的字符串、数字或列表参数统一转换为字符串列表
+func toStringList(v interface{}) (list []string) {
+	switch value := v.(type) {
+	case string:
+		if value != "" {
+			list = append(list, value)
+		}
+	case float64:
+		list = append(list, fmt.Sprint(value))
+	case []interface{}:
+		for _, item := range value {
+			list = append(list, toStringList(item)...)
+		}
+	}
+	return
+}
+
+// 解析模型给出的本地时间参数，未填写时返回零值，无法解析时返回错误（让模型修正格式，而不是按不限时间查询）
+func parseTimeArg(q map[string]interface{}, key string) (time.Time, error) {
+	v, ok := q[key]
+	if !ok || v == nil {
+		return time.Time{}, nil
+	}
+	s, isString := v.(string)
+	if s = strings.TrimSpace(s); isString && s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.DateTime, "2006-01-02 15:04", time.DateOnly, time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间参数 %s=%v, 格式应为 \"2006-01-02 15:04:05\"", key, v)
+}
+
+// 解析 start 和 end 参数
+func parseTimeRange(q map[string]interface{}) (start, end time.Time, err error) {
+	if start, err = parseTimeArg(q, "start"); err != nil {
+		return
+	}
+	end, err = parseTimeArg(q, "end")
+	return
+}
@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"time"
+	"winds-assistant/common"
 )
 
 const (
@@ -16,15 +19,16 @@ const (
 	SYSTEM_PROMPT_WITH_TOOLS_BASE = `
 	你是一个 Windows 系统上的人工智能助手。你需要分析用户的输入，然后以规定的格式返回将使用的工具。获取到信息后，你可以回答用户的问题。
 	注意: 在用户使用工具获取到资料后, 你要回答用户之前提出的问题, 不能再返回json格式的数据, 以免再次触发工具调用。
-	注意: 如果你确信用户不想使用工具获取信息, 可以根据用户需求随意返回任何内容, 无需遵从任何规范格式。\n`
+	注意: 如果你确信用户不想使用工具获取信息, 可以根据用户需求随意返回任何内容, 无需遵从任何规范格式。
+	注意: 部分工具的结果会被缓存并标注获取时间。如果用户明确要求最新数据, 可以在该工具的参数中加上 "force_refresh": true。\n`
 
 	// 使用工具后的用户提示
 	USER_PROMPT_WITH_TOOLS= `现在，现在可以回答我的问题了，通过工具获取的上述问题的资料如下:\n`
 )
 
-// 解析模型返回
-func AgentParser(rawOutput string) (useTool bool, output string){
-	useTool = true
+// 从模型返回中解析出工具调用（不执行工具）
+// 返回去掉思考过程和代码块标识后的文本；ok 为 false 时表示模型没有调用工具
+func ParseToolCalls(rawOutput string) (toolsMap map[string]interface{}, cleaned string, ok bool) {
 	index := strings.Index(rawOutput, "</think>")
 	if index != -1 {
 		rawOutput = rawOutput[index + len("</think>"):]
@@ -33,34 +37,46 @@ func AgentParser(rawOutput string) (useTool bool, output string){
 	rawOutput = re.ReplaceAllString(rawOutput, "\n")
 	rawOutput = strings.TrimPrefix(rawOutput, "\n")
 	rawOutput = strings.TrimSuffix(rawOutput, "\n")
-	rawOutput = strings.TrimSpace(rawOutput)
+	cleaned = strings.TrimSpace(rawOutput)
 
 	// 解析 JSON 到 map
 	var data map[string]interface{}
-	err := json.Unmarshal([]byte(rawOutput), &data)
-	if err != nil {
-		useTool = false
-		output = rawOutput
-		return
+	if err := json.Unmarshal([]byte(cleaned), &data); err != nil {
+		return nil, cleaned, false
 	}
+	toolsMap, ok = data["tools"].(map[string]interface{})
+	return
+}
 
-	// 逐个调用工具
-	toolsMap, ok := data["tools"].(map[string]interface{})
+// 解析模型返回
+// calls 为本次调用的全部工具记录（参数、耗时、原始输出等）
+func AgentParser(rawOutput string) (useTool bool, output string, calls []common.ToolCallRecord){
+	toolsMap, cleaned, ok := ParseToolCalls(rawOutput)
 	if !ok {
 		useTool = false
-		output = rawOutput
-		fmt.Print(err)
+		output = cleaned
 		return
 	}
+	useTool = true
+
+	// 逐个调用工具
 
 	var wg sync.WaitGroup
-	var mu sync.Mutex // 保护共享变量output
+	var mu sync.Mutex // 保护共享变量output和calls
 
 	for k, v := range toolsMap {
 		if q, ok := v.(map[string]interface{}); ok {
 			f, exists := ToolsFuncRegister[k]
 			if !exists {
-				output += fmt.Sprintf("Invalid tool %s\n", k)
+				invalid := fmt.Sprintf("Invalid tool %s\n", k)
+				now := time.Now()
+				mu.Lock()
+				output += invalid
+				calls = append(calls, common.ToolCallRecord{
+					Name: k, Args: q, StartTime: now, EndTime: now,
+					Bytes: len(invalid), Status: "invalid", Output: invalid,
+				})
+				mu.Unlock()
 				continue
 			}
 
@@ -68,19 +84,48 @@ func AgentParser(rawOutput string) (useTool bool, output string){
 			go func(k string, qParam map[string]interface{}) {
 				defer wg.Done()
 
-				ch := make(chan string, 4096)
-				f(qParam, ch)     // 执行工具函数，结果写入ch
-				close(ch)         // 确保通道关闭
-				
-				// 读取通道结果（假设只发送一次结果）
-				result := <-ch
+				// 执行工具函数（可能命中缓存）
+				start := time.Now()
+				result, status := runToolCached(k, f, qParam)
+				record := common.ToolCallRecord{
+					Name: k, Args: qParam, StartTime: start, EndTime: time.Now(),
+					Bytes: len(result), Status: status, Output: result,
+				}
+
 				mu.Lock()
-				output += result
+				output += truncateToolOutput(k, result) // 记录中保留完整结果，只截断交给模型的部分
+				calls = append(calls, record)
 				mu.Unlock()
 			}(k, q) // 显式传递循环变量k和q
 		}
 	}
 
 	wg.Wait() // 主协程直接等待所有任务完成
+	sort.Slice(calls, func(i, j int) bool { return calls[i].StartTime.Before(calls[j].StartTime) })
 	return
-}
\ No newline at end of file
+}
+// 当前本地时间，每次请求时追加在 Agent 模式的系统 Prompt 之后，供模型换算"昨天 14:00"等相对时间
+func AgentTimePrompt() string {
+	now := time.Now()
+	return fmt.Sprintf("\n当前本地时间: %s (%s)。工具参数中的时间均为本地时间, 格式为 \"2006-01-02 15:04:05\"。\n", now.Format(time.DateTime), now.Weekday())
+}
+
+// 根据 ToolsPromptRegister 中启用的工具生成 Agent 模式的系统 Prompt
+func BuildAgentSysPrompt() string {
+	// 按名称排序，保证同样的工具组合生成同样的 Prompt
+	var names []string
+	for name := range ToolsPromptRegister {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var agentPrompt string
+	for _, name := range names {
+		if v, ok := ToolsPromptRegister[name].(map[string]interface{}); ok {
+			if v["enable"].(bool) {
+				agentPrompt += fmt.Sprintf("%s\n", v["prompt"])
+			}
+		}
+	}
+	return SYSTEM_PROMPT_WITH_TOOLS_BASE + agentPrompt
+}
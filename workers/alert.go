@@ -0,0 +1,345 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"winds-assistant/common"
+	"winds-assistant/utils"
+)
+
+// 解析后的告警规则：<指标>[{标签=值,...}] <比较符> <阈值>[%] [for <持续时间>]
+// 用量指标的阈值带 % 时（如 mem_used > 90%），改为比较对应的百分比指标
+type alertRule struct {
+	Name      string
+	Expr      string
+	Metric    string
+	Match     map[string]string
+	Op        string
+	Threshold float64
+	For       time.Duration
+	Diagnose  bool
+}
+
+var alertPercentMetrics = map[string]string{
+	"mem_used":     "mem_used_percent",
+	"disk_used":    "disk_used_percent",
+	"gpu_mem_used": "gpu_mem_used_percent",
+}
+
+var alertRuleRe = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*(?:\{([^}]*)\})?\s*(>=|<=|>|<)\s*(-?[0-9]+(?:\.[0-9]+)?)\s*(%?)\s*(?:for\s+(\S+))?\s*$`)
+
+func parseAlertRule(cfg common.AlertRuleConfig) (alertRule, error) {
+	m := alertRuleRe.FindStringSubmatch(cfg.Rule)
+	if m == nil {
+		return alertRule{}, fmt.Errorf("invalid alert rule %q, expected like \"gpu_temp > 85 for 2m\"", cfg.Rule)
+	}
+	rule := alertRule{Name: cfg.Name, Expr: strings.TrimSpace(cfg.Rule), Metric: m[1], Op: m[3], Diagnose: cfg.Diagnose}
+	if rule.Name == "" {
+		rule.Name = rule.Expr
+	}
+	if m[2] != "" {
+		rule.Match = common.ParseLabels(strings.ReplaceAll(m[2], " ", ""))
+	}
+	rule.Threshold, _ = strconv.ParseFloat(m[4], 64)
+	if m[5] == "%" {
+		if percent, ok := alertPercentMetrics[rule.Metric]; ok {
+			rule.Metric = percent
+		} else if !strings.HasSuffix(rule.Metric, "_percent") && rule.Metric != "gpu_util" {
+			// 阈值按原单位比较，忽略 % 会得到意料之外的结果
+			return alertRule{}, fmt.Errorf("invalid alert rule %q: %% is only supported on percentage metrics and mem_used, disk_used, gpu_mem_used", cfg.Rule)
+		}
+	}
+	if m[6] != "" {
+		d, err := time.ParseDuration(m[6])
+		if err != nil {
+			return alertRule{}, fmt.Errorf("invalid duration in alert rule %q: %w", cfg.Rule, err)
+		}
+		rule.For = d
+	}
+	return rule, nil
+}
+
+func (r alertRule) matches(sample common.MetricSample) bool {
+	if sample.Name != r.Metric {
+		return false
+	}
+	for k, v := range r.Match {
+		if sample.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (r alertRule) breached(value float64) bool {
+	switch r.Op {
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	case "<":
+		return value < r.Threshold
+	default:
+		return value <= r.Threshold
+	}
+}
+
+// 每条规则的每条序列各自维护状态：条件持续满足 For 后触发一次，条件不再满足时发送恢复
+// 规则被删除或修改、序列停止上报时，状态在下一次评估时清理，正在触发的告警同样发送恢复
+type alertState struct {
+	rule     string // 规则名称 + 表达式
+	since    int64  // 条件开始满足的时间，0 表示未满足
+	last     int64  // 最近一次采样的时间
+	value    float64
+	interval int64 // 最近两次采样的间隔
+	firing   *common.AlertEvent
+}
+
+// 序列超过该时长（且超过 3 个采样间隔）没有新采样时视为停止上报，不短于采集器的最长退避时间
+const alertStaleAfter = collectorMaxBackoff
+
+type alertEngine struct {
+	rules    map[string]alertRule // 规则文本 -> 解析结果（配置修改后按文本重新解析）
+	invalid  map[string]bool      // 已记录过错误的规则
+	states   map[string]*alertState
+	diagnose map[string]bool // 需要诊断的告警 ID
+}
+
+func newAlertEngine() *alertEngine {
+	return &alertEngine{rules: map[string]alertRule{}, invalid: map[string]bool{}, states: map[string]*alertState{}, diagnose: map[string]bool{}}
+}
+
+// 读取配置中的规则，无效规则只记录一次日志
+func (e *alertEngine) loadRules(configs []common.AlertRuleConfig) []alertRule {
+	var rules []alertRule
+	for _, cfg := range configs {
+		key := cfg.Name + "\x00" + cfg.Rule + "\x00" + strconv.FormatBool(cfg.Diagnose)
+		rule, ok := e.rules[key]
+		if !ok {
+			var err error
+			if rule, err = parseAlertRule(cfg); err != nil {
+				if !e.invalid[key] {
+					log.Printf("[alert] %v", err)
+					e.invalid[key] = true
+				}
+				continue
+			}
+			e.rules[key] = rule
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// 用一批采样评估全部规则，返回新产生的触发和恢复事件
+func (e *alertEngine) Evaluate(configs []common.AlertRuleConfig, samples []common.MetricSample) []common.AlertEvent {
+	rules := e.loadRules(configs)
+	sorted := append([]common.MetricSample(nil), samples...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time < sorted[j].Time })
+
+	var events []common.AlertEvent
+	active := map[string]bool{}
+	for _, rule := range rules {
+		ruleKey := rule.Name + "\x00" + rule.Expr
+		active[ruleKey] = true
+		for _, sample := range sorted {
+			if !rule.matches(sample) {
+				continue
+			}
+			key := ruleKey + "\x00" + anomalyKey(sample.Name, sample.Labels)
+			st, ok := e.states[key]
+			if !ok {
+				st = &alertState{rule: ruleKey}
+				e.states[key] = st
+			}
+			if st.last > 0 && sample.Time > st.last {
+				st.interval = sample.Time - st.last
+			}
+			st.last, st.value = sample.Time, sample.Value
+
+			if !rule.breached(sample.Value) {
+				st.since = 0
+				if st.firing != nil {
+					resolved := *st.firing
+					resolved.State, resolved.Time, resolved.Value = "resolved", sample.Time, sample.Value
+					events = append(events, resolved)
+					delete(e.diagnose, resolved.ID)
+					st.firing = nil
+				}
+				continue
+			}
+			if st.since == 0 {
+				st.since = sample.Time
+			}
+			if st.firing == nil && time.Duration(sample.Time-st.since)*time.Second >= rule.For {
+				st.firing = &common.AlertEvent{
+					ID:   fmt.Sprintf("%d-%08x", sample.Time, crc32.ChecksumIEEE([]byte(key))),
+					Time: sample.Time, State: "firing", Rule: rule.Name, Expr: rule.Expr,
+					Name: sample.Name, Labels: sample.Labels, Unit: sample.Unit, Value: sample.Value, Since: st.since,
+				}
+				events = append(events, *st.firing)
+				if rule.Diagnose {
+					e.diagnose[st.firing.ID] = true
+				}
+			}
+		}
+	}
+	if len(sorted) > 0 {
+		events = append(events, e.sweep(active, sorted[len(sorted)-1].Time)...)
+	}
+	return events
+}
+
+// 清理不再属于任何规则或已停止上报的序列状态，正在触发的告警以最后一次采样的值发送恢复
+func (e *alertEngine) sweep(active map[string]bool, now int64) []common.AlertEvent {
+	var events []common.AlertEvent
+	for key, st := range e.states {
+		var reason string
+		switch {
+		case !active[st.rule]:
+			reason = common.ALERT_REASON_RULE_REMOVED
+		case time.Duration(now-st.last)*time.Second > max(alertStaleAfter, 3*time.Duration(st.interval)*time.Second):
+			reason = common.ALERT_REASON_STALE
+		default:
+			continue
+		}
+		if st.firing != nil {
+			resolved := *st.firing
+			resolved.State, resolved.Time, resolved.Value, resolved.Reason = "resolved", now, st.value, reason
+			events = append(events, resolved)
+			delete(e.diagnose, resolved.ID)
+		}
+		delete(e.states, key)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events
+}
+
+// 告警事件的通知文本
+func alertMessage(event common.AlertEvent) (title, content string) {
+	series := event.Name
+	if labels := common.FormatLabels(event.Labels); labels != "" {
+		series += "{" + labels + "}"
+	}
+	switch event.State {
+	case "firing":
+		title = common.ALERT_FIRING_TITLE + event.Rule
+		content = fmt.Sprintf("%s = %.2f %s (%s), 自 %s 起", series, event.Value, event.Unit, event.Expr, time.Unix(event.Since, 0).Format(time.TimeOnly))
+	case "resolved":
+		title = common.ALERT_RESOLVED_TITLE + event.Rule
+		content = fmt.Sprintf("%s = %.2f %s, 持续 %v", series, event.Value, event.Unit, time.Duration(event.Time-event.Since)*time.Second)
+		if event.Reason != "" {
+			content += " (" + event.Reason + ")"
+		}
+	default:
+		title = common.ALERT_DIAGNOSIS_TITLE + event.Rule
+		content = event.Diagnosis
+		if r := []rune(content); len(r) > 200 {
+			content = string(r[:200]) + "..."
+		}
+	}
+	return
+}
+
+var (
+	alertNotifierMu sync.Mutex
+	alertNotifier   func(title, content string)
+)
+
+// 设置发送桌面通知的函数，由界面启动后调用
+func SetAlertNotifier(notify func(title, content string)) {
+	alertNotifierMu.Lock()
+	defer alertNotifierMu.Unlock()
+	alertNotifier = notify
+}
+
+// 发送桌面通知（界面尚未启动时只记录日志）
+func notifyAlert(event common.AlertEvent) {
+	title, content := alertMessage(event)
+	log.Printf("[alert] %s: %s", title, content)
+	alertNotifierMu.Lock()
+	notify := alertNotifier
+	alertNotifierMu.Unlock()
+	if notify != nil {
+		notify(title, content)
+	}
+}
+
+// 处理新产生的告警事件：写入历史、发送通知，需要时在后台运行诊断
+func handleAlertEvents(ctx context.Context, engine *alertEngine, events []common.AlertEvent) {
+	for _, event := range events {
+		if err := utils.AppendAlertEvent(event); err != nil {
+			log.Printf("[alert] save event failed: %v", err)
+		}
+		notifyAlert(event)
+		if event.State != "firing" || !engine.diagnose[event.ID] {
+			continue
+		}
+		go func(event common.AlertEvent) {
+			diagnosis, err := DiagnoseAlert(ctx, event)
+			if err != nil {
+				diagnosis = common.ALERT_DIAGNOSIS_FAILED + err.Error()
+			}
+			result := event
+			result.State, result.Time, result.Diagnosis = "diagnosis", time.Now().Unix(), diagnosis
+			if err := utils.AppendAlertEvent(result); err != nil {
+				log.Printf("[alert] save diagnosis failed: %v", err)
+			}
+			notifyAlert(result)
+		}(event)
+	}
+}
+
+const (
+	alertDiagnoseTimeout = 3 * time.Minute
+
+	ALERT_DIAGNOSE_PROMPT = `你是一个 Windows 系统运维助手。监控系统触发了一条告警, 下面附有告警信息以及自动收集的进程、系统事件和指标统计。
+请用中文简要给出: 1. 最可能的原因(指出具体进程、事件或指标); 2. 建议的处理步骤。不超过 200 字, 不要返回 json。`
+)
+
+// 告警触发时自动收集进程、最近的系统事件和相关指标，交给默认后端给出诊断
+func DiagnoseAlert(ctx context.Context, event common.AlertEvent) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, alertDiagnoseTimeout)
+	defer cancel()
+
+	cfg, err := utils.LoadLLMCfg()
+	if err != nil {
+		return "", err
+	}
+	backend, ok := cfg.Backend[cfg.Default]
+	if !ok {
+		return "", fmt.Errorf("default backend %q not configured", cfg.Default)
+	}
+
+	// 与 Agent 模式相同的工具调用，结果经过缓存和长度截断
+	since := time.Unix(event.Since, 0).Add(-10 * time.Minute)
+	calls := fmt.Sprintf(`{"tools": {
+		"get_sys_process": {"sortBy": "cpu", "topN": 15},
+		"get_win_event": {"logName": "System", "startTime": 1, "maxEvents": 30, "levels": ["Critical", "Error", "Warning"]},
+		"get_sys_health": {"start": %q, "metrics": [%q]},
+		"get_anomalies": {"start": %q, "maxResults": 20}
+	}}`, since.Format(time.DateTime), event.Name, since.Format(time.DateTime))
+	_, gathered, _ := AgentParser(calls)
+
+	title, content := alertMessage(event)
+	messages := []common.LLMMessage{
+		{Role: "system", Content: ALERT_DIAGNOSE_PROMPT},
+		{Role: "user", Content: fmt.Sprintf("告警: %s\n%s\n\n%s", title, content, gathered)},
+	}
+	reply, err := ChatReq(ctx, cfg.Default, backend, messages)
+	if err != nil {
+		return "", err
+	}
+	if i := strings.Index(reply, "</think>"); i != -1 {
+		reply = reply[i+len("</think>"):]
+	}
+	return strings.TrimSpace(reply), nil
+}
@@ -0,0 +1,135 @@
+package workers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"winds-assistant/common"
+)
+
+const alertTestBase = 1_700_000_000
+
+// 以 "状态 规则 序列@时间 值[ 原因]" 的形式列出告警事件，时间为相对 alertTestBase 的秒数
+func formatAlertEvents(events []common.AlertEvent) string {
+	var lines []string
+	for _, e := range events {
+		line := fmt.Sprintf("%s %s %s@%d %g", e.State, e.Rule, anomalyKey(e.Name, e.Labels), e.Time-alertTestBase, e.Value)
+		if e.Reason != "" {
+			line += " " + e.Reason
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func gpuTemp(ts int64, gpu string, value float64) common.MetricSample {
+	return common.MetricSample{Time: alertTestBase + ts, Name: "gpu_temp", Labels: map[string]string{"gpu": gpu}, Unit: "°C", Value: value}
+}
+
+func checkAlertEvents(t *testing.T, got []common.AlertEvent, want ...string) {
+	t.Helper()
+	if g, w := formatAlertEvents(got), strings.Join(want, "\n"); g != w {
+		t.Errorf("events =\n%s\nwant\n%s", g, w)
+	}
+}
+
+func TestAlertEngineFireAndResolve(t *testing.T) {
+	rules := []common.AlertRuleConfig{{Name: "hot", Rule: "gpu_temp > 85 for 30s"}}
+	e := newAlertEngine()
+
+	checkAlertEvents(t, e.Evaluate(rules, []common.MetricSample{gpuTemp(0, "0", 90), gpuTemp(10, "0", 91), gpuTemp(10, "1", 50)}))
+	checkAlertEvents(t, e.Evaluate(rules, []common.MetricSample{gpuTemp(20, "0", 92), gpuTemp(30, "0", 93)}),
+		"firing hot gpu_temp{gpu=0}@30 93")
+	// 持续满足时不重复触发
+	checkAlertEvents(t, e.Evaluate(rules, []common.MetricSample{gpuTemp(40, "0", 95)}))
+	checkAlertEvents(t, e.Evaluate(rules, []common.MetricSample{gpuTemp(50, "0", 80)}),
+		"resolved hot gpu_temp{gpu=0}@50 80")
+	// 中途恢复时重新计时
+	checkAlertEvents(t, e.Evaluate(rules, []common.MetricSample{gpuTemp(60, "0", 90), gpuTemp(70, "0", 80), gpuTemp(80, "0", 90), gpuTemp(100, "0", 90)}))
+}
+
+func TestAlertEngineResolvesStaleSeries(t *testing.T) {
+	rules := []common.AlertRuleConfig{{Name: "hot", Rule: "gpu_temp > 85"}}
+	e := newAlertEngine()
+	checkAlertEvents(t, e.Evaluate(rules, []common.MetricSample{gpuTemp(0, "0", 90), gpuTemp(0, "1", 90)}),
+		"firing hot gpu_temp{gpu=0}@0 90", "firing hot gpu_temp{gpu=1}@0 90")
+
+	// GPU 1 不再上报，超过停止上报的时长后恢复
+	stale := int64(alertStaleAfter.Seconds())
+	for ts := int64(60); ts <= stale; ts += 60 {
+		checkAlertEvents(t, e.Evaluate(rules, []common.MetricSample{gpuTemp(ts, "0", 90)}))
+	}
+	checkAlertEvents(t, e.Evaluate(rules, []common.MetricSample{gpuTemp(stale+60, "0", 90)}),
+		"resolved hot gpu_temp{gpu=1}@"+fmt.Sprint(stale+60)+" 90 "+common.ALERT_REASON_STALE)
+	if len(e.states) != 1 {
+		t.Errorf("%d states left, want 1", len(e.states))
+	}
+
+	// 采样间隔较长的序列按 3 个间隔判断
+	e = newAlertEngine()
+	interval := 2 * stale
+	e.Evaluate(rules, []common.MetricSample{gpuTemp(0, "0", 90), gpuTemp(interval, "0", 90)})
+	checkAlertEvents(t, e.Evaluate(rules, []common.MetricSample{gpuTemp(3*interval, "1", 50)}))
+	checkAlertEvents(t, e.Evaluate(rules, []common.MetricSample{gpuTemp(4*interval+1, "1", 50)}),
+		"resolved hot gpu_temp{gpu=0}@"+fmt.Sprint(4*interval+1)+" 90 "+common.ALERT_REASON_STALE)
+}
+
+func TestAlertEngineResolvesRemovedRules(t *testing.T) {
+	e := newAlertEngine()
+	hot := common.AlertRuleConfig{Name: "hot", Rule: "gpu_temp > 85"}
+	checkAlertEvents(t, e.Evaluate([]common.AlertRuleConfig{hot}, []common.MetricSample{gpuTemp(0, "0", 90)}),
+		"firing hot gpu_temp{gpu=0}@0 90")
+
+	// 修改阈值相当于删除旧规则并添加新规则
+	edited := common.AlertRuleConfig{Name: "hot", Rule: "gpu_temp > 95"}
+	checkAlertEvents(t, e.Evaluate([]common.AlertRuleConfig{edited}, []common.MetricSample{gpuTemp(10, "0", 90)}),
+		"resolved hot gpu_temp{gpu=0}@10 90 "+common.ALERT_REASON_RULE_REMOVED)
+	checkAlertEvents(t, e.Evaluate([]common.AlertRuleConfig{edited}, []common.MetricSample{gpuTemp(20, "0", 96)}),
+		"firing hot gpu_temp{gpu=0}@20 96")
+
+	// 删除全部规则
+	checkAlertEvents(t, e.Evaluate(nil, []common.MetricSample{gpuTemp(30, "0", 96)}),
+		"resolved hot gpu_temp{gpu=0}@30 96 "+common.ALERT_REASON_RULE_REMOVED)
+	if len(e.states) != 0 || len(e.diagnose) != 0 {
+		t.Errorf("states %v, diagnose %v left after removing all rules", e.states, e.diagnose)
+	}
+}
+
+func TestParseAlertRule(t *testing.T) {
+	tests := []struct {
+		rule    string
+		metric  string
+		match   string
+		op      string
+		value   float64
+		seconds int
+		err     bool
+	}{
+		{rule: "gpu_temp > 85 for 2m", metric: "gpu_temp", op: ">", value: 85, seconds: 120},
+		{rule: `disk_used{mount="C:"} >= 90%`, metric: "disk_used_percent", match: `mount="C:"`, op: ">=", value: 90},
+		{rule: "mem_used > 95 %", metric: "mem_used_percent", op: ">", value: 95},
+		{rule: "mem_used > 8000000000", metric: "mem_used", op: ">", value: 8e9},
+		{rule: "cpu_percent > 90% for 30s", metric: "cpu_percent", op: ">", value: 90, seconds: 30},
+		{rule: "gpu_util <= 5%", metric: "gpu_util", op: "<=", value: 5},
+		{rule: "gpu_temp > 85%", err: true}, // 温度没有百分比形式
+		{rule: "net_recv_bytes_rate > 50%", err: true},
+		{rule: "gpu_temp > 85 for soon", err: true},
+		{rule: "gpu_temp == 85", err: true},
+	}
+	for _, tt := range tests {
+		rule, err := parseAlertRule(common.AlertRuleConfig{Rule: tt.rule})
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseAlertRule(%q) = %+v, want error", tt.rule, rule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseAlertRule(%q): %v", tt.rule, err)
+			continue
+		}
+		if rule.Metric != tt.metric || common.FormatLabels(rule.Match) != tt.match || rule.Op != tt.op || rule.Threshold != tt.value || int(rule.For.Seconds()) != tt.seconds || rule.Name != tt.rule {
+			t.Errorf("parseAlertRule(%q) = %+v", tt.rule, rule)
+		}
+	}
+}
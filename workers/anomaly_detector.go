@@ -0,0 +1,301 @@
+package workers
+
+import (
+	"log"
+	"math"
+	"sort"
+	"time"
+	"winds-assistant/common"
+	"winds-assistant/utils"
+)
+
+// 异常检测器，按序列（指标名 + 标签）逐个观察采样，发现异常时返回
+// 由存储协程单线程调用，实现无需加锁
+type AnomalyDetector interface {
+	Name() string
+	Observe(key string, sample common.MetricSample) *common.Anomaly
+}
+
+// ** 注册异常检测器
+var AnomalyDetectorRegister = []AnomalyDetector{
+	&zScoreDetector{Window: 60, Threshold: 4},
+	&ewmaDetector{Alpha: 0.1, Threshold: 4, Warmup: 30},
+	&seasonalDetector{Days: 14, MinDays: 3, Threshold: 4},
+}
+
+const (
+	// 偏离超过检测阈值该倍数时记为 critical
+	anomalyCriticalFactor = 1.5
+	// 同一序列同一检测器在该时间内只记录一次，严重程度升级时除外
+	anomalyCooldown = 5 * time.Minute
+)
+
+func anomalySeverity(score, threshold float64) string {
+	if score >= threshold*anomalyCriticalFactor {
+		return "critical"
+	}
+	return "warning"
+}
+
+// 各单位标准差的绝对下限（可以忽略的波动）。长期为 0 的序列（如网络错误数、空闲磁盘的读写速率）
+// 均值和标准差都是 0，没有绝对下限时突增永远无法评估；错误和丢包数的下限很小，出现即为异常
+var anomalySigmaFloors = map[string]float64{
+	"%":         0.5,
+	"bytes":     1 << 20,
+	"MB":        1,
+	"bytes/s":   64 << 10,
+	"packets/s": 1,
+	"errors/s":  0.01,
+	"drops/s":   0.01,
+	"MHz":       10,
+	"°C":        0.5,
+	"W":         0.5,
+	"count":     1,
+}
+
+// 标准差的下限：均值的 1% 与单位的绝对下限中较大者，避免近乎恒定的序列出现微小波动就报警
+func sigmaFloor(sigma, mean float64, unit string) float64 {
+	return math.Max(sigma, math.Max(0.01*math.Abs(mean), anomalySigmaFloors[unit]))
+}
+
+// 偏离超过阈值时生成异常，sigma 为 0 时（未知单位的恒为 0 的序列）无法评估
+func newAnomaly(detector string, sample common.MetricSample, expected, sigma, threshold float64) *common.Anomaly {
+	sigma = sigmaFloor(sigma, expected, sample.Unit)
+	if sigma == 0 {
+		return nil
+	}
+	score := math.Abs(sample.Value-expected) / sigma
+	if score < threshold {
+		return nil
+	}
+	return &common.Anomaly{
+		Time: sample.Time, Name: sample.Name, Labels: sample.Labels, Unit: sample.Unit,
+		Value: sample.Value, Expected: expected, Score: score,
+		Detector: detector, Severity: anomalySeverity(score, threshold),
+	}
+}
+
+// ** 滚动 z-score：与最近 Window 个采样的均值和标准差比较 **
+type zScoreDetector struct {
+	Window    int
+	Threshold float64
+	windows   map[string][]float64
+}
+
+func (d *zScoreDetector) Name() string { return "zscore" }
+
+func (d *zScoreDetector) Observe(key string, sample common.MetricSample) *common.Anomaly {
+	if d.windows == nil {
+		d.windows = map[string][]float64{}
+	}
+	window := d.windows[key]
+	var anomaly *common.Anomaly
+	if len(window) == d.Window {
+		mean, variance := 0.0, 0.0
+		for _, v := range window {
+			mean += v
+		}
+		mean /= float64(len(window))
+		for _, v := range window {
+			variance += (v - mean) * (v - mean)
+		}
+		anomaly = newAnomaly(d.Name(), sample, mean, math.Sqrt(variance/float64(len(window))), d.Threshold)
+		window = window[1:]
+	}
+	d.windows[key] = append(window, sample.Value)
+	return anomaly
+}
+
+// ** EWMA 区间：指数加权的均值和方差，对缓慢漂移自适应 **
+type ewmaDetector struct {
+	Alpha     float64
+	Threshold float64
+	Warmup    int // 观察够该数量的采样后才开始检测
+	states    map[string]*ewmaState
+}
+
+type ewmaState struct {
+	mean, variance float64
+	count          int
+}
+
+func (d *ewmaDetector) Name() string { return "ewma" }
+
+func (d *ewmaDetector) Observe(key string, sample common.MetricSample) *common.Anomaly {
+	if d.states == nil {
+		d.states = map[string]*ewmaState{}
+	}
+	st, ok := d.states[key]
+	if !ok {
+		d.states[key] = &ewmaState{mean: sample.Value, count: 1}
+		return nil
+	}
+	var anomaly *common.Anomaly
+	if st.count >= d.Warmup {
+		anomaly = newAnomaly(d.Name(), sample, st.mean, math.Sqrt(st.variance), d.Threshold)
+	}
+	diff := sample.Value - st.mean
+	st.mean += d.Alpha * diff
+	st.variance = (1 - d.Alpha) * (st.variance + d.Alpha*diff*diff)
+	st.count++
+	return anomaly
+}
+
+// ** 按小时的季节基线：与过去 Days 天同一小时的水平比较 **
+// 基线来自 1 小时汇总（每小时刷新一次），当前值取每分钟的均值，
+// 波动范围合并了各天之间的差异和小时内的波动（由 P95 与均值之差估计）
+type seasonalDetector struct {
+	Days      int
+	MinDays   int // 同一小时至少需要的天数
+	Threshold float64
+	baselines map[string]*[24]seasonalBaseline
+	refreshed time.Time
+	minutes   map[string]*minuteBucket
+}
+
+type seasonalBaseline struct {
+	mean, sigma float64
+	ok          bool
+}
+
+type minuteBucket struct {
+	sample common.MetricSample // 序列信息，Time 为分钟开始时间
+	sum    float64
+	count  int
+}
+
+func (d *seasonalDetector) Name() string { return "seasonal" }
+
+func (d *seasonalDetector) Observe(key string, sample common.MetricSample) *common.Anomaly {
+	if d.minutes == nil {
+		d.minutes = map[string]*minuteBucket{}
+	}
+	start := sample.Time - sample.Time%60
+	b, ok := d.minutes[key]
+	if ok && start < b.sample.Time { // 迟到的采样
+		return nil
+	}
+	if ok && b.sample.Time == start {
+		b.sum += sample.Value
+		b.count++
+		return nil
+	}
+	meta := sample
+	meta.Time = start
+	d.minutes[key] = &minuteBucket{sample: meta, sum: sample.Value, count: 1}
+	if !ok {
+		return nil
+	}
+
+	// 上一分钟结束，用其均值与基线比较
+	d.refresh(time.Unix(sample.Time, 0))
+	baselines, ok := d.baselines[key]
+	if !ok {
+		return nil
+	}
+	base := baselines[time.Unix(b.sample.Time, 0).Hour()]
+	if !base.ok {
+		return nil
+	}
+	avg := b.sample
+	avg.Value = b.sum / float64(b.count)
+	return newAnomaly(d.Name(), avg, base.mean, base.sigma, d.Threshold)
+}
+
+// 每小时从 1 小时汇总重新计算一次全部序列的基线
+func (d *seasonalDetector) refresh(now time.Time) {
+	if !d.refreshed.IsZero() && now.Sub(d.refreshed) < time.Hour {
+		return
+	}
+	d.refreshed = now
+
+	rollups, err := utils.DefaultRollups()
+	if err != nil {
+		log.Printf("[anomaly/%s] open rollups failed: %v", d.Name(), err)
+		return
+	}
+	var hourly *utils.Rollup
+	for _, r := range rollups {
+		if r.Step == time.Hour {
+			hourly = r
+		}
+	}
+	if hourly == nil {
+		return
+	}
+	series, err := hourly.QueryAll(now.AddDate(0, 0, -d.Days), now)
+	if err != nil {
+		log.Printf("[anomaly/%s] query hourly rollup failed: %v", d.Name(), err)
+		return
+	}
+
+	d.baselines = map[string]*[24]seasonalBaseline{}
+	for _, s := range series {
+		var byHour [24][]utils.RollupPoint
+		for _, p := range s.Points {
+			h := time.Unix(p.Time, 0).Hour()
+			byHour[h] = append(byHour[h], p)
+		}
+		baselines := &[24]seasonalBaseline{}
+		for h, points := range byHour {
+			if len(points) < d.MinDays {
+				continue
+			}
+			mean, between, within := 0.0, 0.0, 0.0
+			for _, p := range points {
+				mean += p.Avg
+				within += math.Max(p.P95-p.Avg, 0) / 1.645 // 正态分布下 P95 约为均值 + 1.645σ
+			}
+			mean /= float64(len(points))
+			within /= float64(len(points))
+			for _, p := range points {
+				between += (p.Avg - mean) * (p.Avg - mean)
+			}
+			between /= float64(len(points))
+			baselines[h] = seasonalBaseline{mean: mean, sigma: math.Sqrt(between + within*within), ok: true}
+		}
+		d.baselines[anomalyKey(s.Name, s.Labels)] = baselines
+	}
+}
+
+func anomalyKey(name string, labels map[string]string) string {
+	return name + "{" + common.FormatLabels(labels) + "}"
+}
+
+// 依次交给各检测器观察一批采样，同一序列同一检测器在冷却时间内只保留一条（严重程度升级时除外）
+type anomalyMonitor struct {
+	detectors []AnomalyDetector
+	last      map[string]common.Anomaly // 序列 + 检测器 -> 最近一次记录的异常
+}
+
+func newAnomalyMonitor() *anomalyMonitor {
+	return &anomalyMonitor{detectors: AnomalyDetectorRegister, last: map[string]common.Anomaly{}}
+}
+
+func (m *anomalyMonitor) Detect(samples []common.MetricSample) []common.Anomaly {
+	// 批次中混有多个采集器的数据，按时间排序保证同一序列按顺序观察
+	sorted := append([]common.MetricSample(nil), samples...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time < sorted[j].Time })
+
+	var result []common.Anomaly
+	for _, sample := range sorted {
+		if sample.Source == "process" { // 进程指标随进程启停频繁出现和消失，不做异常检测
+			continue
+		}
+		key := anomalyKey(sample.Name, sample.Labels)
+		for _, d := range m.detectors {
+			a := d.Observe(key, sample)
+			if a == nil {
+				continue
+			}
+			id := key + "/" + d.Name()
+			if prev, ok := m.last[id]; ok && a.Time-prev.Time < int64(anomalyCooldown/time.Second) &&
+				!(a.Severity == "critical" && prev.Severity != "critical") {
+				continue
+			}
+			m.last[id] = *a
+			result = append(result, *a)
+		}
+	}
+	return result
+}
@@ -0,0 +1,206 @@
+package workers
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+	"winds-assistant/common"
+)
+
+// 以 10 秒为间隔生成采样，value(i) 给出第 i 个采样的值
+func genSamples(name, unit string, n int, value func(i int) float64) []common.MetricSample {
+	samples := make([]common.MetricSample, n)
+	for i := range samples {
+		samples[i] = common.MetricSample{Time: 1_700_000_000 + int64(i)*10, Name: name, Unit: unit, Value: value(i)}
+	}
+	return samples
+}
+
+// 依次观察采样，返回每个采样对应的异常（没有时为 nil）
+func observeAll(d AnomalyDetector, samples []common.MetricSample) []*common.Anomaly {
+	result := make([]*common.Anomaly, len(samples))
+	for i, s := range samples {
+		result[i] = d.Observe(anomalyKey(s.Name, s.Labels), s)
+	}
+	return result
+}
+
+// 有规律的小幅波动
+func wave(base, amplitude float64) func(i int) float64 {
+	return func(i int) float64 { return base + amplitude*math.Sin(float64(i)) }
+}
+
+func TestZScoreDetector(t *testing.T) {
+	tests := []struct {
+		name     string
+		unit     string
+		baseline func(i int) float64
+		spike    float64
+		severity string // 为空表示不应报告异常
+	}{
+		{"spike on noisy series", "%", wave(30, 2), 60, "critical"},
+		{"small bump on noisy series", "%", wave(30, 2), 34, ""},
+		{"errors on an always-zero series", "errors/s", wave(0, 0), 0.1, "critical"},
+		{"io on an idle disk", "bytes/s", wave(0, 0), 300 << 10, "warning"},
+		{"tiny io on an idle disk", "bytes/s", wave(0, 0), 4 << 10, ""},
+		{"unknown unit with zero variance", "", wave(0, 0), 5, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &zScoreDetector{Window: 60, Threshold: 4}
+			samples := genSamples("m", tt.unit, 61, tt.baseline)
+			samples[60].Value = tt.spike
+			got := observeAll(d, samples)
+			for i, a := range got[:60] {
+				if a != nil {
+					t.Fatalf("sample %d flagged during warmup: %+v", i, a)
+				}
+			}
+			checkSeverity(t, got[60], tt.severity)
+		})
+	}
+}
+
+func TestEWMADetector(t *testing.T) {
+	tests := []struct {
+		name     string
+		unit     string
+		baseline func(i int) float64
+		spike    float64
+		severity string
+	}{
+		{"spike on noisy series", "°C", wave(50, 1), 70, "critical"},
+		{"small bump on noisy series", "°C", wave(50, 1), 52, ""},
+		{"drops on an always-zero series", "drops/s", wave(0, 0), 0.2, "critical"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &ewmaDetector{Alpha: 0.1, Threshold: 4, Warmup: 30}
+			samples := genSamples("m", tt.unit, 41, tt.baseline)
+			samples[40].Value = tt.spike
+			got := observeAll(d, samples)
+			for i, a := range got[:40] {
+				if a != nil {
+					t.Fatalf("sample %d flagged before the spike: %+v", i, a)
+				}
+			}
+			checkSeverity(t, got[40], tt.severity)
+		})
+	}
+
+	// 预热期内不检测
+	d := &ewmaDetector{Alpha: 0.1, Threshold: 4, Warmup: 30}
+	samples := genSamples("m", "errors/s", 10, wave(0, 0))
+	samples[9].Value = 100
+	if a := observeAll(d, samples)[9]; a != nil {
+		t.Errorf("flagged during warmup: %+v", a)
+	}
+}
+
+func TestSeasonalDetector(t *testing.T) {
+	start := time.Date(2025, 3, 1, 15, 0, 0, 0, time.Local)
+	key := anomalyKey("cpu_percent", nil)
+	newDetector := func() *seasonalDetector {
+		d := &seasonalDetector{Days: 14, MinDays: 3, Threshold: 4, refreshed: start} // 不从汇总存储刷新基线
+		baselines := &[24]seasonalBaseline{}
+		baselines[15] = seasonalBaseline{mean: 20, sigma: 2, ok: true}
+		d.baselines = map[string]*[24]seasonalBaseline{key: baselines}
+		return d
+	}
+	// 每分钟 6 个采样，第 m 分钟的值为 values[m]；下一分钟的第一个采样到达时比较上一分钟的均值
+	observeMinutes := func(d *seasonalDetector, values ...float64) []*common.Anomaly {
+		var result []*common.Anomaly
+		for m, v := range values {
+			for i := 0; i < 6; i++ {
+				s := common.MetricSample{Time: start.Unix() + int64(m*60+i*10), Name: "cpu_percent", Unit: "%", Value: v}
+				if a := d.Observe(key, s); a != nil {
+					result = append(result, a)
+				}
+			}
+		}
+		return result
+	}
+
+	if got := observeMinutes(newDetector(), 21, 19, 22, 20); len(got) != 0 {
+		t.Errorf("normal minutes flagged: %+v", got)
+	}
+	got := observeMinutes(newDetector(), 21, 35, 20)
+	if len(got) != 1 || got[0].Expected != 20 || got[0].Value != 35 || got[0].Severity != "critical" {
+		t.Fatalf("got %+v, want one critical anomaly for the 35%% minute", got)
+	}
+	if got[0].Time != start.Unix()+60 {
+		t.Errorf("anomaly time = %d, want start of the minute %d", got[0].Time, start.Unix()+60)
+	}
+
+	// 没有基线的小时不检测
+	d := newDetector()
+	d.baselines[key][15].ok = false
+	if got := observeMinutes(d, 20, 90, 20); len(got) != 0 {
+		t.Errorf("flagged without baseline: %+v", got)
+	}
+}
+
+func checkSeverity(t *testing.T, a *common.Anomaly, severity string) {
+	t.Helper()
+	switch {
+	case severity == "" && a != nil:
+		t.Errorf("unexpected anomaly: %+v", *a)
+	case severity != "" && a == nil:
+		t.Errorf("no anomaly, want %s", severity)
+	case severity != "" && a.Severity != severity:
+		t.Errorf("severity = %s (score %.1f), want %s", a.Severity, a.Score, severity)
+	}
+}
+
+// 按预设结果返回异常的检测器
+type scriptedDetector struct {
+	severities map[int64]string // 采样时间 -> 严重程度
+}
+
+func (d *scriptedDetector) Name() string { return "scripted" }
+
+func (d *scriptedDetector) Observe(key string, s common.MetricSample) *common.Anomaly {
+	severity, ok := d.severities[s.Time]
+	if !ok {
+		return nil
+	}
+	return &common.Anomaly{Time: s.Time, Name: s.Name, Labels: s.Labels, Severity: severity, Detector: d.Name()}
+}
+
+func TestAnomalyMonitorCooldown(t *testing.T) {
+	cooldown := int64(anomalyCooldown / time.Second)
+	d := &scriptedDetector{severities: map[int64]string{
+		0:              "warning",
+		60:             "warning",  // 冷却时间内，丢弃
+		120:            "critical", // 升级，保留
+		180:            "critical", // 冷却时间内，丢弃
+		240:            "warning",  // 降级不算升级，丢弃
+		120 + cooldown: "warning",  // 冷却结束，保留
+	}}
+	m := &anomalyMonitor{detectors: []AnomalyDetector{d}, last: map[string]common.Anomaly{}}
+
+	var samples []common.MetricSample
+	for ts := range d.severities {
+		samples = append(samples, common.MetricSample{Time: ts, Name: "gpu_temp", Labels: map[string]string{"gpu": "0"}})
+	}
+	// 其他序列和进程指标互不影响
+	samples = append(samples,
+		common.MetricSample{Time: 60, Name: "gpu_temp", Labels: map[string]string{"gpu": "1"}},
+		common.MetricSample{Time: 60, Name: "proc_cpu_percent", Source: "process"},
+	)
+
+	var got []string
+	for _, a := range m.Detect(samples) {
+		got = append(got, fmt.Sprintf("%s@%d %s", anomalyKey(a.Name, a.Labels), a.Time, a.Severity))
+	}
+	want := []string{"gpu_temp{gpu=0}@0 warning", "gpu_temp{gpu=1}@60 warning", "gpu_temp{gpu=0}@120 critical", "gpu_temp{gpu=0}@420 warning"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("anomaly %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package workers
 
 import (
+    "bufio"
     "bytes"
     "encoding/json"
     "fmt"
@@ -29,17 +30,7 @@ func ChatReqStream(ctx context.Context, settings *common.Settings, widgets commo
     }
 
     // 创建HTTP请求
-    reqBody, _ := json.Marshal(body)
-
-    var req *http.Request
-    if settings.BackendName == "ollama" {
-        req, _ = http.NewRequestWithContext(ctx, "POST", settings.BackendCfg.BaseURL + "/api/chat", bytes.NewBuffer(reqBody))
-    }else{
-        req, _ = http.NewRequestWithContext(ctx, "POST", settings.BackendCfg.BaseURL, bytes.NewBuffer(reqBody))
-    }
-    
-    req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer " + settings.BackendCfg.APIKey)
+    req, reqBody := newChatRequest(ctx, settings.BackendName, settings.BackendCfg, body)
 
     // 发送请求
     client := &http.Client{Timeout: 0} // 无超时限制
@@ -112,6 +103,61 @@ func ChatReqStream(ctx context.Context, settings *common.Settings, widgets commo
     return nil
 }
 
+// 根据后端类型构造聊天请求
+func newChatRequest(ctx context.Context, backendName string, cfg common.BackendConfig, body map[string]interface{}) (*http.Request, []byte) {
+    reqBody, _ := json.Marshal(body)
+
+    var req *http.Request
+    if backendName == "ollama" {
+        req, _ = http.NewRequestWithContext(ctx, "POST", cfg.BaseURL + "/api/chat", bytes.NewBuffer(reqBody))
+    }else{
+        req, _ = http.NewRequestWithContext(ctx, "POST", cfg.BaseURL, bytes.NewBuffer(reqBody))
+    }
+    
+    req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer " + cfg.APIKey)
+    return req, reqBody
+}
+
+// 不依赖界面的聊天请求，等待流式输出结束后返回完整回复（用于评测、后台诊断等）
+func ChatReq(ctx context.Context, backendName string, cfg common.BackendConfig, messages []common.LLMMessage) (string, error) {
+    parser, ok := ParserFuncRegister[backendName]
+    if !ok {
+        return "", fmt.Errorf("unknown backend: %s", backendName)
+    }
+
+    req, reqBody := newChatRequest(ctx, backendName, cfg, map[string]interface{}{
+        "model":    cfg.Model,
+        "stream":   true,
+        "messages": messages,
+    })
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return "", fmt.Errorf("request failed: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        respBody, _ := io.ReadAll(resp.Body)
+        return "", fmt.Errorf("error code: %d, req body: %s, resp body: %s", resp.StatusCode, reqBody, respBody)
+    }
+
+    var content bytes.Buffer
+    scanner := bufio.NewScanner(resp.Body)
+    scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+    for scanner.Scan() {
+        c, done := parser(scanner.Bytes())
+        content.WriteString(c)
+        if done {
+            break
+        }
+    }
+    if err := scanner.Err(); err != nil {
+        return content.String(), fmt.Errorf("read failed: %w", err)
+    }
+    return content.String(), nil
+}
+
 // 获取模型列表
 func GetModelList(url string, window fyne.Window) (modelList []string){
     // 发送 GET 请求
@@ -6,36 +6,16 @@ import (
 	"time"
 	"sync"
 	"winds-assistant/utils"
-	"strconv"
 	"winds-assistant/common"
-	"winds-assistant/tools"
 	"fmt"
-	"os"
-	"strings"
 )
-type DailyWriter struct {
-    writers    map[string]*utils.CSVWriter
-    currentDay string
-    mu         sync.Mutex
-}
-
-// NewDailyWriter 创建一个新的 DailyWriter 实例。(单例模式)
-func NewDailyWriter() *DailyWriter {
-    return &DailyWriter{
-        writers:    make(map[string]*utils.CSVWriter),
-        currentDay: time.Now().Local().Format(dateFormat),
-    }
-}
 
 const (
-	collectInterval = 10 * time.Second
-	storeInterval   = 10 * time.Second
+	collectInterval = 10 * time.Second // 采集器的内置周期，监控配置中未指定时使用
 	bufferSize      = 1024
-	dateFormat = "20060102"
+	rollupDelay     = 30 * time.Second // 桶结束后等待迟到采样的时间
 )
 
-var dailyWriter = NewDailyWriter()
-
 // 启动系统监控并返回停止函数
 func MonitorSys(ctx context.Context) (stopFunc func()) {
 	var wg sync.WaitGroup
@@ -45,233 +25,202 @@ func MonitorSys(ctx context.Context) (stopFunc func()) {
 	if err := utils.EnsureDir("data/"); err != nil {
 		fmt.Printf("Error: %v\n", err)
 	}
+	store, err := utils.DefaultTSStore()
+	if err != nil {
+		log.Printf("open metric store failed: %v", err)
+		return func() { cancel() }
+	}
+	rollups, err := utils.DefaultRollups()
+	if err != nil {
+		log.Printf("open metric rollups failed: %v", err)
+		return func() { cancel() }
+	}
 
-	dataChan := make(chan common.MetricData, bufferSize)
+	dataChan := make(chan []common.MetricSample, bufferSize)
 
-	// 启动采集协程
-	wg.Add(1)
+	// 每个采集器独立运行，全部退出后关闭通道通知存储协程
+	var collectors sync.WaitGroup
+	for _, c := range CollectorRegister {
+		collectors.Add(1)
+		go func(c Collector) {
+			defer collectors.Done()
+			runCollector(ctx, c, dataChan)
+		}(c)
+	}
 	go func() {
-		defer wg.Done()
-		collectMetrics(ctx, dataChan)
+		collectors.Wait()
+		close(dataChan)
 	}()
 
-	// 启动存储协程
+	// 启动存储协程：先导入旧数据并补算汇总，期间的采样在通道中缓冲
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		storeMetrics(ctx, dailyWriter.writers, dataChan)
+		importLegacyMetrics(store)
+		backfillRollups(store, rollups)
+		storeMetrics(ctx, store, rollups, dataChan)
 	}()
 
 	return func() {
 		cancel()  // 发送停止信号
 		wg.Wait() // 等待所有协程退出
+		utils.CloseDefaultStores()
 	}
 }
 
-// 定期收集系统指标并将其发送到指定的通道。
-func collectMetrics(ctx context.Context, dataChan chan<- common.MetricData) {
-	ticker := time.NewTicker(collectInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			close(dataChan) // 关闭通道通知存储协程
-			return
-		case <-ticker.C:
-			data, err := gatherSystemMetrics()
-			if err != nil {
-				log.Printf("Metrics collection failed: %v", err)
-				continue
-			}
-
-			select {
-			case dataChan <- data:
-			default:
-				log.Println("Metrics buffer full, discarding data")
-			}
-		}
+// 导入旧版本按天保存的 CSV 数据（只在存在时执行一次）
+func importLegacyMetrics(store *utils.TSStore) {
+	if n, err := utils.ImportMetricCSV(store, "data/"); err != nil {
+		log.Printf("import legacy metrics failed: %v", err)
+	} else if n > 0 {
+		log.Printf("imported %d legacy metric samples", n)
 	}
 }
 
-// 收集系统的各项指标数据，包括 CPU、内存、磁盘和 GPU 信息。
-func gatherSystemMetrics() (common.MetricData, error) {
-	cpuInfo, err := tools.GetCPUInfo()
-	if err != nil {
-		return common.MetricData{}, err
-	}
-
-	memInfo, err := tools.GetMemInfo()
-	if err != nil {
-		return common.MetricData{}, err
-	}
-
-	diskInfo, err := tools.GetDiskInfo()
-	if err != nil {
-		return common.MetricData{}, err
-	}
-
-	gpuInfo, err := tools.GetNVGPUInfo()
-	if err != nil {
-		return common.MetricData{}, err
+// 补算上次退出后（或原始数据保留期内）尚未汇总的桶
+func backfillRollups(store *utils.TSStore, rollups []*utils.Rollup) {
+	now := time.Now()
+	since := now.Add(-utils.LoadMonitorCfg().Retention.Raw)
+	for _, r := range rollups {
+		if err := r.Backfill(store, since, now); err != nil {
+			log.Printf("backfill %s rollup failed: %v", r.Name, err)
+		}
 	}
-
-	return common.MetricData{
-		CPU:  cpuInfo,
-		Mem:  memInfo,
-		Disk: diskInfo,
-		GPU:  gpuInfo,
-		Time: time.Now().Local().Unix(),
-	}, nil
 }
 
-// 持续接收 MetricData 并定期将其存储到 CSV 文件中。
-func storeMetrics(ctx context.Context, writers map[string]*utils.CSVWriter, dataChan <-chan common.MetricData) {
-	ticker := time.NewTicker(storeInterval)
+// 持续接收采样数据并定期写入时序存储和汇总，日期变化时压缩前一天的数据并清理过期数据
+// 写入周期、数据目录和导出设置修改后在下一次写入时生效
+func storeMetrics(ctx context.Context, store *utils.TSStore, rollups []*utils.Rollup, dataChan <-chan []common.MetricSample) {
+	interval := utils.LoadMonitorCfg().StoreInterval
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	var batch []common.MetricData
+	var batch []common.MetricSample
+	var maintainedDay string
+	anomalies := newAnomalyMonitor()
+	alerts := newAlertEngine()
+	exporter := newMetricExporter()
+	exporter.Sync(utils.LoadMonitorCfg().Exporter)
+	defer exporter.Stop()
 	for {
 		select {
 		case <-ctx.Done():
-			flushRemainingData(batch)
-			// 记得关闭所有 writer
-			for _, writer := range writers {
-				writer.Close()
-			}
+			flushRemainingData(store, batch)
 			return
 		case data, ok := <-dataChan:
 			if !ok {
-				flushRemainingData(batch)
+				flushRemainingData(store, batch)
 				return
 			}
-			batch = append(batch, data)
+			batch = append(batch, data...)
+			exporter.Update(data)
 		case <-ticker.C:
 			if len(batch) > 0 {
-				writeBatchToCSV(batch)
+				if err := store.Append(batch); err != nil {
+					log.Printf("store metrics failed: %v", err)
+				}
+				for _, r := range rollups {
+					r.Add(batch)
+				}
+				recordAnomalies(anomalies, batch)
+				handleAlertEvents(ctx, alerts, alerts.Evaluate(utils.LoadMonitorCfg().Alerts, batch))
 				batch = nil
 			}
+			for _, r := range rollups {
+				if err := r.Flush(time.Now().Add(-rollupDelay)); err != nil {
+					log.Printf("store %s rollup failed: %v", r.Name, err)
+				}
+			}
+			if today := time.Now().Local().Format("20060102"); today != maintainedDay {
+				maintainStore(store, rollups)
+				maintainedDay = today
+			}
+
+			if s, r, ok := switchDataDir(store); ok {
+				store, rollups = s, r
+				backfillRollups(store, rollups)
+				maintainedDay = ""
+			}
+			if d := utils.LoadMonitorCfg().StoreInterval; d != interval {
+				interval = d
+				ticker.Reset(interval)
+			}
+			exporter.Sync(utils.LoadMonitorCfg().Exporter)
 		}
 	}
 }
 
-// 将一批 MetricData 写入 CSV 文件。
-func writeBatchToCSV(batch []common.MetricData) {
-    for _, data := range batch {
-        dailyWriter.WriteMetric(data.Time, "cpu_percent", data.CPU.Percent, "%")
-        dailyWriter.WriteMetric(data.Time, "mem_used", float64(data.Mem.Used), "bytes")
-        dailyWriter.WriteMetric(data.Time, "disk_used", float64(data.Disk.Used), "bytes")
-        
-        if len(data.GPU) > 0 {
-            g := data.GPU[0]
-            dailyWriter.WriteMetric(data.Time, "gpu_util", float64(g.Utilization), "%")
-            dailyWriter.WriteMetric(data.Time, "gpu_mem_used", float64(g.MemUsed), "MB")
-            dailyWriter.WriteMetric(data.Time, "gpu_mem_clock", float64(g.MemClock), "MHz")
-            dailyWriter.WriteMetric(data.Time, "gpu_core_clock", float64(g.CoreClock), "MHz")
-            dailyWriter.WriteMetric(data.Time, "gpu_temp", float64(g.Temperature), "°C")
-            dailyWriter.WriteMetric(data.Time, "gpu_power", float64(g.PowerDraw), "W")
-        }
-    }
-    
-    // 批量刷新
-    dailyWriter.mu.Lock()
-    defer dailyWriter.mu.Unlock()
-    for _, w := range dailyWriter.writers {
-        w.Flush()
-    }
-	// 删除旧文件（三天以前）
-    dailyWriter.rotateFiles()
+// 检测一批采样中的异常并写入异常记录
+func recordAnomalies(monitor *anomalyMonitor, batch []common.MetricSample) {
+	found := monitor.Detect(batch)
+	if len(found) == 0 {
+		return
+	}
+	store, err := utils.DefaultAnomalyStore()
+	if err == nil {
+		err = store.Append(found)
+	}
+	if err != nil {
+		log.Printf("store %d anomalies failed: %v", len(found), err)
+	}
 }
 
-// 根据新的日期旋转文件，清理旧文件并重置写入器状态。
-// 该方法会删除三天前的旧文件，关闭当前的写入器，并初始化新的写入器状态。
-func (dw *DailyWriter) rotateFiles() {
-    // 清理旧文件
-    oldDay := time.Now().AddDate(0, 0, -3).Local().Format(dateFormat)
-
-	files, err := os.ReadDir("data/")
-    if err != nil {
-        log.Fatalf("%v", err)
-    }
-
-	for _, file := range files {
-        if file.IsDir() {
-            continue // 跳过子目录
-        }
-        
-        // 只处理 CSV 文件
-        if !strings.HasSuffix(file.Name(), ".csv") {
-            continue
-        }
-        
-		_f := strings.Split(file.Name(), "_")
-		_c := strings.Compare(strings.Split(_f[len(_f)-1], ".")[0], oldDay)
-		if _c<=0 {
-			oldFile := fmt.Sprintf("data/%s", file.Name())
-			if _, err := os.Stat(oldFile); err == nil {
-				os.Remove(oldFile)
-			}
+// 配置中的数据目录变化时切换到新目录的存储
+// 旧目录中尚未汇总的数据在下次切换回该目录时从原始数据补算
+func switchDataDir(current *utils.TSStore) (*utils.TSStore, []*utils.Rollup, bool) {
+	store, err := utils.DefaultTSStore()
+	if err != nil || store == current {
+		if err != nil {
+			log.Printf("open metric store failed: %v", err)
 		}
-    }
+		return nil, nil, false
+	}
+	rollups, err := utils.DefaultRollups()
+	if err != nil {
+		log.Printf("open metric rollups failed: %v", err)
+		return nil, nil, false
+	}
+	log.Printf("metric data directory changed to %s", utils.LoadMonitorCfg().DataDir)
+	return store, rollups, true
 }
 
-// 将指定时间戳、名称、值和单位写入 CSV 文件
-func (dw *DailyWriter) WriteMetric(timestamp int64, name string, value float64, unit string) {
-    dw.mu.Lock()
-    defer dw.mu.Unlock()
-
-	// 更新 dailyWriter 的当前日期
-	currentDay := time.Unix(timestamp, 0).Format(dateFormat)
-	dw.currentDay = currentDay
-
-    // 获取或创建写入器
-    writer, exists := dw.writers[name]
-	if !exists {
-		fileName := fmt.Sprintf("data/%s_%s.csv", name, dw.currentDay)
-		
-		// 检查文件是否已存在
-		fileExists := false
-		if _, err := os.Stat(fileName); err == nil {
-			fileExists = true
-		} else if !os.IsNotExist(err) {
-			log.Printf("check file status failed: %v", err)
-			return
+// 压缩已结束的日期并按各精度的保留时长删除过期数据
+func maintainStore(store *utils.TSStore, rollups []*utils.Rollup) {
+	now := time.Now()
+	retention := utils.LoadMonitorCfg().Retention
+	stores := map[*utils.TSStore]time.Duration{store: retention.Raw}
+	for _, r := range rollups {
+		switch r.Name {
+		case "1m":
+			stores[r.Store()] = retention.Minute
+		case "1h":
+			stores[r.Store()] = retention.Hour
 		}
-
-		var err error
-        if writer, err = utils.NewCSVWriter(fileName); err != nil {
-            log.Printf("create writer failed: %v", err)
-            return
-        }
-
-		dw.writers[name] = writer
-		// 仅在创建新文件时写入表头
-		if !fileExists {
-			if err := writer.Write([]string{"timestamp", "time", "name", "value", "unit", "source"}); err != nil {
-				log.Printf("write table failed: %v", err)
-			}
+	}
+	for s, keep := range stores {
+		if err := s.Compact(now); err != nil {
+			log.Printf("compact metrics failed: %v", err)
+		}
+		if err := s.DeleteBefore(now.Add(-keep)); err != nil {
+			log.Printf("delete expired metrics failed: %v", err)
 		}
 	}
-
-    // 构造记录
-    record := []string{
-		fmt.Sprint(timestamp),
-        time.Unix(timestamp, 0).Format(time.RFC3339Nano),
-        name,
-        strconv.FormatFloat(value, 'f', 2, 64),
-        unit,
-        "system",
-    }
-
-    if err := writer.Write(record); err != nil {
-        log.Printf("写入失败: %v", err)
-    }
+	if anomalies, err := utils.DefaultAnomalyStore(); err == nil {
+		if err := anomalies.DeleteBefore(now.Add(-retention.Anomalies)); err != nil {
+			log.Printf("delete expired anomalies failed: %v", err)
+		}
+	}
+	if err := utils.DeleteAlertEventsBefore(now.Add(-retention.Alerts)); err != nil {
+		log.Printf("delete expired alerts failed: %v", err)
+	}
 }
 
 // 处理剩余数据
-func flushRemainingData(batch []common.MetricData) {
-    if len(batch) > 0 {
-        log.Printf("Flushing %d pending metrics", len(batch))
-        writeBatchToCSV(batch)
-    }
-}
\ No newline at end of file
+func flushRemainingData(store *utils.TSStore, batch []common.MetricSample) {
+	if len(batch) > 0 {
+		log.Printf("Flushing %d pending metrics", len(batch))
+		if err := store.Append(batch); err != nil {
+			log.Printf("store metrics failed: %v", err)
+		}
+	}
+}
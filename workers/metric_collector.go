@@ -0,0 +1,422 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+	"winds-assistant/common"
+	"winds-assistant/tools"
+	"winds-assistant/utils"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// 指标采集器，每个采集器按自己的周期独立调度，失败互不影响
+// Interval 为内置周期，可被监控配置覆盖
+type Collector interface {
+	Name() string
+	Interval() time.Duration
+	Collect(ctx context.Context) ([]common.MetricSample, error)
+}
+
+// ** 注册指标采集器
+var CollectorRegister = []Collector{
+	&cpuCollector{},
+	&memCollector{},
+	&diskCollector{},
+	&diskIOCollector{},
+	&netCollector{},
+	&gpuCollector{},
+	&processCollector{},
+}
+
+const (
+	// 连续失败时采集间隔按 2 的幂次退避，最长不超过该值
+	collectorMaxBackoff = 10 * time.Minute
+)
+
+// 按采集器的周期循环采集，失败时退避重试并单独记录日志
+// 每轮重新读取监控配置，周期、启用状态和记录的指标修改后立即生效
+func runCollector(ctx context.Context, c Collector, dataChan chan<- []common.MetricSample) {
+	failures := 0
+	timer := time.NewTimer(utils.CollectorInterval(utils.LoadMonitorCfg(), c.Name(), c.Interval()))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		config := utils.LoadMonitorCfg()
+		interval := utils.CollectorInterval(config, c.Name(), c.Interval())
+		if !utils.CollectorEnabled(config, c.Name()) {
+			timer.Reset(interval) // 停用期间按周期检查是否重新启用
+			continue
+		}
+
+		samples, err := c.Collect(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			failures++
+			wait := collectorBackoff(interval, failures)
+			log.Printf("[collector/%s] collect failed (%d in a row), retry in %v: %v", c.Name(), failures, wait, err)
+			timer.Reset(wait)
+			continue
+		}
+		if failures > 0 {
+			log.Printf("[collector/%s] recovered after %d failures", c.Name(), failures)
+			failures = 0
+		}
+
+		enabled := samples[:0]
+		for _, sample := range samples {
+			if utils.MetricEnabled(config, sample.Name) {
+				sample.Source = c.Name()
+				enabled = append(enabled, sample)
+			}
+		}
+		if len(enabled) > 0 {
+			select {
+			case dataChan <- enabled:
+			default:
+				log.Printf("[collector/%s] metrics buffer full, discarding data", c.Name())
+			}
+		}
+		timer.Reset(interval)
+	}
+}
+
+func collectorBackoff(interval time.Duration, failures int) time.Duration {
+	wait := interval
+	for i := 1; i < failures && wait < collectorMaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > collectorMaxBackoff {
+		wait = collectorMaxBackoff
+	}
+	return wait
+}
+
+// ** CPU 利用率 **
+type cpuCollector struct{}
+
+func (c *cpuCollector) Name() string            { return "cpu" }
+func (c *cpuCollector) Interval() time.Duration { return collectInterval }
+
+func (c *cpuCollector) Collect(ctx context.Context) ([]common.MetricSample, error) {
+	percent, err := cpu.PercentWithContext(ctx, time.Second, false)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Local().Unix()
+	return []common.MetricSample{{Time: now, Name: "cpu_percent", Value: percent[0], Unit: "%"}}, nil
+}
+
+// ** 内存用量 **
+type memCollector struct{}
+
+func (c *memCollector) Name() string            { return "mem" }
+func (c *memCollector) Interval() time.Duration { return collectInterval }
+
+func (c *memCollector) Collect(ctx context.Context) ([]common.MetricSample, error) {
+	memInfo, err := tools.GetMemInfo()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Local().Unix()
+	return []common.MetricSample{
+		{Time: now, Name: "mem_used", Value: float64(memInfo.Used), Unit: "bytes"},
+		{Time: now, Name: "mem_used_percent", Value: memInfo.UsedPercent, Unit: "%"},
+	}, nil
+}
+
+// ** 磁盘用量（按挂载点） **
+type diskCollector struct{}
+
+func (c *diskCollector) Name() string            { return "disk" }
+func (c *diskCollector) Interval() time.Duration { return collectInterval }
+
+func (c *diskCollector) Collect(ctx context.Context) ([]common.MetricSample, error) {
+	usages, err := tools.GetDiskUsages(utils.LoadMonitorCfg().Disk.FsTypes)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Local().Unix()
+	var samples []common.MetricSample
+	for _, u := range usages {
+		labels := map[string]string{"mount": u.Path, "fstype": u.Fstype}
+		samples = append(samples,
+			common.MetricSample{Time: now, Name: "disk_used", Value: float64(u.Used), Unit: "bytes", Labels: labels},
+			common.MetricSample{Time: now, Name: "disk_used_percent", Value: u.UsedPercent, Unit: "%", Labels: labels},
+		)
+	}
+	return samples, nil
+}
+
+// ** 磁盘 I/O 速率（按设备） **
+// 由两次采集之间累计计数的差值计算，第一次采集只记录基准
+type diskIOCollector struct {
+	last     map[string]disk.IOCountersStat
+	lastTime time.Time
+}
+
+func (c *diskIOCollector) Name() string            { return "disk_io" }
+func (c *diskIOCollector) Interval() time.Duration { return collectInterval }
+
+func (c *diskIOCollector) Collect(ctx context.Context) ([]common.MetricSample, error) {
+	counters, err := tools.GetDiskIO()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	last, elapsed := c.last, now.Sub(c.lastTime).Seconds()
+	c.last, c.lastTime = counters, now
+	if last == nil || elapsed <= 0 {
+		return nil, nil
+	}
+
+	var samples []common.MetricSample
+	for name, cur := range counters {
+		if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") { // Linux 虚拟块设备
+			continue
+		}
+		prev, ok := last[name]
+		if !ok {
+			continue
+		}
+		labels := map[string]string{"device": name}
+		samples = append(samples,
+			common.MetricSample{Time: now.Unix(), Name: "disk_read_bytes_rate", Value: counterRate(cur.ReadBytes, prev.ReadBytes, elapsed), Unit: "bytes/s", Labels: labels},
+			common.MetricSample{Time: now.Unix(), Name: "disk_write_bytes_rate", Value: counterRate(cur.WriteBytes, prev.WriteBytes, elapsed), Unit: "bytes/s", Labels: labels},
+		)
+	}
+	return samples, nil
+}
+
+// 累计计数在 elapsed 秒内的平均速率，计数回绕时记为 0
+func counterRate(cur, prev uint64, elapsed float64) float64 {
+	if cur < prev {
+		return 0
+	}
+	return float64(cur-prev) / elapsed
+}
+
+// ** 网络收发速率（按网卡）与连接数（按状态） **
+type netCollector struct {
+	last     map[string]net.IOCountersStat
+	lastTime time.Time
+	states   map[string]bool // 出现过的连接状态，之后没有该状态的连接时记为 0
+}
+
+func (c *netCollector) Name() string            { return "net" }
+func (c *netCollector) Interval() time.Duration { return collectInterval }
+
+func (c *netCollector) Collect(ctx context.Context) ([]common.MetricSample, error) {
+	counters, err := tools.GetNetIO()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var samples []common.MetricSample
+
+	// 连接数获取失败（如权限不足）时仍记录收发速率
+	// 连接数降为 0 的状态也要记录，否则统计看不到 0、告警无法恢复、导出的仍是旧值
+	if counts, err := tools.GetConnectionCounts(); err == nil {
+		if c.states == nil {
+			c.states = map[string]bool{}
+		}
+		for state := range counts {
+			c.states[state] = true
+		}
+		for state := range c.states {
+			samples = append(samples, common.MetricSample{
+				Time: now.Unix(), Name: "net_connections", Value: float64(counts[state]), Unit: "count",
+				Labels: map[string]string{"state": state},
+			})
+		}
+	} else {
+		log.Printf("[collector/%s] count connections failed: %v", c.Name(), err)
+	}
+
+	last, elapsed := c.last, now.Sub(c.lastTime).Seconds()
+	c.last, c.lastTime = counters, now
+	if last == nil || elapsed <= 0 {
+		return samples, nil
+	}
+	for name, cur := range counters {
+		prev, ok := last[name]
+		if !ok {
+			continue
+		}
+		labels := map[string]string{"iface": name}
+		add := func(metric string, cur, prev uint64, unit string) {
+			samples = append(samples, common.MetricSample{
+				Time: now.Unix(), Name: metric, Value: counterRate(cur, prev, elapsed), Unit: unit, Labels: labels,
+			})
+		}
+		add("net_recv_bytes_rate", cur.BytesRecv, prev.BytesRecv, "bytes/s")
+		add("net_sent_bytes_rate", cur.BytesSent, prev.BytesSent, "bytes/s")
+		add("net_recv_packets_rate", cur.PacketsRecv, prev.PacketsRecv, "packets/s")
+		add("net_sent_packets_rate", cur.PacketsSent, prev.PacketsSent, "packets/s")
+		add("net_errors_rate", cur.Errin+cur.Errout, prev.Errin+prev.Errout, "errors/s")
+		add("net_drops_rate", cur.Dropin+cur.Dropout, prev.Dropin+prev.Dropout, "drops/s")
+	}
+	return samples, nil
+}
+
+// ** GPU（所有厂商、所有显卡，见 tools.GPUProviderRegister） **
+type gpuCollector struct{}
+
+func (c *gpuCollector) Name() string            { return "gpu" }
+func (c *gpuCollector) Interval() time.Duration { return collectInterval }
+
+func (c *gpuCollector) Collect(ctx context.Context) ([]common.MetricSample, error) {
+	gpuInfo, err := tools.GetGPUInfo()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Local().Unix()
+	var samples []common.MetricSample
+	for _, g := range gpuInfo {
+		labels := map[string]string{"gpu": fmt.Sprint(g.Index), "vendor": strings.ToLower(g.Vendor)}
+		add := func(name string, value float64, unit string) {
+			samples = append(samples, common.MetricSample{Time: now, Name: name, Value: value, Unit: unit, Labels: labels})
+		}
+		// 无法获取的数值为 -1（取决于厂商和驱动），不记录，避免 0 值拉低统计或触发告警
+		if g.Utilization >= 0 {
+			add("gpu_util", g.Utilization, "%")
+		}
+		if g.MemUsed >= 0 {
+			add("gpu_mem_used", float64(g.MemUsed), "MB")
+			if g.MemTotal > 0 {
+				add("gpu_mem_used_percent", float64(g.MemUsed)/float64(g.MemTotal)*100, "%")
+			}
+		}
+		if g.MemClock >= 0 {
+			add("gpu_mem_clock", float64(g.MemClock), "MHz")
+		}
+		if g.CoreClock >= 0 {
+			add("gpu_core_clock", float64(g.CoreClock), "MHz")
+		}
+		if g.Temperature >= 0 {
+			add("gpu_temp", float64(g.Temperature), "°C")
+		}
+		if g.PowerDraw >= 0 {
+			add("gpu_power", g.PowerDraw, "W")
+		}
+	}
+	return samples, nil
+}
+
+// ** 进程资源占用（分别按 CPU、内存、I/O 取前 N 个进程） **
+// 以进程名和执行路径为标签，CPU 利用率和 I/O 速率由两次采集之间累计计数的差值计算，
+// CPU 利用率按全部逻辑核归一化（与 cpu_percent 一致），第一次采集和新出现的进程只记录基准
+type processCollector struct {
+	last     map[int32]common.ProcessCounterStat
+	lastTime time.Time
+}
+
+type processUsage struct {
+	counter          common.ProcessCounterStat
+	cpu, read, write float64
+}
+
+func (c *processCollector) Name() string            { return "process" }
+func (c *processCollector) Interval() time.Duration { return collectInterval }
+
+func (c *processCollector) Collect(ctx context.Context) ([]common.MetricSample, error) {
+	counters, err := tools.GetProcessCounters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	last, elapsed := c.last, now.Sub(c.lastTime).Seconds()
+	c.last, c.lastTime = make(map[int32]common.ProcessCounterStat, len(counters)), now
+	for _, cur := range counters {
+		c.last[cur.Pid] = cur
+	}
+	if last == nil || elapsed <= 0 {
+		return nil, nil
+	}
+
+	var usages []processUsage
+	for _, cur := range counters {
+		prev, ok := last[cur.Pid]
+		if !ok || prev.CreateTime != cur.CreateTime || cur.Pid == 0 { // PID 0 为 Windows 的 System Idle Process
+			continue
+		}
+		usages = append(usages, processUsage{
+			counter: cur,
+			cpu:     math.Max(cur.CPUTime-prev.CPUTime, 0) / elapsed / float64(runtime.NumCPU()) * 100,
+			read:    counterRate(cur.ReadBytes, prev.ReadBytes, elapsed),
+			write:   counterRate(cur.WriteBytes, prev.WriteBytes, elapsed),
+		})
+	}
+
+	topN := utils.LoadMonitorCfg().Process.TopN
+	selected := map[int32]processUsage{}
+	for _, key := range []func(u processUsage) float64{
+		func(u processUsage) float64 { return u.cpu },
+		func(u processUsage) float64 { return float64(u.counter.RSS) },
+		func(u processUsage) float64 { return u.read + u.write },
+	} {
+		sort.SliceStable(usages, func(i, j int) bool { return key(usages[i]) > key(usages[j]) })
+		for _, u := range usages[:min(topN, len(usages))] {
+			if key(u) > 0 {
+				selected[u.counter.Pid] = u
+			}
+		}
+	}
+
+	// 同名同路径的进程（如浏览器的多个子进程）合并为一条序列，资源占用求和。
+	// PID 不作为标签，否则进程每次重启都会产生新序列，序列表无限增长；占用 CPU 最高的实例的 PID 记为 proc_pid
+	type processGroup struct {
+		labels           map[string]string
+		cpu, read, write float64
+		rss              uint64
+		count            int
+		top              processUsage
+	}
+	groups := map[string]*processGroup{}
+	for _, u := range selected {
+		labels := map[string]string{"name": u.counter.Name}
+		if u.counter.Exe != "" {
+			labels["exe"] = u.counter.Exe
+		}
+		key := common.FormatLabels(labels)
+		g, ok := groups[key]
+		if !ok {
+			g = &processGroup{labels: labels, top: u}
+			groups[key] = g
+		}
+		g.cpu, g.rss, g.read, g.write, g.count = g.cpu+u.cpu, g.rss+u.counter.RSS, g.read+u.read, g.write+u.write, g.count+1
+		if u.cpu > g.top.cpu || (u.cpu == g.top.cpu && u.counter.Pid < g.top.counter.Pid) {
+			g.top = u
+		}
+	}
+
+	var samples []common.MetricSample
+	for _, g := range groups {
+		add := func(name string, value float64, unit string) {
+			samples = append(samples, common.MetricSample{Time: now.Unix(), Name: name, Value: value, Unit: unit, Labels: g.labels})
+		}
+		add("proc_cpu_percent", g.cpu, "%")
+		add("proc_mem_rss", float64(g.rss), "bytes")
+		add("proc_io_read_bytes_rate", g.read, "bytes/s")
+		add("proc_io_write_bytes_rate", g.write, "bytes/s")
+		add("proc_count", float64(g.count), "count")
+		add("proc_pid", float64(g.top.counter.Pid), "")
+	}
+	return samples, nil
+}
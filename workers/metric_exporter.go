@@ -0,0 +1,187 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"winds-assistant/common"
+)
+
+// ** OpenMetrics 导出 **
+// 保存每条序列的最新采样，在 /metrics 以 OpenMetrics 文本格式提供给 Prometheus 抓取
+// 指标名加上 winds_ 前缀和单位后缀，单位统一换算为基本单位（字节、赫兹、秒）
+const (
+	exporterNamespace = "winds_"
+	// 超过该时间没有更新的序列（如已退出的进程、拔出的网卡）不再导出
+	exporterStaleAfter     = 5 * time.Minute
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// 采样单位 -> OpenMetrics 单位后缀和换算系数
+var openMetricsUnits = map[string]struct {
+	suffix string
+	scale  float64
+}{
+	"%":         {"percent", 1},
+	"bytes":     {"bytes", 1},
+	"MB":        {"bytes", 1 << 20},
+	"bytes/s":   {"bytes_per_second", 1},
+	"packets/s": {"per_second", 1},
+	"errors/s":  {"per_second", 1},
+	"drops/s":   {"per_second", 1},
+	"MHz":       {"hertz", 1e6},
+	"°C":        {"celsius", 1},
+	"W":         {"watts", 1},
+}
+
+// 指标在 OpenMetrics 中的名称、单位和换算系数，如 gpu_mem_used(MB) -> winds_gpu_mem_used_bytes
+func openMetricsName(name, unit string) (string, string, float64) {
+	u, ok := openMetricsUnits[unit]
+	if !ok { // count 等无单位的指标
+		return exporterNamespace + name, "", 1
+	}
+	name = strings.TrimSuffix(name, "_rate") // 速率由单位后缀 per_second 表示
+	name = strings.TrimSuffix(name, "_bytes")
+	if !strings.HasSuffix(name, "_"+u.suffix) {
+		name += "_" + u.suffix
+	}
+	return exporterNamespace + name, u.suffix, u.scale
+}
+
+// 转义标签值中的反斜杠、双引号和换行
+var openMetricsEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type metricExporter struct {
+	mu     sync.Mutex
+	latest map[string]common.MetricSample // 序列 -> 最新采样
+	server *http.Server
+	listen string
+}
+
+func newMetricExporter() *metricExporter {
+	return &metricExporter{latest: map[string]common.MetricSample{}}
+}
+
+// 记录一批采样中各序列的最新值
+// 进程采集每次只记录当前占用最高的几个进程，新的一批到达时清空上一批，已跌出前几名或退出的进程不再导出
+func (e *metricExporter) Update(samples []common.MetricSample) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if slices.ContainsFunc(samples, func(s common.MetricSample) bool { return s.Source == "process" }) {
+		for key, s := range e.latest {
+			if s.Source == "process" {
+				delete(e.latest, key)
+			}
+		}
+	}
+	for _, s := range samples {
+		key := anomalyKey(s.Name, s.Labels)
+		if prev, ok := e.latest[key]; !ok || s.Time >= prev.Time {
+			e.latest[key] = s
+		}
+	}
+}
+
+// 按配置启动、停止或重启监听（监听地址修改后重启）
+func (e *metricExporter) Sync(config common.ExporterConfig) {
+	if e.server != nil && (!config.Enabled || config.Listen != e.listen) {
+		e.Stop()
+	}
+	if !config.Enabled || e.server != nil {
+		return
+	}
+	listener, err := net.Listen("tcp", config.Listen)
+	if err != nil {
+		log.Printf("[exporter] listen on %s failed: %v", config.Listen, err)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", e.serveMetrics)
+	e.server, e.listen = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}, config.Listen
+	go func(server *http.Server) {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[exporter] serve failed: %v", err)
+		}
+	}(e.server)
+	log.Printf("[exporter] serving OpenMetrics on http://%s/metrics", config.Listen)
+}
+
+// 停止监听
+func (e *metricExporter) Stop() {
+	if e.server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.server.Shutdown(ctx); err != nil {
+		log.Printf("[exporter] shutdown failed: %v", err)
+	}
+	e.server, e.listen = nil, ""
+}
+
+func (e *metricExporter) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", openMetricsContentType)
+	fmt.Fprint(w, e.render(time.Now()))
+}
+
+// 生成 OpenMetrics 文本：同名指标归为一个 family，family 和序列按名称排序
+func (e *metricExporter) render(now time.Time) string {
+	type family struct {
+		unit  string
+		lines []string
+	}
+	families := map[string]*family{}
+
+	e.mu.Lock()
+	for key, s := range e.latest {
+		if now.Sub(time.Unix(s.Time, 0)) > exporterStaleAfter {
+			delete(e.latest, key)
+			continue
+		}
+		name, unit, scale := openMetricsName(s.Name, s.Unit)
+		f, ok := families[name]
+		if !ok {
+			f = &family{unit: unit}
+			families[name] = f
+		}
+		var labels []string
+		for k, v := range s.Labels {
+			labels = append(labels, fmt.Sprintf(`%s="%s"`, k, openMetricsEscaper.Replace(v)))
+		}
+		sort.Strings(labels)
+		line := name
+		if len(labels) > 0 {
+			line += "{" + strings.Join(labels, ",") + "}"
+		}
+		f.lines = append(f.lines, fmt.Sprintf("%s %g %d", line, s.Value*scale, s.Time))
+	}
+	e.mu.Unlock()
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		f := families[name]
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", name)
+		if f.unit != "" {
+			fmt.Fprintf(&b, "# UNIT %s %s\n", name, f.unit)
+		}
+		sort.Strings(f.lines)
+		for _, line := range f.lines {
+			b.WriteString(line + "\n")
+		}
+	}
+	b.WriteString("# EOF\n")
+	return b.String()
+}
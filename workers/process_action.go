@@ -0,0 +1,80 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"winds-assistant/common"
+	"winds-assistant/tools"
+	"winds-assistant/utils"
+)
+
+// 等待用户确认的进程操作
+type ProcessActionRequest struct {
+	Target   tools.ProcessTarget
+	Action   string
+	Priority string // 只用于 set_priority
+	Reason   string // 模型给出的操作理由
+}
+
+var (
+	processConfirmerMu sync.Mutex
+	processConfirmer   func(req ProcessActionRequest) bool
+)
+
+// 设置向用户确认进程操作的函数（阻塞直到用户做出选择），由界面启动后调用
+// 未设置时（如评测、告警诊断）所有进程操作都会被拒绝
+func SetProcessActionConfirmer(confirm func(req ProcessActionRequest) bool) {
+	processConfirmerMu.Lock()
+	defer processConfirmerMu.Unlock()
+	processConfirmer = confirm
+}
+
+// 向用户确认后执行进程操作，无论结果如何都写入审计日志，返回给模型的说明
+func runProcessAction(ctx context.Context, pid int32, action, priority, reason string) string {
+	if err := tools.CheckProcessAction(action, priority); err != nil {
+		return toolError(err)
+	}
+	if action != "set_priority" {
+		priority = ""
+	}
+	target, err := tools.GetProcessTarget(ctx, pid)
+	if err != nil {
+		return toolError(err)
+	}
+	record := common.ProcessAuditRecord{
+		Action: action, Priority: priority, Reason: reason,
+		Pid: target.Pid, Name: target.Name, Exe: target.Exe, User: target.User, CreateTime: target.CreateTime,
+	}
+
+	processConfirmerMu.Lock()
+	confirm := processConfirmer
+	processConfirmerMu.Unlock()
+
+	var out string
+	switch {
+	case confirm == nil:
+		record.Result = "denied"
+		out = "当前无法向用户确认, 操作未执行。请告诉用户手动处理该进程。"
+	case !confirm(ProcessActionRequest{Target: target, Action: action, Priority: priority, Reason: reason}):
+		record.Result = "denied"
+		out = fmt.Sprintf("用户拒绝了对进程 %s(PID %d) 的 %s 操作, 操作未执行, 不要再次请求。", target.Name, target.Pid, action)
+	default:
+		record.Confirmed = true
+		if err := tools.ManageProcess(ctx, target, action, priority); err != nil {
+			record.Result = err.Error()
+			out = toolError(fmt.Errorf("用户已确认, 但对进程 %s(PID %d) 执行 %s 失败: %w", target.Name, target.Pid, action, err))
+		} else {
+			record.Result = "ok"
+			out = fmt.Sprintf("已对进程 %s(PID %d) 执行 %s。", target.Name, target.Pid, strings.TrimSpace(action+" "+priority))
+		}
+	}
+	record.Time = time.Now().Unix()
+	if err := utils.AppendProcessAudit(record); err != nil {
+		log.Printf("[process] write audit log failed: %v", err)
+	}
+	return out
+}